@@ -1,7 +1,10 @@
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 const (
@@ -9,13 +12,29 @@ const (
 	IgnoreStrategy           = "Ignore" // TODO: implement "IgnoreStrategy"
 )
 
+const (
+	PersistentVolumeClaimWorkVolumeMode = "PersistentVolumeClaim"
+	EmptyDirWorkVolumeMode              = "EmptyDir"
+)
+
+const (
+	JobsExecutionMode      = "Jobs"
+	SinglePodExecutionMode = "SinglePod"
+)
+
+const (
+	WaitCancellationPolicy                 = "Wait"
+	CancelCancellationPolicy               = "Cancel"
+	CancelUnlessApplyingCancellationPolicy = "CancelUnlessApplying"
+)
+
 // Application represents a single application, optionally spanning multiple repositories (or a single one) and manages
 // multiple deployment environments, as deducted from the different branches in said repositories.
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +condition:commons
 // +condition:Current,Stale:EnvironmentsAreStale,InternalError,RepositoryNotAccessible,RepositoryNotFound
-// +condition:Valid,Invalid:InvalidBranchSpecification
+// +condition:Valid,Invalid:InvalidBranchSpecification,InvalidImagesTimeout,InvalidVersionConstraint
 // +kubebuilder:printcolumn:name="Service Account",type=string,JSONPath=`.spec.serviceAccountName`
 // +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.privateArea.Valid`
 // +kubebuilder:printcolumn:name="Current",type=string,JSONPath=`.status.privateArea.Current`
@@ -54,9 +73,122 @@ type ApplicationSpec struct {
 	// +kubebuilder:validation:Optional
 	Branches []string `json:"branches,omitempty"`
 
+	// PullRequests switches the application to pull-request-driven environments: instead of an environment per branch,
+	// an environment is created for the head branch of every open pull request in the participating repositories, and
+	// removed once the pull request is merged or closed. Branch expressions in "branches" still apply to head branches.
+	// +kubebuilder:validation:Optional
+	PullRequests *ApplicationSpecPullRequests `json:"pullRequests,omitempty"`
+
+	// PinnedEnvironments is a list of environments that exist regardless of branches or pull requests, and deploy the
+	// highest tag matching a semantic version constraint from each participating repository (e.g. a release train).
+	// +kubebuilder:validation:Optional
+	PinnedEnvironments []ApplicationSpecPinnedEnvironment `json:"pinnedEnvironments,omitempty"`
+
+	// EnvironmentURL is an optional URL template pointing to a deployed environment of this application. It is reported
+	// to the source repositories (e.g. as the target URL of GitHub commit statuses) once a deployment succeeds. The
+	// template may reference the "${APPLICATION}", "${ENVIRONMENT}", "${PREFERRED_BRANCH}", "${ACTUAL_BRANCH}",
+	// "${COMMIT_SHA}" and "${TAG}" variables, which are expanded the same way they are expanded in deployment manifests.
+	// +kubebuilder:validation:Optional
+	EnvironmentURL string `json:"environmentURL,omitempty"`
+
+	// PullRequestComments enables posting a single, continuously-updated comment on the pull request whose head branch
+	// is the preferred branch of an environment. The comment lists the deployed revision, status & environment URL of
+	// each participating repository.
+	// +kubebuilder:validation:Optional
+	PullRequestComments bool `json:"pullRequestComments,omitempty"`
+
+	// WorkVolume configures the volume deployment jobs clone & bake into. Fields left empty fall back to the defaults
+	// configured for the controller.
+	// +kubebuilder:validation:Optional
+	WorkVolume *ApplicationSpecWorkVolume `json:"workVolume,omitempty"`
+
+	// ExecutionMode is either "Jobs", running the clone, bake & apply phases of deployments as three sequential jobs;
+	// or "SinglePod", running them in a single job whose pod clones & bakes in init containers and applies in its main
+	// container, saving the scheduling & volume attachment latency of the additional jobs. Deployments with an
+	// "EmptyDir" work volume always run in a single pod. If empty, the controller's default is used.
+	// +kubebuilder:validation:Enum=Jobs;SinglePod
+	// +kubebuilder:validation:Optional
+	ExecutionMode string `json:"executionMode,omitempty"`
+
+	// MaxAttempts is the number of failed attempts of each phase of deploying a revision (each retried by its job),
+	// after which deployments give up on the revision until a new revision appears, or a retry is requested via the
+	// deployment's "retry.devbot.com" annotation. Attempts are backed off exponentially. If zero, the controller's
+	// default is used.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+
+	// CancellationPolicy determines what happens to an active job of a deployment once the revision it was started for
+	// is superseded by a newer one: "Wait" lets the job finish before deploying the newer revision; "Cancel" deletes the
+	// job immediately; and "CancelUnlessApplying" deletes it unless it is already applying, avoiding half-applied
+	// revisions. If empty, the controller's default is used.
+	// +kubebuilder:validation:Enum=Wait;Cancel;CancelUnlessApplying
+	// +kubebuilder:validation:Optional
+	CancellationPolicy string `json:"cancellationPolicy,omitempty"`
+
+	// MaxConcurrentJobs bounds the number of concurrently running jobs of this application's deployments; further jobs
+	// are queued. If zero, the controller's default is used.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	MaxConcurrentJobs int `json:"maxConcurrentJobs,omitempty"`
+
+	// JobTemplate is a partial Job merged onto the clone, bake & apply jobs of this application's deployments with
+	// strategic-merge semantics, after the controller's job template (if any). It allows customizing e.g. the retries &
+	// TTL of jobs, or their pods' node selectors, tolerations, security contexts & image pull secrets. Containers are
+	// merged by name ("clone", "bake" & "apply").
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	JobTemplate *runtime.RawExtension `json:"jobTemplate,omitempty"`
+
 	// TODO: Add environment expiry support, comprised of a default expiry time, a per-environment override & stickiness
 }
 
+// ApplicationSpecWorkVolume configures the work volume of deployments.
+type ApplicationSpecWorkVolume struct {
+
+	// Mode is either "PersistentVolumeClaim", creating a persistent volume claim per deployment which is shared by its
+	// clone, bake & apply jobs; or "EmptyDir", which avoids persistent volume claims by running the clone, bake & apply
+	// phases in a single pod, cloning afresh on every run.
+	// +kubebuilder:validation:Enum=PersistentVolumeClaim;EmptyDir
+	// +kubebuilder:validation:Optional
+	Mode string `json:"mode,omitempty"`
+
+	// StorageClassName is the storage class of persistent volume claims. If empty, the cluster's default storage class
+	// is used.
+	// +kubebuilder:validation:Optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// Size is the requested size of persistent volume claims (or the size limit of "EmptyDir" volumes).
+	// +kubebuilder:validation:Optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// AccessMode is the access mode of persistent volume claims.
+	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadWriteMany;ReadWriteOncePod
+	// +kubebuilder:validation:Optional
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+}
+
+type ApplicationSpecPullRequests struct {
+	// Label optionally restricts environments to pull requests carrying this label. If empty, all open pull requests
+	// get an environment.
+	// +kubebuilder:validation:Optional
+	Label string `json:"label,omitempty"`
+}
+
+type ApplicationSpecPinnedEnvironment struct {
+	// Name is the name of the pinned environment. Branches with the same name do not get an environment of their own.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Version is the semantic version constraint (e.g. "~1.4") that tags must match in order to be deployed to this
+	// environment. Repositories without a matching tag deploy their default branch instead.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	Version string `json:"version"`
+}
+
 type ApplicationSpecRepository struct {
 	// +kubebuilder:validation:MaxLength=63
 	// +kubebuilder:validation:MinLength=1
@@ -80,6 +212,81 @@ type ApplicationSpecRepository struct {
 	// +kubebuilder:default=UseDefaultBranch
 	// +kubebuilder:validation:Enum=Ignore;UseDefaultBranch
 	MissingBranchStrategy string `json:"missingBranchStrategy,omitempty"`
+
+	// Version is an optional semantic version constraint (e.g. "~1.4"). If set, this repository is deployed from the
+	// highest tag matching the constraint in every environment of the application, instead of from a branch.
+	// +kubebuilder:validation:Optional
+	Version string `json:"version,omitempty"`
+
+	// Checks, if set, gates deploying revisions of this repository on their GitHub check runs & commit statuses. A
+	// revision is only deployed once all checks succeed; a revision with a failed check is not deployed.
+	// +kubebuilder:validation:Optional
+	Checks *ApplicationSpecRepositoryChecks `json:"checks,omitempty"`
+
+	// WaitForImages, if set, makes the apply phase wait until all container images referenced by the baked manifest
+	// exist in their registries before applying it.
+	// +kubebuilder:validation:Optional
+	WaitForImages *ApplicationSpecRepositoryWaitForImages `json:"waitForImages,omitempty"`
+
+	// Clone, if set, tunes how deployments clone this repository, e.g. to speed up cloning huge repositories.
+	// +kubebuilder:validation:Optional
+	Clone *ApplicationSpecRepositoryClone `json:"clone,omitempty"`
+}
+
+// ApplicationSpecRepositoryClone specifies how deployments clone a repository.
+type ApplicationSpecRepositoryClone struct {
+
+	// Depth limits the fetched history to the given number of commits from the deployed revision (a shallow clone). If
+	// the deployed revision is not within that depth (e.g. since its branch moved on), the full history is fetched. If
+	// zero, the full history is always fetched.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	Depth int `json:"depth,omitempty"`
+
+	// Sparse limits the checked out tree to the repository's deploy path, and to the additional directories in
+	// SparsePaths.
+	// +kubebuilder:validation:Optional
+	Sparse bool `json:"sparse,omitempty"`
+
+	// SparsePaths lists additional directories to check out in sparse mode, e.g. Kustomize bases residing outside the
+	// deploy path.
+	// +kubebuilder:validation:Optional
+	SparsePaths []string `json:"sparsePaths,omitempty"`
+
+	// Submodules recursively initializes & updates the submodules of the deployed revision, using the repository's
+	// credentials. In sparse mode, only submodules within the checked out directories are updated.
+	// +kubebuilder:validation:Optional
+	Submodules bool `json:"submodules,omitempty"`
+
+	// LFS fetches the Git LFS objects of the deployed revision in place of their pointer files. Objects of submodules
+	// are not fetched.
+	// +kubebuilder:validation:Optional
+	LFS bool `json:"lfs,omitempty"`
+}
+
+// ApplicationSpecRepositoryWaitForImages specifies how to wait for container images before applying a manifest.
+type ApplicationSpecRepositoryWaitForImages struct {
+
+	// Timeout is how long each apply attempt waits for missing images before failing (and being retried). The value
+	// should be specified as a duration string, e.g. "5m" for 5 minutes.
+	// +kubebuilder:default="2m"
+	// +kubebuilder:validation:Optional
+	Timeout string `json:"timeout,omitempty"`
+
+	// PullSecrets lists secrets of type "kubernetes.io/dockerconfigjson", in the deployment namespace, holding
+	// credentials of the registries to check.
+	// +kubebuilder:validation:Optional
+	PullSecrets []corev1.LocalObjectReference `json:"pullSecrets,omitempty"`
+}
+
+// ApplicationSpecRepositoryChecks specifies which GitHub checks must succeed before a revision is deployed.
+type ApplicationSpecRepositoryChecks struct {
+
+	// Names limits the checks to the check runs & commit status contexts with these names. Listed checks that were not
+	// reported yet are waited for. If empty, all reported checks must succeed (and revisions without any checks are
+	// deployed immediately).
+	// +kubebuilder:validation:Optional
+	Names []string `json:"names,omitempty"`
 }
 
 type ApplicationStatus struct {
@@ -4,19 +4,33 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// RetryAnnotation is updated on deployments to request another round of attempts of deploying their revision, e.g.
+	// after giving up on it. Each distinct value requests a single retry.
+	RetryAnnotation = "retry.devbot.com"
+
+	// RedeployAnnotation is updated on deployments, environments or applications to request redeploying the current
+	// revision of the deployment(s) - i.e. rerunning their clone, bake & apply phases. Each distinct value requests a
+	// single redeploy.
+	RedeployAnnotation = "redeploy.devbot.com"
+)
+
 // Deployment represents a deployment of a repository into an environment.
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +condition:commons
 // +condition:Current,Stale:InternalError,Invalid
 // +condition:Current,Stale:PersistentVolumeCreationFailed,PersistentVolumeMissing
-// +condition:Current,Stale:Cloning,CloneFailed,BranchNotFound,RepositoryNotAccessible,RepositoryNotFound
+// +condition:Current,Stale:WaitingForChecks,ChecksFailed
+// +condition:Current,Stale:Cloning,CloneFailed,BranchNotFound,RepositoryNotAccessible,RepositoryNotFound,TagNotFound
 // +condition:Current,Stale:Baking,BakingFailed
-// +condition:Current,Stale:Applying,ApplyFailed
+// +condition:Current,Stale:Applying,ApplyFailed,WaitingForImages
+// +condition:Current,Stale:AttemptsExhausted,Queued
 // +condition:Valid,Invalid:RepositoryNotSupported
 // +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.privateArea.Valid`
 // +kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.status.resolvedRepository`
 // +kubebuilder:printcolumn:name="Branch",type=string,JSONPath=`.status.branch`
+// +kubebuilder:printcolumn:name="Tag",type=string,JSONPath=`.status.tag`
 // +kubebuilder:printcolumn:name="PVC",type=string,JSONPath=`.status.persistentVolumeNameClaim`
 // +kubebuilder:printcolumn:name="Last Attempted Revision",type=string,JSONPath=`.status.lastAttemptedRevision`
 // +kubebuilder:printcolumn:name="Last Applied Revision",type=string,JSONPath=`.status.lastAppliedRevision`
@@ -55,6 +69,11 @@ type DeploymentStatus struct {
 	// +kubebuilder:validation:Optional
 	Branch string `json:"branch,omitempty"`
 
+	// Tag is the tag being deployed from the repository, in case the repository or environment follow a semantic
+	// version constraint. The branch is empty when a tag is deployed.
+	// +kubebuilder:validation:Optional
+	Tag string `json:"tag,omitempty"`
+
 	// PersistentVolumeClaimName points to the name of the [k8s.io/api/core/v1.PersistentVolumeClaim] used for hosting
 	// the cloned Git repository that this deployment will apply. The volume will be mounted to the various jobs this
 	// deployment will create & run over its lifetime.
@@ -80,6 +99,33 @@ type DeploymentStatus struct {
 	// +kubebuilder:validation:Pattern=^[a-f0-9]+$
 	LastAppliedRevision string `json:"lastAppliedRevision,omitempty"`
 
+	// TraceParent is the W3C trace context ("traceparent") of the deployment of the last attempted revision. It is
+	// passed to the deployment's jobs, so that their spans join the trace that led to the deployment (e.g. a push).
+	// +kubebuilder:validation:Optional
+	TraceParent string `json:"traceParent,omitempty"`
+
+	// QueuePosition is the (1-based) position of the deployment in the queue of deployments waiting for a job slot, or
+	// zero if it is not queued.
+	// +kubebuilder:validation:Optional
+	QueuePosition int `json:"queuePosition,omitempty"`
+
+	// SupersededRevision is the last revision whose active job was cancelled in favor of a newer revision.
+	// +kubebuilder:validation:Optional
+	SupersededRevision string `json:"supersededRevision,omitempty"`
+
+	// FailedAttempts tracks the failed attempts of deploying the last attempted revision, if any.
+	// +kubebuilder:validation:Optional
+	FailedAttempts *DeploymentStatusFailedAttempts `json:"failedAttempts,omitempty"`
+
+	// LastRetryRequest is the last handled value of the retry annotation.
+	// +kubebuilder:validation:Optional
+	LastRetryRequest string `json:"lastRetryRequest,omitempty"`
+
+	// LastRedeployRequest holds the last handled values of the redeploy annotation of the deployment, its environment &
+	// its application.
+	// +kubebuilder:validation:Optional
+	LastRedeployRequest DeploymentStatusRedeployRequest `json:"lastRedeployRequest,omitempty"`
+
 	// LastAppliedTime is the time the last deployment was applied.
 	// +kubebuilder:validation:Optional
 	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
@@ -89,6 +135,51 @@ type DeploymentStatus struct {
 	PrivateArea ConditionsInverseState `json:"privateArea,omitempty"`
 }
 
+// DeploymentStatusFailedAttempts tracks the failed attempts of deploying a revision.
+type DeploymentStatusFailedAttempts struct {
+
+	// Revision is the revision the attempts were made to deploy.
+	// +kubebuilder:validation:Required
+	Revision string `json:"revision"`
+
+	// Phases maps each phase ("clone", "bake" or "apply") to the number of its failed attempts.
+	// +kubebuilder:validation:Optional
+	Phases map[string]int `json:"phases,omitempty"`
+
+	// LastFailedJob is the name of the job of the last failed attempt.
+	// +kubebuilder:validation:Optional
+	LastFailedJob string `json:"lastFailedJob,omitempty"`
+
+	// LastFailedPhase is the phase that failed in the last failed attempt.
+	// +kubebuilder:validation:Optional
+	LastFailedPhase string `json:"lastFailedPhase,omitempty"`
+
+	// LastFailure describes the failure of the last failed attempt.
+	// +kubebuilder:validation:Optional
+	LastFailure string `json:"lastFailure,omitempty"`
+
+	// LastFailureTime is the time the last failed attempt was observed, from which the next attempt is backed off.
+	// +kubebuilder:validation:Optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+}
+
+// DeploymentStatusRedeployRequest holds the values of the redeploy annotation of a deployment, its environment & its
+// application.
+type DeploymentStatusRedeployRequest struct {
+
+	// Application is the value of the redeploy annotation of the deployment's application.
+	// +kubebuilder:validation:Optional
+	Application string `json:"application,omitempty"`
+
+	// Environment is the value of the redeploy annotation of the deployment's environment.
+	// +kubebuilder:validation:Optional
+	Environment string `json:"environment,omitempty"`
+
+	// Deployment is the value of the redeploy annotation of the deployment itself.
+	// +kubebuilder:validation:Optional
+	Deployment string `json:"deployment,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 
 type DeploymentList struct {
@@ -10,6 +10,7 @@ import (
 // +condition:commons
 // +condition:Current,Stale:DeploymentsAreStale,FailedCreatingDeployment,FailedDeletingDeployment,InternalError
 // +kubebuilder:printcolumn:name="Preferred Branch",type=string,JSONPath=`.spec.branch`
+// +kubebuilder:printcolumn:name="Pinned",type=string,JSONPath=`.spec.pinned`
 // +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.privateArea.Valid`
 // +kubebuilder:printcolumn:name="Current",type=string,JSONPath=`.status.privateArea.Current`
 type Environment struct {
@@ -28,9 +29,28 @@ type Environment struct {
 type EnvironmentSpec struct {
 	// PreferredBranch is the preferred branch for deployment to this environment from each repository. Repositories
 	// that lack this branch may opt to deploy their default branch instead (see [ApplicationSpecRepository.MissingBranchStrategy]).
-	// +kubebuilder:validation:MinLength=1
-	// +kubebuilder:validation:Required
-	PreferredBranch string `json:"branch"`
+	// Empty for pinned environments, which deploy their default branch unless a tag matches their version constraint.
+	// +kubebuilder:validation:Optional
+	PreferredBranch string `json:"branch,omitempty"`
+
+	// Pinned is the name of the application's pinned environment (see [ApplicationSpec.PinnedEnvironments]) that this
+	// environment represents, if any.
+	// +kubebuilder:validation:Optional
+	Pinned string `json:"pinned,omitempty"`
+
+	// Version is an optional semantic version constraint (e.g. "~1.4"). If set, each repository deploys the highest tag
+	// matching it to this environment, falling back to the preferred (or default) branch if no tag matches.
+	// +kubebuilder:validation:Optional
+	Version string `json:"version,omitempty"`
+}
+
+// GetDisplayName returns the name of the environment within its application: its pinned environment name if pinned, or
+// its preferred branch otherwise.
+func (in *EnvironmentSpec) GetDisplayName() string {
+	if in.Pinned != "" {
+		return in.Pinned
+	}
+	return in.PreferredBranch
 }
 
 type EnvironmentStatus struct {
@@ -39,11 +59,35 @@ type EnvironmentStatus struct {
 	// +kubebuilder:validation:Optional
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
 
+	// PullRequestComments tracks the pull request comments posted for this environment, if enabled by the application.
+	// +kubebuilder:validation:Optional
+	PullRequestComments []EnvironmentPullRequestComment `json:"pullRequestComments,omitempty"`
+
 	// PrivateArea is not meant for public consumption, nor is it part of the public API. It is exposed due to Go and
 	// controller-runtime limitations but is an internal part of the implementation.
 	PrivateArea ConditionsInverseState `json:"privateArea,omitempty"`
 }
 
+// EnvironmentPullRequestComment represents a comment posted on a pull request for this environment.
+type EnvironmentPullRequestComment struct {
+
+	// Repository is the resolved name of the repository hosting the pull request.
+	// +kubebuilder:validation:Required
+	Repository string `json:"repository"`
+
+	// PullRequest is the number of the pull request.
+	// +kubebuilder:validation:Required
+	PullRequest int `json:"pullRequest"`
+
+	// CommentID is the ID of the comment posted on the pull request.
+	// +kubebuilder:validation:Required
+	CommentID int64 `json:"commentID"`
+
+	// BodyHash is the hash of the last comment body posted, used to avoid needless comment updates.
+	// +kubebuilder:validation:Optional
+	BodyHash string `json:"bodyHash,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 
 type EnvironmentList struct {
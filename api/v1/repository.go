@@ -1,16 +1,45 @@
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// RefreshAnnotation is updated on repositories (e.g. by the webhooks server) to request an immediate refresh.
+	RefreshAnnotation = "refresh.devbot.com"
+
+	// RefreshBranchesAnnotation narrows the refresh requested via the RefreshAnnotation to the listed (comma-separated)
+	// branches. It is ignored once the refresh request was handled (see [RepositoryStatus.LastRefreshRequest]).
+	RefreshBranchesAnnotation = "refresh-branches.devbot.com"
+
+	// TraceParentAnnotation holds the W3C trace context ("traceparent") of the last webhook delivery that changed (or
+	// requested a refresh of) the repository's revisions, so that deployments of the revisions it introduced continue its
+	// trace.
+	TraceParentAnnotation = "traceparent.devbot.com"
+
+	// MaxWebhookDeliveries is the number of most recent webhook deliveries retained in the repository status.
+	MaxWebhookDeliveries = 20
+)
+
+const (
+	WebhookDeliveryAccepted          = "Accepted"
+	WebhookDeliveryFailed            = "Failed"
+	WebhookDeliveryInvalidRequest    = "InvalidRequest"
+	WebhookDeliveryInvalidSignature  = "InvalidSignature"
+	WebhookDeliveryMissingDeliveryID = "MissingDeliveryID"
+	WebhookDeliveryReplayed          = "Replayed"
+	WebhookDeliverySecretUnavailable = "SecretUnavailable"
+)
+
 // Repository represents a single source code repository hosted remotely (e.g. on GitHub).
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +condition:commons
 // +condition:Authenticated,Unauthenticated:AuthenticationFailed,AuthSecretForbidden,AuthSecretKeyNotFound,AuthSecretNotFound,AuthTokenEmpty,InternalError,Invalid
 // +condition:Current,Stale:InternalError,Invalid,RepositoryNotFound,Unauthenticated
-// +condition:Valid,Invalid:InvalidRefreshInterval,UnknownRepositoryType
+// +condition:Valid,Invalid:InvalidRefreshInterval,RepositoryMoved,UnknownRepositoryType
 // +condition:Valid,Invalid:WebhookSecretEmpty,WebhookSecretForbidden,WebhookSecretKeyMissing,WebhookSecretKeyNotFound,WebhookSecretNameMissing,WebhookSecretNotFound,WebhooksNotEnabled
 // +kubebuilder:printcolumn:name="Refresh Interval",type=string,JSONPath=`.spec.refreshInterval`
 // +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.privateArea.Valid`
@@ -46,6 +75,61 @@ type RepositorySpec struct {
 	// +kubebuilder:validation:MinLength=1
 	// +kubebuilder:validation:Optional
 	RefreshInterval string `json:"refreshInterval,omitempty"`
+
+	// RefreshWebhook enables the generic refresh webhook for this repository (e.g. for CI systems to trigger a refresh
+	// once a build finishes), and specifies where to find the secret used to authenticate its requests.
+	// +kubebuilder:validation:Optional
+	RefreshWebhook *RepositoryRefreshWebhook `json:"refreshWebhook,omitempty"`
+
+	// Mirror enables a bare mirror of the repository, shared by all deployments of the repository and kept up to date by
+	// a fetch job whenever the repository's branches change. Clone jobs borrow objects from the mirror instead of
+	// downloading them again, which cuts clone time, disk usage and bandwidth. Since the mirror resides in the
+	// repository's namespace, deployments in other namespaces clone without it.
+	// +kubebuilder:validation:Optional
+	Mirror *RepositoryMirror `json:"mirror,omitempty"`
+
+	// MaxConcurrentJobs bounds the number of concurrently running jobs of deployments of this repository, across all
+	// applications; further jobs are queued. If zero, the controller's default is used.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	MaxConcurrentJobs int `json:"maxConcurrentJobs,omitempty"`
+}
+
+// RepositoryMirror configures the persistent volume claim housing the shared mirror of a repository. Since the claim is
+// mounted by clone jobs of all deployments concurrently, it should normally support the "ReadWriteMany" access mode.
+type RepositoryMirror struct {
+
+	// StorageClassName is the storage class of the mirror's persistent volume claim. If empty, the cluster's default
+	// storage class is used.
+	// +kubebuilder:validation:Optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// Size is the requested size of the mirror's persistent volume claim. Defaults to "10Gi".
+	// +kubebuilder:validation:Optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// AccessMode is the access mode of the mirror's persistent volume claim. Defaults to "ReadWriteMany".
+	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadWriteMany
+	// +kubebuilder:validation:Optional
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+}
+
+// RepositoryRefreshWebhook specifies the Kubernetes secret & key that house the secret used to authenticate requests to
+// the generic refresh webhook of a repository. Requests must either carry the secret as a bearer token, or sign their
+// body with it using HMAC-SHA256 (in the "X-Devbot-Signature-256" header, formatted as "sha256=<hex>"). Requests may
+// optionally carry a JSON body naming a single branch to refresh (e.g. {"branch":"main"}).
+type RepositoryRefreshWebhook struct {
+
+	// Secret is the reference to the secret containing the refresh webhook secret.
+	// +kubebuilder:validation:Required
+	Secret SecretReferenceWithOptionalNamespace `json:"secret"`
+
+	// Key is the key in the secret containing the refresh webhook secret.
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=^[a-zA-Z0-9][a-zA-Z0-9-_.]*[a-zA-Z0-9_.]$
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
 }
 
 // GitHubRepositorySpec provides the specification for a GitHub repository.
@@ -74,6 +158,34 @@ type GitHubRepositorySpec struct {
 	// WebhookSecret specifies where to find the webhook secret used to validate incoming webhook requests from GitHub.
 	// +kubebuilder:validation:Optional
 	WebhookSecret *GitHubRepositoryWebhookSecret `json:"webhookSecret,omitempty"`
+
+	// DeployKey signals that the repository should be cloned over SSH using a deploy key, rather than over HTTPS using
+	// the personal access token, and specifies where to find the key and the known hosts used to verify GitHub's host.
+	// +kubebuilder:validation:Optional
+	DeployKey *GitHubRepositoryDeployKey `json:"deployKey,omitempty"`
+}
+
+// GitHubRepositoryDeployKey specifies the Kubernetes secret & keys that house the SSH private key of a GitHub deploy
+// key, and the known hosts entries (e.g. as printed by "ssh-keyscan github.com") used to verify GitHub's host keys.
+type GitHubRepositoryDeployKey struct {
+
+	// Secret is the reference to the secret containing the deploy key and known hosts.
+	// +kubebuilder:validation:Required
+	Secret SecretReferenceWithOptionalNamespace `json:"secret"`
+
+	// Key is the key in the secret containing the PEM-encoded SSH private key.
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=^[a-zA-Z0-9][a-zA-Z0-9-_.]*[a-zA-Z0-9_.]$
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+
+	// KnownHostsKey is the key in the secret containing the known hosts entries, in OpenSSH "known_hosts" format.
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=^[a-zA-Z0-9][a-zA-Z0-9-_.]*[a-zA-Z0-9_.]$
+	// +kubebuilder:validation:Required
+	KnownHostsKey string `json:"knownHostsKey"`
 }
 
 // GitHubRepositoryPersonalAccessToken specifies the Kubernetes secret & key that house the GitHub personal access token
@@ -131,6 +243,35 @@ type RepositoryStatus struct {
 	// +kubebuilder:validation:Optional
 	Revisions map[string]string `json:"revisions,omitempty"`
 
+	// Tags is a map of tag names to the commit SHA they point to.
+	// +kubebuilder:validation:Optional
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// PullRequests is the list of open pull requests whose head branch resides in this repository. Pull requests from
+	// forks are not tracked, since their head branches cannot be deployed from this repository.
+	// +kubebuilder:validation:Optional
+	PullRequests []RepositoryPullRequest `json:"pullRequests,omitempty"`
+
+	// WebhookDeliveries lists the most recent webhook deliveries received for this repository, oldest first. Only the
+	// last MaxWebhookDeliveries deliveries are retained, and only deliveries authenticated by the repository's webhook
+	// secret are listed.
+	// +kubebuilder:validation:Optional
+	WebhookDeliveries []RepositoryWebhookDelivery `json:"webhookDeliveries,omitempty"`
+
+	// WebhookDeliveryCounts is the total number of authenticated webhook deliveries received for this repository, by
+	// result.
+	// +kubebuilder:validation:Optional
+	WebhookDeliveryCounts map[string]int64 `json:"webhookDeliveryCounts,omitempty"`
+
+	// Mirror is the observed state of the repository's shared mirror, if enabled.
+	// +kubebuilder:validation:Optional
+	Mirror *RepositoryStatusMirror `json:"mirror,omitempty"`
+
+	// LastRefreshRequest is the value of the refresh annotation (see RefreshAnnotation) when the repository was last
+	// refreshed; a different annotation value signals a pending refresh request.
+	// +kubebuilder:validation:Optional
+	LastRefreshRequest string `json:"lastRefreshRequest,omitempty"`
+
 	// LastWebhookPing is the last time a successful
 	LastWebhookPing *metav1.Time `json:"lastWebhookPing,omitempty"`
 
@@ -139,6 +280,74 @@ type RepositoryStatus struct {
 	PrivateArea ConditionsInverseState `json:"privateArea,omitempty"`
 }
 
+// RepositoryWebhookDelivery records a single webhook delivery received for the repository.
+type RepositoryWebhookDelivery struct {
+
+	// ID is the delivery ID assigned by the sender (e.g. the "X-GitHub-Delivery" header).
+	// +kubebuilder:validation:Optional
+	ID string `json:"id,omitempty"`
+
+	// Event is the type of event delivered (e.g. "push" or "pull_request").
+	// +kubebuilder:validation:Required
+	Event string `json:"event"`
+
+	// Ref is the branch or tag the event refers to, if any.
+	// +kubebuilder:validation:Optional
+	Ref string `json:"ref,omitempty"`
+
+	// Result is the outcome of the delivery for this repository (e.g. "Accepted" or "Replayed").
+	// +kubebuilder:validation:Required
+	Result string `json:"result"`
+
+	// StatusCode is the HTTP status code returned to the sender.
+	// +kubebuilder:validation:Optional
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// ReceivedAt is the time the delivery was received.
+	// +kubebuilder:validation:Required
+	ReceivedAt metav1.Time `json:"receivedAt"`
+
+	// Latency is the time it took to handle the delivery.
+	// +kubebuilder:validation:Optional
+	Latency metav1.Duration `json:"latency,omitempty"`
+}
+
+// RepositoryPullRequest represents a single open pull request in the repository.
+type RepositoryPullRequest struct {
+
+	// Number is the pull request number.
+	// +kubebuilder:validation:Required
+	Number int `json:"number"`
+
+	// HeadBranch is the name of the branch the pull request wants to merge.
+	// +kubebuilder:validation:Required
+	HeadBranch string `json:"headBranch"`
+
+	// HeadSHA is the commit SHA of the head branch, as known to the pull request.
+	// +kubebuilder:validation:Required
+	HeadSHA string `json:"headSHA"`
+
+	// Labels is the list of label names attached to the pull request.
+	// +kubebuilder:validation:Optional
+	Labels []string `json:"labels,omitempty"`
+}
+
+// RepositoryStatusMirror represents the observed state of a repository's shared mirror.
+type RepositoryStatusMirror struct {
+
+	// PersistentVolumeClaimName is the name of the persistent volume claim housing the mirror.
+	// +kubebuilder:validation:Optional
+	PersistentVolumeClaimName string `json:"persistentVolumeClaimName,omitempty"`
+
+	// FetchJobName is the name of the currently running fetch job, if any.
+	// +kubebuilder:validation:Optional
+	FetchJobName string `json:"fetchJobName,omitempty"`
+
+	// FetchedRevisions is the map of branch names to revisions that the mirror was last updated for.
+	// +kubebuilder:validation:Optional
+	FetchedRevisions map[string]string `json:"fetchedRevisions,omitempty"`
+}
+
 type RepositoryStatusPrivateArea struct {
 	Initialized   string `json:"-"`
 	Finalized     string `json:"-"`
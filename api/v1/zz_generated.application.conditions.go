@@ -361,6 +361,58 @@ func (s *ApplicationStatus) SetMaybeInvalidDueToInvalidBranchSpecification(messa
 	return changed
 }
 
+func (s *ApplicationStatus) SetInvalidDueToInvalidImagesTimeout(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Valid]; !ok || v != "No: "+InvalidImagesTimeout {
+		s.PrivateArea[Valid] = "No: " + InvalidImagesTimeout
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Invalid, v1.ConditionTrue, InvalidImagesTimeout, message, args...) || changed
+	return changed
+}
+
+func (s *ApplicationStatus) SetMaybeInvalidDueToInvalidImagesTimeout(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Valid]; !ok || v != "No: "+InvalidImagesTimeout {
+		s.PrivateArea[Valid] = "No: " + InvalidImagesTimeout
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Invalid, v1.ConditionUnknown, InvalidImagesTimeout, message, args...) || changed
+	return changed
+}
+
+func (s *ApplicationStatus) SetInvalidDueToInvalidVersionConstraint(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Valid]; !ok || v != "No: "+InvalidVersionConstraint {
+		s.PrivateArea[Valid] = "No: " + InvalidVersionConstraint
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Invalid, v1.ConditionTrue, InvalidVersionConstraint, message, args...) || changed
+	return changed
+}
+
+func (s *ApplicationStatus) SetMaybeInvalidDueToInvalidVersionConstraint(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Valid]; !ok || v != "No: "+InvalidVersionConstraint {
+		s.PrivateArea[Valid] = "No: " + InvalidVersionConstraint
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Invalid, v1.ConditionUnknown, InvalidVersionConstraint, message, args...) || changed
+	return changed
+}
+
 func (s *ApplicationStatus) SetValidIfInvalidDueToAnyOf(reasons ...string) bool {
 	changed := false
 	changed = RemoveConditionIfReasonIsOneOf(&s.Conditions, Invalid, reasons...) || changed
@@ -390,7 +442,7 @@ func (s *ApplicationStatus) SetValid() bool {
 		s.PrivateArea[Valid] = "Yes"
 		changed = true
 	}
-	changed = RemoveConditionIfReasonIsOneOf(&s.Conditions, Invalid, ControllerNotAccessible, ControllerNotFound, ControllerReferenceMissing, InternalError, InvalidBranchSpecification, "NonExistent") || changed
+	changed = RemoveConditionIfReasonIsOneOf(&s.Conditions, Invalid, ControllerNotAccessible, ControllerNotFound, ControllerReferenceMissing, InternalError, InvalidBranchSpecification, InvalidImagesTimeout, InvalidVersionConstraint, "NonExistent") || changed
 	return changed
 }
 
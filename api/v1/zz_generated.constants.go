@@ -3,6 +3,7 @@ package v1
 const (
 	ApplyFailed                    = "ApplyFailed"
 	Applying                       = "Applying"
+	AttemptsExhausted              = "AttemptsExhausted"
 	AuthSecretForbidden            = "AuthSecretForbidden"
 	AuthSecretKeyNotFound          = "AuthSecretKeyNotFound"
 	AuthSecretNotFound             = "AuthSecretNotFound"
@@ -12,6 +13,7 @@ const (
 	Baking                         = "Baking"
 	BakingFailed                   = "BakingFailed"
 	BranchNotFound                 = "BranchNotFound"
+	ChecksFailed                   = "ChecksFailed"
 	CloneFailed                    = "CloneFailed"
 	Cloning                        = "Cloning"
 	Current                        = "Current"
@@ -25,16 +27,23 @@ const (
 	Initialized                    = "Initialized"
 	Invalid                        = "Invalid"
 	InvalidBranchSpecification     = "InvalidBranchSpecification"
+	InvalidImagesTimeout           = "InvalidImagesTimeout"
 	InvalidRefreshInterval         = "InvalidRefreshInterval"
+	InvalidVersionConstraint       = "InvalidVersionConstraint"
 	PersistentVolumeCreationFailed = "PersistentVolumeCreationFailed"
 	PersistentVolumeMissing        = "PersistentVolumeMissing"
+	Queued                         = "Queued"
+	RepositoryMoved                = "RepositoryMoved"
 	RepositoryNotAccessible        = "RepositoryNotAccessible"
 	RepositoryNotFound             = "RepositoryNotFound"
 	RepositoryNotSupported         = "RepositoryNotSupported"
 	Stale                          = "Stale"
+	TagNotFound                    = "TagNotFound"
 	Unauthenticated                = "Unauthenticated"
 	UnknownRepositoryType          = "UnknownRepositoryType"
 	Valid                          = "Valid"
+	WaitingForChecks               = "WaitingForChecks"
+	WaitingForImages               = "WaitingForImages"
 	WebhookSecretEmpty             = "WebhookSecretEmpty"
 	WebhookSecretForbidden         = "WebhookSecretForbidden"
 	WebhookSecretKeyMissing        = "WebhookSecretKeyMissing"
@@ -5,8 +5,9 @@
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -74,13 +75,35 @@ func (in *ApplicationSpec) DeepCopyInto(out *ApplicationSpec) {
 	if in.Repositories != nil {
 		in, out := &in.Repositories, &out.Repositories
 		*out = make([]ApplicationSpecRepository, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Branches != nil {
 		in, out := &in.Branches, &out.Branches
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PullRequests != nil {
+		in, out := &in.PullRequests, &out.PullRequests
+		*out = new(ApplicationSpecPullRequests)
+		**out = **in
+	}
+	if in.PinnedEnvironments != nil {
+		in, out := &in.PinnedEnvironments, &out.PinnedEnvironments
+		*out = make([]ApplicationSpecPinnedEnvironment, len(*in))
+		copy(*out, *in)
+	}
+	if in.WorkVolume != nil {
+		in, out := &in.WorkVolume, &out.WorkVolume
+		*out = new(ApplicationSpecWorkVolume)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JobTemplate != nil {
+		in, out := &in.JobTemplate, &out.JobTemplate
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpec.
@@ -93,9 +116,54 @@ func (in *ApplicationSpec) DeepCopy() *ApplicationSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationSpecPinnedEnvironment) DeepCopyInto(out *ApplicationSpecPinnedEnvironment) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpecPinnedEnvironment.
+func (in *ApplicationSpecPinnedEnvironment) DeepCopy() *ApplicationSpecPinnedEnvironment {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationSpecPinnedEnvironment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationSpecPullRequests) DeepCopyInto(out *ApplicationSpecPullRequests) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpecPullRequests.
+func (in *ApplicationSpecPullRequests) DeepCopy() *ApplicationSpecPullRequests {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationSpecPullRequests)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationSpecRepository) DeepCopyInto(out *ApplicationSpecRepository) {
 	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = new(ApplicationSpecRepositoryChecks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WaitForImages != nil {
+		in, out := &in.WaitForImages, &out.WaitForImages
+		*out = new(ApplicationSpecRepositoryWaitForImages)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Clone != nil {
+		in, out := &in.Clone, &out.Clone
+		*out = new(ApplicationSpecRepositoryClone)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpecRepository.
@@ -108,6 +176,86 @@ func (in *ApplicationSpecRepository) DeepCopy() *ApplicationSpecRepository {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationSpecRepositoryChecks) DeepCopyInto(out *ApplicationSpecRepositoryChecks) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpecRepositoryChecks.
+func (in *ApplicationSpecRepositoryChecks) DeepCopy() *ApplicationSpecRepositoryChecks {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationSpecRepositoryChecks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationSpecRepositoryClone) DeepCopyInto(out *ApplicationSpecRepositoryClone) {
+	*out = *in
+	if in.SparsePaths != nil {
+		in, out := &in.SparsePaths, &out.SparsePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpecRepositoryClone.
+func (in *ApplicationSpecRepositoryClone) DeepCopy() *ApplicationSpecRepositoryClone {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationSpecRepositoryClone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationSpecRepositoryWaitForImages) DeepCopyInto(out *ApplicationSpecRepositoryWaitForImages) {
+	*out = *in
+	if in.PullSecrets != nil {
+		in, out := &in.PullSecrets, &out.PullSecrets
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpecRepositoryWaitForImages.
+func (in *ApplicationSpecRepositoryWaitForImages) DeepCopy() *ApplicationSpecRepositoryWaitForImages {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationSpecRepositoryWaitForImages)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationSpecWorkVolume) DeepCopyInto(out *ApplicationSpecWorkVolume) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpecWorkVolume.
+func (in *ApplicationSpecWorkVolume) DeepCopy() *ApplicationSpecWorkVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationSpecWorkVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationStatus) DeepCopyInto(out *ApplicationStatus) {
 	*out = *in
@@ -273,6 +421,12 @@ func (in *DeploymentStatus) DeepCopyInto(out *DeploymentStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.FailedAttempts != nil {
+		in, out := &in.FailedAttempts, &out.FailedAttempts
+		*out = new(DeploymentStatusFailedAttempts)
+		(*in).DeepCopyInto(*out)
+	}
+	out.LastRedeployRequest = in.LastRedeployRequest
 	if in.LastAppliedTime != nil {
 		in, out := &in.LastAppliedTime, &out.LastAppliedTime
 		*out = (*in).DeepCopy()
@@ -296,6 +450,47 @@ func (in *DeploymentStatus) DeepCopy() *DeploymentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentStatusFailedAttempts) DeepCopyInto(out *DeploymentStatusFailedAttempts) {
+	*out = *in
+	if in.Phases != nil {
+		in, out := &in.Phases, &out.Phases
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentStatusFailedAttempts.
+func (in *DeploymentStatusFailedAttempts) DeepCopy() *DeploymentStatusFailedAttempts {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentStatusFailedAttempts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentStatusRedeployRequest) DeepCopyInto(out *DeploymentStatusRedeployRequest) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentStatusRedeployRequest.
+func (in *DeploymentStatusRedeployRequest) DeepCopy() *DeploymentStatusRedeployRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentStatusRedeployRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Environment) DeepCopyInto(out *Environment) {
 	*out = *in
@@ -355,6 +550,21 @@ func (in *EnvironmentList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvironmentPullRequestComment) DeepCopyInto(out *EnvironmentPullRequestComment) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvironmentPullRequestComment.
+func (in *EnvironmentPullRequestComment) DeepCopy() *EnvironmentPullRequestComment {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvironmentPullRequestComment)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EnvironmentSpec) DeepCopyInto(out *EnvironmentSpec) {
 	*out = *in
@@ -380,6 +590,11 @@ func (in *EnvironmentStatus) DeepCopyInto(out *EnvironmentStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PullRequestComments != nil {
+		in, out := &in.PullRequestComments, &out.PullRequestComments
+		*out = make([]EnvironmentPullRequestComment, len(*in))
+		copy(*out, *in)
+	}
 	if in.PrivateArea != nil {
 		in, out := &in.PrivateArea, &out.PrivateArea
 		*out = make(ConditionsInverseState, len(*in))
@@ -399,6 +614,22 @@ func (in *EnvironmentStatus) DeepCopy() *EnvironmentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubRepositoryDeployKey) DeepCopyInto(out *GitHubRepositoryDeployKey) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubRepositoryDeployKey.
+func (in *GitHubRepositoryDeployKey) DeepCopy() *GitHubRepositoryDeployKey {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubRepositoryDeployKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubRepositoryPersonalAccessToken) DeepCopyInto(out *GitHubRepositoryPersonalAccessToken) {
 	*out = *in
@@ -424,6 +655,11 @@ func (in *GitHubRepositorySpec) DeepCopyInto(out *GitHubRepositorySpec) {
 		*out = new(GitHubRepositoryWebhookSecret)
 		**out = **in
 	}
+	if in.DeployKey != nil {
+		in, out := &in.DeployKey, &out.DeployKey
+		*out = new(GitHubRepositoryDeployKey)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubRepositorySpec.
@@ -541,6 +777,62 @@ func (in *RepositoryList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryMirror) DeepCopyInto(out *RepositoryMirror) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryMirror.
+func (in *RepositoryMirror) DeepCopy() *RepositoryMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPullRequest) DeepCopyInto(out *RepositoryPullRequest) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPullRequest.
+func (in *RepositoryPullRequest) DeepCopy() *RepositoryPullRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPullRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryRefreshWebhook) DeepCopyInto(out *RepositoryRefreshWebhook) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryRefreshWebhook.
+func (in *RepositoryRefreshWebhook) DeepCopy() *RepositoryRefreshWebhook {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryRefreshWebhook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RepositorySpec) DeepCopyInto(out *RepositorySpec) {
 	*out = *in
@@ -549,6 +841,16 @@ func (in *RepositorySpec) DeepCopyInto(out *RepositorySpec) {
 		*out = new(GitHubRepositorySpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RefreshWebhook != nil {
+		in, out := &in.RefreshWebhook, &out.RefreshWebhook
+		*out = new(RepositoryRefreshWebhook)
+		**out = **in
+	}
+	if in.Mirror != nil {
+		in, out := &in.Mirror, &out.Mirror
+		*out = new(RepositoryMirror)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositorySpec.
@@ -578,6 +880,39 @@ func (in *RepositoryStatus) DeepCopyInto(out *RepositoryStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PullRequests != nil {
+		in, out := &in.PullRequests, &out.PullRequests
+		*out = make([]RepositoryPullRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WebhookDeliveries != nil {
+		in, out := &in.WebhookDeliveries, &out.WebhookDeliveries
+		*out = make([]RepositoryWebhookDelivery, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WebhookDeliveryCounts != nil {
+		in, out := &in.WebhookDeliveryCounts, &out.WebhookDeliveryCounts
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Mirror != nil {
+		in, out := &in.Mirror, &out.Mirror
+		*out = new(RepositoryStatusMirror)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.LastWebhookPing != nil {
 		in, out := &in.LastWebhookPing, &out.LastWebhookPing
 		*out = (*in).DeepCopy()
@@ -601,6 +936,28 @@ func (in *RepositoryStatus) DeepCopy() *RepositoryStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryStatusMirror) DeepCopyInto(out *RepositoryStatusMirror) {
+	*out = *in
+	if in.FetchedRevisions != nil {
+		in, out := &in.FetchedRevisions, &out.FetchedRevisions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryStatusMirror.
+func (in *RepositoryStatusMirror) DeepCopy() *RepositoryStatusMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryStatusMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RepositoryStatusPrivateArea) DeepCopyInto(out *RepositoryStatusPrivateArea) {
 	*out = *in
@@ -616,6 +973,23 @@ func (in *RepositoryStatusPrivateArea) DeepCopy() *RepositoryStatusPrivateArea {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryWebhookDelivery) DeepCopyInto(out *RepositoryWebhookDelivery) {
+	*out = *in
+	in.ReceivedAt.DeepCopyInto(&out.ReceivedAt)
+	out.Latency = in.Latency
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryWebhookDelivery.
+func (in *RepositoryWebhookDelivery) DeepCopy() *RepositoryWebhookDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryWebhookDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretReferenceWithOptionalNamespace) DeepCopyInto(out *SecretReferenceWithOptionalNamespace) {
 	*out = *in
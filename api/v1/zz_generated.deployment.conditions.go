@@ -470,6 +470,32 @@ func (s *DeploymentStatus) SetMaybeStaleDueToApplying(message string, args ...in
 	return changed
 }
 
+func (s *DeploymentStatus) SetStaleDueToAttemptsExhausted(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+AttemptsExhausted {
+		s.PrivateArea[Current] = "No: " + AttemptsExhausted
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionTrue, AttemptsExhausted, message, args...) || changed
+	return changed
+}
+
+func (s *DeploymentStatus) SetMaybeStaleDueToAttemptsExhausted(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+AttemptsExhausted {
+		s.PrivateArea[Current] = "No: " + AttemptsExhausted
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionUnknown, AttemptsExhausted, message, args...) || changed
+	return changed
+}
+
 func (s *DeploymentStatus) SetStaleDueToBaking(message string, args ...interface{}) bool {
 	changed := false
 	if s.PrivateArea == nil {
@@ -548,6 +574,32 @@ func (s *DeploymentStatus) SetMaybeStaleDueToBranchNotFound(message string, args
 	return changed
 }
 
+func (s *DeploymentStatus) SetStaleDueToChecksFailed(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+ChecksFailed {
+		s.PrivateArea[Current] = "No: " + ChecksFailed
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionTrue, ChecksFailed, message, args...) || changed
+	return changed
+}
+
+func (s *DeploymentStatus) SetMaybeStaleDueToChecksFailed(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+ChecksFailed {
+		s.PrivateArea[Current] = "No: " + ChecksFailed
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionUnknown, ChecksFailed, message, args...) || changed
+	return changed
+}
+
 func (s *DeploymentStatus) SetStaleDueToCloneFailed(message string, args ...interface{}) bool {
 	changed := false
 	if s.PrivateArea == nil {
@@ -704,6 +756,32 @@ func (s *DeploymentStatus) SetMaybeStaleDueToPersistentVolumeMissing(message str
 	return changed
 }
 
+func (s *DeploymentStatus) SetStaleDueToQueued(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+Queued {
+		s.PrivateArea[Current] = "No: " + Queued
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionTrue, Queued, message, args...) || changed
+	return changed
+}
+
+func (s *DeploymentStatus) SetMaybeStaleDueToQueued(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+Queued {
+		s.PrivateArea[Current] = "No: " + Queued
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionUnknown, Queued, message, args...) || changed
+	return changed
+}
+
 func (s *DeploymentStatus) SetStaleDueToRepositoryNotAccessible(message string, args ...interface{}) bool {
 	changed := false
 	if s.PrivateArea == nil {
@@ -756,6 +834,84 @@ func (s *DeploymentStatus) SetMaybeStaleDueToRepositoryNotFound(message string,
 	return changed
 }
 
+func (s *DeploymentStatus) SetStaleDueToTagNotFound(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+TagNotFound {
+		s.PrivateArea[Current] = "No: " + TagNotFound
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionTrue, TagNotFound, message, args...) || changed
+	return changed
+}
+
+func (s *DeploymentStatus) SetMaybeStaleDueToTagNotFound(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+TagNotFound {
+		s.PrivateArea[Current] = "No: " + TagNotFound
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionUnknown, TagNotFound, message, args...) || changed
+	return changed
+}
+
+func (s *DeploymentStatus) SetStaleDueToWaitingForChecks(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+WaitingForChecks {
+		s.PrivateArea[Current] = "No: " + WaitingForChecks
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionTrue, WaitingForChecks, message, args...) || changed
+	return changed
+}
+
+func (s *DeploymentStatus) SetMaybeStaleDueToWaitingForChecks(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+WaitingForChecks {
+		s.PrivateArea[Current] = "No: " + WaitingForChecks
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionUnknown, WaitingForChecks, message, args...) || changed
+	return changed
+}
+
+func (s *DeploymentStatus) SetStaleDueToWaitingForImages(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+WaitingForImages {
+		s.PrivateArea[Current] = "No: " + WaitingForImages
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionTrue, WaitingForImages, message, args...) || changed
+	return changed
+}
+
+func (s *DeploymentStatus) SetMaybeStaleDueToWaitingForImages(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Current]; !ok || v != "No: "+WaitingForImages {
+		s.PrivateArea[Current] = "No: " + WaitingForImages
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Stale, v1.ConditionUnknown, WaitingForImages, message, args...) || changed
+	return changed
+}
+
 func (s *DeploymentStatus) SetCurrentIfStaleDueToAnyOf(reasons ...string) bool {
 	changed := false
 	changed = RemoveConditionIfReasonIsOneOf(&s.Conditions, Stale, reasons...) || changed
@@ -785,7 +941,7 @@ func (s *DeploymentStatus) SetCurrent() bool {
 		s.PrivateArea[Current] = "Yes"
 		changed = true
 	}
-	changed = RemoveConditionIfReasonIsOneOf(&s.Conditions, Stale, ApplyFailed, Applying, Baking, BakingFailed, BranchNotFound, CloneFailed, Cloning, InternalError, Invalid, PersistentVolumeCreationFailed, PersistentVolumeMissing, RepositoryNotAccessible, RepositoryNotFound, "NonExistent") || changed
+	changed = RemoveConditionIfReasonIsOneOf(&s.Conditions, Stale, ApplyFailed, Applying, AttemptsExhausted, Baking, BakingFailed, BranchNotFound, ChecksFailed, CloneFailed, Cloning, InternalError, Invalid, PersistentVolumeCreationFailed, PersistentVolumeMissing, Queued, RepositoryNotAccessible, RepositoryNotFound, TagNotFound, WaitingForChecks, WaitingForImages, "NonExistent") || changed
 	return changed
 }
 
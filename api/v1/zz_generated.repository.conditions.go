@@ -361,6 +361,32 @@ func (s *RepositoryStatus) SetMaybeInvalidDueToInvalidRefreshInterval(message st
 	return changed
 }
 
+func (s *RepositoryStatus) SetInvalidDueToRepositoryMoved(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Valid]; !ok || v != "No: "+RepositoryMoved {
+		s.PrivateArea[Valid] = "No: " + RepositoryMoved
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Invalid, v1.ConditionTrue, RepositoryMoved, message, args...) || changed
+	return changed
+}
+
+func (s *RepositoryStatus) SetMaybeInvalidDueToRepositoryMoved(message string, args ...interface{}) bool {
+	changed := false
+	if s.PrivateArea == nil {
+		s.PrivateArea = make(map[string]string)
+	}
+	if v, ok := s.PrivateArea[Valid]; !ok || v != "No: "+RepositoryMoved {
+		s.PrivateArea[Valid] = "No: " + RepositoryMoved
+		changed = true
+	}
+	changed = SetCondition(&s.Conditions, Invalid, v1.ConditionUnknown, RepositoryMoved, message, args...) || changed
+	return changed
+}
+
 func (s *RepositoryStatus) SetInvalidDueToUnknownRepositoryType(message string, args ...interface{}) bool {
 	changed := false
 	if s.PrivateArea == nil {
@@ -598,7 +624,7 @@ func (s *RepositoryStatus) SetValid() bool {
 		s.PrivateArea[Valid] = "Yes"
 		changed = true
 	}
-	changed = RemoveConditionIfReasonIsOneOf(&s.Conditions, Invalid, ControllerNotAccessible, ControllerNotFound, ControllerReferenceMissing, InternalError, InvalidRefreshInterval, UnknownRepositoryType, WebhookSecretEmpty, WebhookSecretForbidden, WebhookSecretKeyMissing, WebhookSecretKeyNotFound, WebhookSecretNameMissing, WebhookSecretNotFound, WebhooksNotEnabled, "NonExistent") || changed
+	changed = RemoveConditionIfReasonIsOneOf(&s.Conditions, Invalid, ControllerNotAccessible, ControllerNotFound, ControllerReferenceMissing, InternalError, InvalidRefreshInterval, RepositoryMoved, UnknownRepositoryType, WebhookSecretEmpty, WebhookSecretForbidden, WebhookSecretKeyMissing, WebhookSecretKeyNotFound, WebhookSecretNameMissing, WebhookSecretNotFound, WebhooksNotEnabled, "NonExistent") || changed
 	return changed
 }
 
@@ -12,6 +12,7 @@ import (
 	"github.com/rs/zerolog/log"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -34,11 +35,23 @@ import (
 )
 
 type Action struct {
-	JobsLogLevel         string `required:"true" desc:"Log level to use for the clone, bake and apply jobs."`
-	MetricsAddr          string `required:"true" desc:"Address the metrics endpoint should bind to."`
-	HealthProbeAddr      string `required:"true" desc:"Address the health endpoint should bind to"`
-	EnableLeaderElection bool   `desc:"Enable leader election, ensuring only one controller is active"`
-	GithubWebhooksURL    string `desc:"Base URL (host & port without trailing slash) of GitHub webhooks URLs."`
+	JobsLogLevel           string `required:"true" desc:"Log level to use for the clone, bake and apply jobs."`
+	MetricsAddr            string `required:"true" desc:"Address the metrics endpoint should bind to."`
+	HealthProbeAddr        string `required:"true" desc:"Address the health endpoint should bind to"`
+	EnableLeaderElection   bool   `desc:"Enable leader election, ensuring only one controller is active"`
+	GithubWebhooksURL      string `desc:"Base URL (host & port without trailing slash) of GitHub webhooks URLs."`
+	GithubAPIURL           string `desc:"Base URL of the GitHub API (for GitHub Enterprise); defaults to the public GitHub API."`
+	WorkVolumeMode         string `desc:"Default work volume mode of deployments (PersistentVolumeClaim or EmptyDir)."`
+	WorkVolumeStorageClass string `desc:"Default storage class of deployments work volume claims; defaults to the cluster's default storage class."`
+	WorkVolumeSize         string `desc:"Default size of deployments work volumes."`
+	WorkVolumeAccessMode   string `desc:"Default access mode of deployments work volume claims."`
+	JobTemplateFile        string `desc:"YAML file of a partial Job merged onto all clone, bake & apply jobs."`
+	ExecutionMode          string `desc:"Default execution mode of deployments (Jobs or SinglePod)."`
+	MaxAttempts            int    `desc:"Default number of failed attempts of each deployment phase before giving up on a revision."`
+	CancellationPolicy     string `desc:"Default policy for active jobs of superseded revisions (Wait, Cancel or CancelUnlessApplying)."`
+	MaxJobs                int    `desc:"Maximum number of concurrently running deployment jobs (0 for unlimited)."`
+	MaxJobsPerApplication  int    `desc:"Default maximum number of concurrently running deployment jobs per application (0 for unlimited)."`
+	MaxJobsPerRepository   int    `desc:"Default maximum number of concurrently running deployment jobs per repository (0 for unlimited)."`
 }
 
 func (e *Action) Run(ctx context.Context) error {
@@ -84,8 +97,19 @@ func (e *Action) Run(ctx context.Context) error {
 		log.Fatal().Err(err).Msg("Failed to create deployment index")
 	}
 
+	// Create GitHub clients factory
+	var gitHubClientFactory controller.GitHubClientFactory
+	if e.GithubAPIURL != "" {
+		gitHubClientFactory = controller.NewGitHubClientFactoryForURL(e.GithubAPIURL)
+	}
+
 	// Create & register application controller
-	repositoryReconciler := &controller.RepositoryReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme(), GitHubWebhookURL: e.GithubWebhooksURL}
+	repositoryReconciler := &controller.RepositoryReconciler{
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		GitHubWebhookURL:    e.GithubWebhooksURL,
+		GitHubClientFactory: gitHubClientFactory,
+	}
 	if err := repositoryReconciler.SetupWithManager(mgr); err != nil {
 		log.Fatal().Err(err).Msg("Unable to create repository controller")
 	}
@@ -97,17 +121,59 @@ func (e *Action) Run(ctx context.Context) error {
 	}
 
 	// Create & register environment controller
-	environmentReconciler := &controller.EnvironmentReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}
+	environmentReconciler := &controller.EnvironmentReconciler{
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		GitHubClientFactory: gitHubClientFactory,
+	}
 	if err := environmentReconciler.SetupWithManager(mgr); err != nil {
 		log.Fatal().Err(err).Msg("Unable to create environment controller")
 	}
 
 	// Create & register environment controller
 	deploymentReconciler := &controller.DeploymentReconciler{
-		Client:             mgr.GetClient(),
-		Scheme:             mgr.GetScheme(),
-		DisableJSONLogging: false,
-		LogLevel:           e.JobsLogLevel,
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		DisableJSONLogging:  false,
+		LogLevel:            e.JobsLogLevel,
+		GitHubClientFactory: gitHubClientFactory,
+		DefaultWorkVolume: apiv1.ApplicationSpecWorkVolume{
+			Mode:             e.WorkVolumeMode,
+			StorageClassName: e.WorkVolumeStorageClass,
+			AccessMode:       v1.PersistentVolumeAccessMode(e.WorkVolumeAccessMode),
+		},
+		DefaultExecutionMode:                   e.ExecutionMode,
+		DefaultMaxAttempts:                     e.MaxAttempts,
+		DefaultCancellationPolicy:              e.CancellationPolicy,
+		MaxConcurrentJobs:                      e.MaxJobs,
+		DefaultMaxConcurrentJobsPerApplication: e.MaxJobsPerApplication,
+		DefaultMaxConcurrentJobsPerRepository:  e.MaxJobsPerRepository,
+	}
+	switch e.WorkVolumeMode {
+	case "", apiv1.PersistentVolumeClaimWorkVolumeMode, apiv1.EmptyDirWorkVolumeMode:
+	default:
+		log.Fatal().Str("mode", e.WorkVolumeMode).Msg("Invalid work volume mode")
+	}
+	switch v1.PersistentVolumeAccessMode(e.WorkVolumeAccessMode) {
+	case "", v1.ReadWriteOnce, v1.ReadWriteMany, v1.ReadWriteOncePod:
+	default:
+		log.Fatal().Str("accessMode", e.WorkVolumeAccessMode).Msg("Invalid work volume access mode")
+	}
+	if e.WorkVolumeSize != "" {
+		size, err := resource.ParseQuantity(e.WorkVolumeSize)
+		if err != nil {
+			log.Fatal().Err(err).Str("size", e.WorkVolumeSize).Msg("Invalid work volume size")
+		}
+		deploymentReconciler.DefaultWorkVolume.Size = &size
+	}
+	if e.JobTemplateFile != "" {
+		data, err := os.ReadFile(e.JobTemplateFile)
+		if err != nil {
+			log.Fatal().Err(err).Str("file", e.JobTemplateFile).Msg("Failed reading job template")
+		}
+		if deploymentReconciler.JobTemplate, err = controller.ParseJobTemplate(data); err != nil {
+			log.Fatal().Err(err).Str("file", e.JobTemplateFile).Msg("Invalid job template")
+		}
 	}
 	if err := deploymentReconciler.SetupWithManager(mgr); err != nil {
 		log.Fatal().Err(err).Msg("Unable to create deployment controller")
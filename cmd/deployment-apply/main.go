@@ -2,15 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/arikkfir/command"
 	"github.com/rs/zerolog/log"
 
 	"github.com/arikkfir/devbot/internal/util/observability"
+	"github.com/arikkfir/devbot/internal/util/registry"
+	"github.com/arikkfir/devbot/internal/util/termination"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -20,6 +26,9 @@ import (
 const (
 	// kubectlBinaryFilePath is the path to the kubectl binary.
 	kubectlBinaryFilePath = "/usr/local/bin/kubectl"
+
+	// imagesPollInterval is how often registries are polled for missing images.
+	imagesPollInterval = 5 * time.Second
 )
 
 type Action struct {
@@ -27,6 +36,9 @@ type Action struct {
 	EnvironmentName string `required:"true" desc:"Kubernetes Environment object name."`
 	DeploymentName  string `required:"true" desc:"Kubernetes Deployment object name."`
 	ManifestFile    string `required:"true" desc:"Target file to write resources YAML manifest to."`
+	WaitForImages   bool   `desc:"Wait for container images referenced by the manifest to exist before applying it."`
+	ImagesTimeout   string `desc:"How long to wait for container images to exist (e.g. 2m)."`
+	PullSecretsDir  string `desc:"Directory containing Docker config files with registry credentials."`
 }
 
 func (e *Action) Run(ctx context.Context) error {
@@ -37,6 +49,13 @@ func (e *Action) Run(ctx context.Context) error {
 		Str("outputManifest", e.ManifestFile).
 		Logger()
 
+	// Wait for container images referenced by the manifest, if requested
+	if e.WaitForImages {
+		if err := e.waitForImages(ctx); err != nil {
+			return err
+		}
+	}
+
 	// Create the apply command
 	cmd := exec.CommandContext(ctx, kubectlBinaryFilePath,
 		"apply",
@@ -44,7 +63,8 @@ func (e *Action) Run(ctx context.Context) error {
 		fmt.Sprintf("--server-side=%v", true),
 	)
 	cmd.Dir = filepath.Dir(e.ManifestFile)
-	cmd.Stderr = log.With().Str("output", "stderr").Logger()
+	output := termination.NewTail()
+	cmd.Stderr = io.MultiWriter(log.With().Str("output", "stderr").Logger(), output)
 	cmd.Stdout = log.With().Str("output", "stdout").Logger()
 
 	log.Info().Str("command", cmd.String()).Msg("Running kubectl command")
@@ -53,12 +73,65 @@ func (e *Action) Run(ctx context.Context) error {
 	}
 
 	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("failed running kubectl command: %w", err)
+		return termination.StepFailed("kubectl apply", err, output)
 	}
 
 	return nil
 }
 
+// waitForImages polls the registries of all container images referenced by the manifest, until they all exist. If the
+// timeout expires first, an error listing the missing images is returned (and written to the termination message).
+func (e *Action) waitForImages(ctx context.Context) error {
+	timeout := 2 * time.Minute
+	if e.ImagesTimeout != "" {
+		if d, err := time.ParseDuration(e.ImagesTimeout); err != nil {
+			return fmt.Errorf("invalid images timeout '%s': %w", e.ImagesTimeout, err)
+		} else {
+			timeout = d
+		}
+	}
+
+	manifest, err := os.ReadFile(e.ManifestFile)
+	if err != nil {
+		return fmt.Errorf("failed reading manifest: %w", err)
+	}
+	images, err := registry.ExtractImages(manifest)
+	if err != nil {
+		return fmt.Errorf("failed extracting images from manifest: %w", err)
+	}
+
+	c := &registry.Client{}
+	if e.PullSecretsDir != "" {
+		if c.Credentials, err = registry.LoadDockerConfigs(e.PullSecretsDir); err != nil {
+			return fmt.Errorf("failed loading registry credentials: %w", err)
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		missing, err := c.MissingImages(ctx, images)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed checking container images")
+			missing = images
+		} else if len(missing) == 0 {
+			log.Info().Strs("images", images).Msg("All container images exist")
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			message := registry.MissingImagesMessagePrefix + strings.Join(missing, ", ")
+			return termination.StepFailed("wait for images", errors.New(message), nil)
+		}
+
+		log.Info().Strs("missing", missing).Msg("Waiting for container images")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(imagesPollInterval):
+		}
+	}
+}
+
 func main() {
 
 	// Create command structure
@@ -71,6 +144,7 @@ kubernetes cluster, thereby deploying a repository to a given environment.'`,
 		[]any{
 			&observability.LoggingHook{LogLevel: "info"},
 			&observability.OTelHook{ServiceName: "devbot-apply-job"},
+			&observability.TerminationHook{},
 		},
 	)
 
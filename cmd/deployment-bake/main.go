@@ -15,6 +15,7 @@ import (
 	"github.com/arikkfir/devbot/internal/util/lang"
 	"github.com/arikkfir/devbot/internal/util/observability"
 	stringsutil "github.com/arikkfir/devbot/internal/util/strings"
+	"github.com/arikkfir/devbot/internal/util/termination"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -33,24 +34,28 @@ type Action struct {
 	ActualBranch      string `required:"true" desc:"Git branch serving as a default, in case the preferred branch was missing."`
 	ApplicationName   string `required:"true" desc:"Kubernetes Application object name."`
 	BaseDeployDir     string `required:"true" desc:"Base directory Directory holding the Kustomize overlay to build."`
+	Environment       string `required:"true" desc:"Name of the environment within the application (preferred branch or pinned environment name)."`
 	EnvironmentName   string `required:"true" desc:"Kubernetes Environment object name."`
 	DeploymentName    string `required:"true" desc:"Kubernetes Deployment object name."`
 	ManifestFile      string `required:"true" desc:"Target file to write resources YAML manifest to."`
-	PreferredBranch   string `required:"true" desc:"Git branch preferred for baking, if it exists."`
+	PreferredBranch   string `desc:"Git branch preferred for baking, if it exists (empty for pinned environments)."`
 	RepoDefaultBranch string `required:"true" desc:"The default branch of the repository being deployed."`
 	SHA               string `required:"true" desc:"Commit SHA to checkout."`
+	Tag               string `desc:"Git tag being deployed, if any."`
 }
 
 func (e *Action) Run(ctx context.Context) error {
 	log.Logger = log.With().
 		Str("actualBranch", e.ActualBranch).
 		Str("appName", e.ApplicationName).
+		Str("environment", e.Environment).
 		Str("envName", e.EnvironmentName).
 		Str("deploymentName", e.DeploymentName).
 		Str("baseDeployDir", e.BaseDeployDir).
 		Str("manifestFile", e.ManifestFile).
 		Str("preferredBranch", e.PreferredBranch).
 		Str("sha", e.SHA).
+		Str("tag", e.Tag).
 		Logger()
 
 	// Create target resources file
@@ -66,7 +71,7 @@ func (e *Action) Run(ctx context.Context) error {
 	// This command produces resources from the kustomization file and outputs them to stdout
 	var kustomizeCmd *exec.Cmd
 	searchPaths := lang.Uniq([]string{
-		filepath.Join(e.BaseDeployDir, e.PreferredBranch),
+		filepath.Join(e.BaseDeployDir, e.Environment),
 		filepath.Join(e.BaseDeployDir, e.ActualBranch),
 		filepath.Join(e.BaseDeployDir, e.RepoDefaultBranch),
 		e.BaseDeployDir,
@@ -82,7 +87,7 @@ func (e *Action) Run(ctx context.Context) error {
 		}
 	}
 	if kustomizeCmd == nil {
-		return fmt.Errorf("failed finding Kustomize in any of: %v", searchPaths)
+		return termination.StepFailed("find kustomization", fmt.Errorf("no kustomization in any of: %v", searchPaths), nil)
 	}
 	kustomizeLogger := log.With().
 		Str("command", kustomizeBinaryFilePath).
@@ -91,7 +96,8 @@ func (e *Action) Run(ctx context.Context) error {
 		Strs("args", kustomizeCmd.Args).
 		Str("output", "stderr").
 		Logger()
-	kustomizeCmd.Stderr = kustomizeLogger
+	kustomizeOutput := termination.NewTail()
+	kustomizeCmd.Stderr = io.MultiWriter(kustomizeLogger, kustomizeOutput)
 	kustomizeCmd.Stdout = pipeWriter
 	if err := kustomizeCmd.Start(); err != nil {
 		return fmt.Errorf("failed starting kustomize command: %w", err)
@@ -104,8 +110,9 @@ func (e *Action) Run(ctx context.Context) error {
 		"ACTUAL_BRANCH="+stringsutil.Slugify(e.ActualBranch),
 		"APPLICATION="+stringsutil.Slugify(e.ApplicationName),
 		"COMMIT_SHA="+e.SHA,
-		"ENVIRONMENT="+stringsutil.Slugify(e.PreferredBranch),
+		"ENVIRONMENT="+stringsutil.Slugify(e.Environment),
 		"PREFERRED_BRANCH="+stringsutil.Slugify(e.PreferredBranch),
+		"TAG="+e.Tag,
 	)
 	yqLogger := log.With().
 		Str("command", yqBinaryFilePath).
@@ -116,7 +123,8 @@ func (e *Action) Run(ctx context.Context) error {
 	yqStderrLogger := yqLogger.With().Str("output", "stderr").Logger()
 	yqStdoutLogger := yqLogger.With().Str("output", "stdout").Logger()
 	yqCmd.Stdin = pipeReader
-	yqCmd.Stderr = yqStderrLogger
+	yqOutput := termination.NewTail()
+	yqCmd.Stderr = io.MultiWriter(yqStderrLogger, yqOutput)
 	yqCmd.Stdout = io.MultiWriter(resourcesFile, yqStdoutLogger)
 	if err := yqCmd.Start(); err != nil {
 		return fmt.Errorf("failed starting yq command: %w", err)
@@ -124,14 +132,14 @@ func (e *Action) Run(ctx context.Context) error {
 
 	// Wait for kustomize command to finish
 	if err := kustomizeCmd.Wait(); err != nil {
-		return fmt.Errorf("failed running kustomize command: %w", err)
+		return termination.StepFailed("kustomize build", err, kustomizeOutput)
 	} else if err := pipeWriter.Close(); err != nil {
 		return fmt.Errorf("failed closing connecting pipe between Kustomize and YQ: %w", err)
 	}
 
 	// Wait for yq command to finish
 	if err := yqCmd.Wait(); err != nil {
-		return fmt.Errorf("failed running YQ command: %w", err)
+		return termination.StepFailed("yq envsubst", err, yqOutput)
 	}
 
 	return nil
@@ -149,6 +157,7 @@ in preparation for deployment into an environment.'`,
 		[]any{
 			&observability.LoggingHook{LogLevel: "info"},
 			&observability.OTelHook{ServiceName: "devbot-bake-job"},
+			&observability.TerminationHook{},
 		},
 	)
 
@@ -2,17 +2,37 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/arikkfir/command"
+	gitutil "github.com/arikkfir/devbot/internal/util/git"
 	"github.com/arikkfir/devbot/internal/util/observability"
+	"github.com/arikkfir/devbot/internal/util/termination"
+	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/filesystem"
 	"github.com/rs/zerolog/log"
+	gossh "golang.org/x/crypto/ssh"
+	"io"
+	"maps"
+	"math"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -20,9 +40,403 @@ import (
 )
 
 type Action struct {
-	Branch string `required:"true" desc:"Git branch to checkout."`
-	GitURL string `required:"true" desc:"Git URL."`
-	SHA    string `required:"true" desc:"Commit SHA to checkout."`
+	Branch         string `desc:"Git branch to checkout (required unless a tag is given)."`
+	CredentialsDir string `desc:"Directory containing the Git credentials: a token, or an SSH private key and known hosts."`
+	Depth          int    `desc:"Limit fetched history to the given number of commits (0 fetches the full history)."`
+	GitURL         string `required:"true" desc:"Git URL."`
+	LFS            bool   `desc:"Fetch Git LFS objects of the checked out revision."`
+	Mirror         bool   `desc:"Update the bare mirror in the mirror directory instead of cloning."`
+	MirrorDir      string `desc:"Directory of a bare mirror of the repository to borrow objects from, if any."`
+	SHA            string `desc:"Commit SHA to checkout (required unless updating the mirror)."`
+	SparseDirs     string `desc:"Comma-separated list of directories to check out; if empty, the whole tree is checked out."`
+	Submodules     bool   `desc:"Recursively initialize & update submodules of the checked out revision."`
+	Tag            string `desc:"Git tag to checkout (required unless a branch is given)."`
+}
+
+// auth returns the authentication method to use with the remote, based on the credentials found in the credentials
+// directory: an SSH private key (verified against the known hosts file next to it), or a token. If no credentials are
+// available, the remote is accessed anonymously.
+func (e *Action) auth() (transport.AuthMethod, error) {
+	if e.CredentialsDir == "" {
+		return nil, nil
+	}
+
+	if privateKey, err := os.ReadFile(filepath.Join(e.CredentialsDir, "ssh-privatekey")); err == nil {
+		publicKeys, err := ssh.NewPublicKeys("git", privateKey, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing SSH private key: %w", err)
+		}
+		hostKeyCallback, err := ssh.NewKnownHostsCallback(filepath.Join(e.CredentialsDir, "known_hosts"))
+		if err != nil {
+			return nil, fmt.Errorf("failed reading known hosts: %w", err)
+		}
+		publicKeys.HostKeyCallback = hostKeyCallback
+		return publicKeys, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed reading SSH private key: %w", err)
+	}
+
+	if token, err := os.ReadFile(filepath.Join(e.CredentialsDir, "token")); err == nil {
+		return &http.BasicAuth{Username: "x-access-token", Password: strings.TrimSpace(string(token))}, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed reading token: %w", err)
+	}
+
+	return nil, nil
+}
+
+// openRepository opens the repository in the given directory. Alternates (e.g. the mirror) are given as absolute paths,
+// hence they are resolved against the root filesystem rather than the repository directory.
+func openRepository(dir string) (*git.Repository, error) {
+	storage := filesystem.NewStorageWithOptions(
+		osfs.New(filepath.Join(dir, git.GitDirName)),
+		cache.NewObjectLRUDefault(),
+		filesystem.Options{AlternatesFS: osfs.New("/")},
+	)
+	return git.Open(storage, osfs.New(dir))
+}
+
+// clone clones the repository into the given directory. If a mirror is available, the clone borrows its objects rather
+// than copying them, and only objects missing from the mirror are fetched from the remote. For shallow clones, only the
+// remote is configured, leaving the fetching of the deployed ref (up to the configured depth) to the caller.
+func (e *Action) clone(ctx context.Context, dir string, cloneOptions *git.CloneOptions) error {
+	useMirror := false
+	if e.MirrorDir != "" {
+		if _, err := os.Stat(filepath.Join(e.MirrorDir, "objects")); errors.Is(err, os.ErrNotExist) {
+			log.Warn().Str("mirrorDir", e.MirrorDir).Msg("Mirror not populated yet, cloning from remote")
+		} else if err != nil {
+			return fmt.Errorf("failed inspecting mirror: %w", err)
+		} else {
+			useMirror = true
+		}
+	}
+	if !useMirror && e.Depth == 0 {
+		_, err := git.PlainCloneContext(ctx, dir, false, cloneOptions)
+		return err
+	}
+
+	gitRepo, err := git.PlainInit(dir, false)
+	if err != nil {
+		return fmt.Errorf("failed initializing repository: %w", err)
+	}
+	if useMirror {
+		if err := gitRepo.Storer.AddAlternate(e.MirrorDir); err != nil {
+			return fmt.Errorf("failed adding mirror as alternate: %w", err)
+		}
+	}
+	if _, err := gitRepo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{e.GitURL}}); err != nil {
+		return fmt.Errorf("failed creating remote: %w", err)
+	}
+	return nil
+}
+
+// checkoutSparsely checks out only the given directories of the given commit into the worktree at the given directory,
+// detaching HEAD at that commit. The checkout is done manually, since go-git's sparse checkout still writes the entire
+// tree into fresh worktrees. The index is reset, as it would not reflect the partial worktree anyway; it only records
+// the submodules found in the sparse directories (along with the ".gitmodules" file), so they can be updated later.
+func checkoutSparsely(gitRepo *git.Repository, dir string, hash plumbing.Hash, sparseDirs []string) error {
+	commit, err := gitRepo.CommitObject(hash)
+	if err != nil {
+		return fmt.Errorf("failed looking up commit: %w", err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed looking up commit tree: %w", err)
+	}
+
+	// Clear the worktree
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed reading directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != git.GitDirName {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return fmt.Errorf("failed removing file: %w", err)
+			}
+		}
+	}
+
+	// Write the files of each sparse directory
+	for _, sparseDir := range sparseDirs {
+		subtree, err := tree.Tree(sparseDir)
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			log.Warn().Str("dir", sparseDir).Msg("Sparse directory not found in revision")
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed looking up directory '%s': %w", sparseDir, err)
+		}
+		if err := subtree.Files().ForEach(func(f *object.File) error {
+			name := path.Join(sparseDir, f.Name)
+			for _, segment := range strings.Split(name, "/") {
+				if segment == ".." || segment == git.GitDirName {
+					return fmt.Errorf("invalid path '%s'", name)
+				}
+			}
+			target := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("failed creating directory of '%s': %w", name, err)
+			}
+
+			contents, err := f.Reader()
+			if err != nil {
+				return fmt.Errorf("failed reading '%s': %w", name, err)
+			}
+			defer contents.Close()
+
+			if f.Mode == filemode.Symlink {
+				link, err := io.ReadAll(contents)
+				if err != nil {
+					return fmt.Errorf("failed reading '%s': %w", name, err)
+				}
+				return os.Symlink(string(link), target)
+			}
+
+			perm := os.FileMode(0644)
+			if f.Mode == filemode.Executable {
+				perm = 0755
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+			if err != nil {
+				return fmt.Errorf("failed creating '%s': %w", name, err)
+			}
+			defer file.Close()
+			if _, err := io.Copy(file, contents); err != nil {
+				return fmt.Errorf("failed writing '%s': %w", name, err)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Collect the submodules in the sparse directories
+	idx := &index.Index{Version: 2}
+	for _, sparseDir := range sparseDirs {
+		subtree, err := tree.Tree(sparseDir)
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed looking up directory '%s': %w", sparseDir, err)
+		}
+		walker := object.NewTreeWalker(subtree, true, nil)
+		for {
+			name, entry, err := walker.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				walker.Close()
+				return fmt.Errorf("failed walking directory '%s': %w", sparseDir, err)
+			}
+			if entry.Mode == filemode.Submodule {
+				idx.Entries = append(idx.Entries, &index.Entry{Name: path.Join(sparseDir, name), Mode: filemode.Submodule, Hash: entry.Hash})
+			}
+		}
+		walker.Close()
+	}
+	if len(idx.Entries) > 0 {
+		slices.SortFunc(idx.Entries, func(a, b *index.Entry) int { return strings.Compare(a.Name, b.Name) })
+		if gitmodules, err := tree.File(".gitmodules"); err == nil {
+			contents, err := gitmodules.Contents()
+			if err != nil {
+				return fmt.Errorf("failed reading '.gitmodules': %w", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, ".gitmodules"), []byte(contents), 0644); err != nil {
+				return fmt.Errorf("failed writing '.gitmodules': %w", err)
+			}
+		} else if !errors.Is(err, object.ErrFileNotFound) {
+			return fmt.Errorf("failed looking up '.gitmodules': %w", err)
+		}
+	}
+
+	// Detach HEAD at the commit, and reset the index
+	if err := gitRepo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, hash)); err != nil {
+		return fmt.Errorf("failed updating HEAD: %w", err)
+	}
+	if err := gitRepo.Storer.SetIndex(idx); err != nil {
+		return fmt.Errorf("failed resetting index: %w", err)
+	}
+	return nil
+}
+
+// updateSubmodules recursively initializes & updates the submodules of the checked out revision, authenticating with
+// the same credentials as the repository itself. Relative submodule URLs are resolved against the repository URL.
+// Submodules missing from the index (i.e. outside the sparse directories, if any) are skipped.
+func (e *Action) updateSubmodules(ctx context.Context, gitRepo *git.Repository, auth transport.AuthMethod, depth int) error {
+	if depth > int(git.DefaultSubmoduleRecursionDepth) {
+		return fmt.Errorf("submodules nested deeper than %d levels", git.DefaultSubmoduleRecursionDepth)
+	}
+
+	worktree, err := gitRepo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed opening worktree: %w", err)
+	}
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return fmt.Errorf("failed reading submodules: %w", err)
+	}
+	idx, err := gitRepo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed reading index: %w", err)
+	}
+
+	for _, submodule := range submodules {
+		name := submodule.Config().Name
+		if _, err := idx.Entry(submodule.Config().Path); errors.Is(err, index.ErrEntryNotFound) {
+			log.Debug().Str("submodule", name).Msg("Skipping submodule outside of checked out directories")
+			continue
+		}
+		log.Info().Str("submodule", name).Str("url", submodule.Config().URL).Msg("Updating submodule")
+		if err := submodule.Init(); err != nil && !errors.Is(err, git.ErrSubmoduleAlreadyInitialized) {
+			return fmt.Errorf("failed initializing submodule '%s': %w", name, err)
+		}
+		subRepo, err := submodule.Repository()
+		if err != nil {
+			return fmt.Errorf("failed opening submodule '%s': %w", name, err)
+		}
+
+		// Restore the worktree of previously cloned submodules, since sparse checkouts clear it, and go-git refuses to
+		// check out a new revision over missing files
+		if head, err := subRepo.Head(); err == nil {
+			subWorktree, err := subRepo.Worktree()
+			if err != nil {
+				return fmt.Errorf("failed opening worktree of submodule '%s': %w", name, err)
+			}
+			if err := subWorktree.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset}); err != nil {
+				return fmt.Errorf("failed restoring worktree of submodule '%s': %w", name, err)
+			}
+		}
+
+		updateOptions := &git.SubmoduleUpdateOptions{Auth: auth, Depth: e.Depth}
+		if err := submodule.UpdateContext(ctx, updateOptions); err != nil {
+			return fmt.Errorf("failed updating submodule '%s': %w", name, err)
+		}
+		if err := e.updateSubmodules(ctx, subRepo, auth, depth+1); err != nil {
+			return fmt.Errorf("failed updating submodules of '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// lfsEndpoint returns the Git LFS endpoint of the repository, authenticated by the given authentication method. For
+// SSH remotes, the endpoint & its credentials are obtained via the "git-lfs-authenticate" SSH command, like Git LFS
+// does.
+func (e *Action) lfsEndpoint(auth transport.AuthMethod) (gitutil.LFSEndpoint, error) {
+	endpoint, err := transport.NewEndpoint(e.GitURL)
+	if err != nil {
+		return gitutil.LFSEndpoint{}, fmt.Errorf("failed parsing Git URL: %w", err)
+	}
+
+	switch endpoint.Protocol {
+	case "http", "https":
+		lfsEndpoint := gitutil.LFSEndpointForURL(e.GitURL)
+		if basicAuth, ok := auth.(*http.BasicAuth); ok {
+			credentials := base64.StdEncoding.EncodeToString([]byte(basicAuth.Username + ":" + basicAuth.Password))
+			lfsEndpoint.Header = map[string]string{"Authorization": "Basic " + credentials}
+		}
+		return lfsEndpoint, nil
+
+	case "ssh":
+		publicKeys, ok := auth.(*ssh.PublicKeys)
+		if !ok {
+			return gitutil.LFSEndpoint{}, fmt.Errorf("SSH credentials are required for LFS over SSH")
+		}
+		clientConfig, err := publicKeys.ClientConfig()
+		if err != nil {
+			return gitutil.LFSEndpoint{}, fmt.Errorf("failed creating SSH client configuration: %w", err)
+		}
+		port := endpoint.Port
+		if port == 0 {
+			port = 22
+		}
+		client, err := gossh.Dial("tcp", net.JoinHostPort(endpoint.Host, fmt.Sprint(port)), clientConfig)
+		if err != nil {
+			return gitutil.LFSEndpoint{}, fmt.Errorf("failed connecting to '%s': %w", endpoint.Host, err)
+		}
+		defer client.Close()
+		session, err := client.NewSession()
+		if err != nil {
+			return gitutil.LFSEndpoint{}, fmt.Errorf("failed creating SSH session: %w", err)
+		}
+		defer session.Close()
+		output, err := session.Output("git-lfs-authenticate " + strings.TrimPrefix(endpoint.Path, "/") + " download")
+		if err != nil {
+			return gitutil.LFSEndpoint{}, fmt.Errorf("failed authenticating LFS over SSH: %w", err)
+		}
+		lfsEndpoint := gitutil.LFSEndpoint{}
+		if err := json.Unmarshal(output, &lfsEndpoint); err != nil {
+			return gitutil.LFSEndpoint{}, fmt.Errorf("failed parsing LFS authentication response: %w", err)
+		}
+		return lfsEndpoint, nil
+
+	default:
+		return gitutil.LFSEndpoint{}, fmt.Errorf("unsupported protocol for LFS: %s", endpoint.Protocol)
+	}
+}
+
+// fetchLFSObjects replaces the Git LFS pointer files in the worktree at the given directory with their objects.
+// Submodules are skipped, as their objects are stored in their own LFS endpoints.
+func (e *Action) fetchLFSObjects(ctx context.Context, gitRepo *git.Repository, dir string, auth transport.AuthMethod) error {
+	pointers, err := gitutil.FindLFSPointers(dir)
+	if err != nil {
+		return err
+	}
+	idx, err := gitRepo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed reading index: %w", err)
+	}
+	for _, entry := range idx.Entries {
+		if entry.Mode == filemode.Submodule {
+			submoduleDir := filepath.Join(dir, filepath.FromSlash(entry.Name)) + string(filepath.Separator)
+			maps.DeleteFunc(pointers, func(path string, _ gitutil.LFSPointer) bool { return strings.HasPrefix(path, submoduleDir) })
+		}
+	}
+	if len(pointers) == 0 {
+		return nil
+	}
+
+	endpoint, err := e.lfsEndpoint(auth)
+	if err != nil {
+		return err
+	}
+	ref := plumbing.NewBranchReferenceName(e.Branch)
+	if e.Tag != "" {
+		ref = plumbing.NewTagReferenceName(e.Tag)
+	}
+	log.Info().Int("files", len(pointers)).Msg("Fetching LFS objects")
+	return gitutil.FetchLFSObjects(ctx, nil, endpoint, ref.String(), pointers)
+}
+
+// updateMirror creates or updates the bare mirror of the repository in the mirror directory.
+func (e *Action) updateMirror(ctx context.Context, auth transport.AuthMethod) error {
+	gitRepo, err := git.PlainOpen(e.MirrorDir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		cloneOptions := &git.CloneOptions{
+			URL:      e.GitURL,
+			Auth:     auth,
+			Mirror:   true,
+			Progress: log.With().Str("process", "git").Logger(),
+		}
+		if _, err := git.PlainCloneContext(ctx, e.MirrorDir, true, cloneOptions); err != nil {
+			return termination.StepFailed("create mirror", err, nil)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed opening mirror: %w", err)
+	}
+
+	fetchOptions := &git.FetchOptions{
+		RemoteName: "origin",
+		RemoteURL:  e.GitURL,
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"},
+		Auth:       auth,
+		Progress:   log.With().Str("process", "git").Logger(),
+		Prune:      true,
+	}
+	if err := gitRepo.FetchContext(ctx, fetchOptions); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return termination.StepFailed("update mirror", err, nil)
+	}
+	return nil
 }
 
 func (e *Action) Run(ctx context.Context) error {
@@ -30,23 +444,41 @@ func (e *Action) Run(ctx context.Context) error {
 		Str("gitURL", e.GitURL).
 		Str("branch", e.Branch).
 		Str("sha", e.SHA).
+		Str("tag", e.Tag).
 		Logger()
+	auth, err := e.auth()
+	if err != nil {
+		return err
+	}
+	if e.Mirror {
+		if e.MirrorDir == "" {
+			return fmt.Errorf("mirror directory must be given")
+		}
+		return e.updateMirror(ctx, auth)
+	}
+	if e.SHA == "" {
+		return fmt.Errorf("commit SHA must be given")
+	}
+	if e.Branch == "" && e.Tag == "" {
+		return fmt.Errorf("either a branch or a tag must be given")
+	}
 	cloneOptions := &git.CloneOptions{
 		URL:      e.GitURL,
+		Auth:     auth,
 		Progress: log.With().Str("process", "git").Logger(),
 	}
 
 	// Calculate Git URL from repository
 	if _, err := os.Stat("/data/.git"); errors.Is(err, os.ErrNotExist) {
-		if _, err := git.PlainClone("/data", false, cloneOptions); err != nil {
-			return fmt.Errorf("failed cloning repository: %w", err)
+		if err := e.clone(ctx, "/data", cloneOptions); err != nil {
+			return termination.StepFailed("clone", err, nil)
 		}
 	} else if err != nil {
 		return fmt.Errorf("failed inspecting target clone directory: %w", err)
 	}
 
 	// Open the cloned repository
-	gitRepo, err := git.PlainOpen("/data")
+	gitRepo, err := openRepository("/data")
 	if err != nil {
 		return fmt.Errorf("failed opening cloned repository: %w", err)
 	}
@@ -73,22 +505,42 @@ func (e *Action) Run(ctx context.Context) error {
 				}
 			}
 		}
-		if _, err := git.PlainClone("/data", false, cloneOptions); err != nil {
-			return fmt.Errorf("failed cloning repository: %w", err)
+		if err := e.clone(ctx, "/data", cloneOptions); err != nil {
+			return termination.StepFailed("clone", err, nil)
+		}
+		if gitRepo, err = openRepository("/data"); err != nil {
+			return fmt.Errorf("failed opening cloned repository: %w", err)
 		}
 	}
 
-	// Fetch our branch
-	localBranchRefName := plumbing.NewBranchReferenceName(e.Branch)
-	remoteBranchRefName := plumbing.NewRemoteReferenceName("origin", e.Branch)
-	refSpec := fmt.Sprintf("%s:%s", localBranchRefName, remoteBranchRefName)
+	// Fetch our branch (or tag)
+	var refSpec string
+	if e.Tag != "" {
+		tagRefName := plumbing.NewTagReferenceName(e.Tag)
+		refSpec = fmt.Sprintf("+%s:%s", tagRefName, tagRefName)
+	} else {
+		localBranchRefName := plumbing.NewBranchReferenceName(e.Branch)
+		remoteBranchRefName := plumbing.NewRemoteReferenceName("origin", e.Branch)
+		refSpec = fmt.Sprintf("%s:%s", localBranchRefName, remoteBranchRefName)
+	}
 	fetchOptions := git.FetchOptions{
 		RemoteName: "origin",
+		Auth:       auth,
 		RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
+		Depth:      e.Depth,
 		Progress:   log.With().Str("process", "git").Logger(),
 	}
 	if err := gitRepo.FetchContext(ctx, &fetchOptions); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
-		return fmt.Errorf("failed fetching branch: %w", err)
+		return termination.StepFailed("fetch", err, nil)
+	}
+
+	// The revision may be missing from a shallow fetch if the ref moved on since; fetch the full history in that case
+	if _, err := gitRepo.CommitObject(plumbing.NewHash(e.SHA)); errors.Is(err, plumbing.ErrObjectNotFound) && e.Depth > 0 {
+		log.Info().Int("depth", e.Depth).Msg("Revision not within fetched depth, fetching full history")
+		fetchOptions.Depth = math.MaxInt32 // equivalent of "git fetch --unshallow"
+		if err := gitRepo.FetchContext(ctx, &fetchOptions); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return termination.StepFailed("fetch", err, nil)
+		}
 	}
 
 	// Attempt to open the worktree
@@ -97,9 +549,31 @@ func (e *Action) Run(ctx context.Context) error {
 		return fmt.Errorf("failed opening worktree: %w", err)
 	}
 
-	// Checkout the exact revision listed in the repository
-	if err := worktree.Checkout(&git.CheckoutOptions{Force: true, Keep: false, Hash: plumbing.NewHash(e.SHA)}); err != nil {
-		return fmt.Errorf("failed checking out revision: %w", err)
+	// Checkout the exact revision listed in the repository, limited to the sparse directories (if any)
+	var sparseDirs []string
+	for _, dir := range strings.Split(e.SparseDirs, ",") {
+		if dir = strings.Trim(strings.TrimSpace(dir), "/"); dir != "" {
+			sparseDirs = append(sparseDirs, dir)
+		}
+	}
+	if len(sparseDirs) > 0 {
+		if err := checkoutSparsely(gitRepo, "/data", plumbing.NewHash(e.SHA), sparseDirs); err != nil {
+			return termination.StepFailed("checkout", err, nil)
+		}
+	} else if err := worktree.Checkout(&git.CheckoutOptions{Force: true, Keep: false, Hash: plumbing.NewHash(e.SHA)}); err != nil {
+		return termination.StepFailed("checkout", err, nil)
+	}
+
+	// Bring in submodules & LFS objects, if requested
+	if e.Submodules {
+		if err := e.updateSubmodules(ctx, gitRepo, auth, 1); err != nil {
+			return termination.StepFailed("update submodules", err, nil)
+		}
+	}
+	if e.LFS {
+		if err := e.fetchLFSObjects(ctx, gitRepo, "/data", auth); err != nil {
+			return termination.StepFailed("fetch LFS objects", err, nil)
+		}
 	}
 
 	return nil
@@ -116,6 +590,7 @@ func main() {
 		[]any{
 			&observability.LoggingHook{LogLevel: "info"},
 			&observability.OTelHook{ServiceName: "devbot-clone-job"},
+			&observability.TerminationHook{},
 		},
 	)
 
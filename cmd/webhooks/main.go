@@ -45,7 +45,7 @@ func (e *Action) Run(ctx context.Context) error {
 	}
 
 	// Create the webhook handlers
-	handler, err := github.NewPushHandler(kubeConfig, scheme)
+	handler, err := github.NewPushHandler(ctx, kubeConfig, scheme)
 	if err != nil {
 		return fmt.Errorf("failed to create push handler: %w", err)
 	}
@@ -111,6 +111,7 @@ func (e *Action) newMetricsHTTPServer() *http.Server {
 func (e *Action) newWebhooksHTTPServer(handler *github.PushHandler) *http.Server {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/github/webhook", handler.HandleWebhookRequest)
+	mux.HandleFunc("POST /refresh/{namespace}/{repository}", handler.HandleRefreshRequest)
 	server := &http.Server{
 		Addr:    ":" + strconv.Itoa(e.ServerPort),
 		Handler: webhooksutil.AccessLogMiddleware(false, nil, mux),
@@ -8,7 +8,9 @@ import (
 	"go.opentelemetry.io/otel/metric"
 	"regexp"
 	"slices"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -79,12 +81,17 @@ func (r *ApplicationReconciler) executeReconciliation(ctx context.Context, req c
 		return k8s.RequeueDueToError(fmt.Errorf("failed listing owned objects: %w", err))
 	}
 
-	// Create a map of all environments by their preferred branch
+	// Create maps of all environments by their preferred branch, or pinned environment name
 	existingEnvironmentsByBranch := make(map[string]*apiv1.Environment)
+	existingPinnedEnvironments := make(map[string]*apiv1.Environment)
 	for i, item := range envsList.Items {
-		existingEnvironmentsByBranch[item.Spec.PreferredBranch] = &envsList.Items[i]
+		if item.Spec.Pinned != "" {
+			existingPinnedEnvironments[item.Spec.Pinned] = &envsList.Items[i]
+		} else {
+			existingEnvironmentsByBranch[item.Spec.PreferredBranch] = &envsList.Items[i]
+		}
 	}
-	var namesOfEnvsToRetain []string
+	var namesOfEnvsToRetain, pinnedEnvsToRetain []string
 
 	// Setup branch regular expressions
 	var allowedBranches []*regexp.Regexp
@@ -104,6 +111,87 @@ func (r *ApplicationReconciler) executeReconciliation(ctx context.Context, req c
 		return result
 	}
 
+	// Validate version constraints
+	var versionConstraints []string
+	for _, repoRef := range rec.Object.Spec.Repositories {
+		if repoRef.Version != "" {
+			versionConstraints = append(versionConstraints, repoRef.Version)
+		}
+	}
+	for _, pinned := range rec.Object.Spec.PinnedEnvironments {
+		versionConstraints = append(versionConstraints, pinned.Version)
+	}
+	validVersionConstraints := true
+	for _, versionConstraint := range versionConstraints {
+		if _, err := semver.NewConstraint(versionConstraint); err != nil {
+			rec.Object.Status.SetInvalidDueToInvalidVersionConstraint("Invalid version constraint '%s': %+v", versionConstraint, err)
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			validVersionConstraints = false
+		}
+	}
+	if validVersionConstraints {
+		rec.Object.Status.SetValidIfInvalidDueToAnyOf(apiv1.InvalidVersionConstraint)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+	}
+
+	// Validate image wait timeouts
+	validImagesTimeouts := true
+	for _, repoRef := range rec.Object.Spec.Repositories {
+		if repoRef.WaitForImages != nil && repoRef.WaitForImages.Timeout != "" {
+			if _, err := time.ParseDuration(repoRef.WaitForImages.Timeout); err != nil {
+				rec.Object.Status.SetInvalidDueToInvalidImagesTimeout("Invalid images timeout '%s' for repository '%s': %+v", repoRef.WaitForImages.Timeout, repoRef.Name, err)
+				if result := rec.UpdateStatus(); result != nil {
+					return result
+				}
+				validImagesTimeouts = false
+			}
+		}
+	}
+	if validImagesTimeouts {
+		rec.Object.Status.SetValidIfInvalidDueToAnyOf(apiv1.InvalidImagesTimeout)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+	}
+
+	// Ensure an environment exists for every pinned environment, following its version constraint
+	for _, pinned := range rec.Object.Spec.PinnedEnvironments {
+		if env, ok := existingPinnedEnvironments[pinned.Name]; ok {
+			if env.Spec.Version != pinned.Version {
+				env.Spec.Version = pinned.Version
+				if err := r.Update(rec.Ctx, env); err != nil {
+					rec.Object.Status.SetMaybeStaleDueToInternalError("Failed updating pinned environment '%s': %+v", pinned.Name, err)
+					if result := rec.UpdateStatus(); result != nil {
+						return result
+					}
+					return k8s.Requeue()
+				}
+			}
+		} else {
+			env := &apiv1.Environment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            strings.RandomHash(7),
+					Namespace:       rec.Object.Namespace,
+					OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(rec.Object, apiv1.ApplicationGVK)},
+				},
+				Spec: apiv1.EnvironmentSpec{Pinned: pinned.Name, Version: pinned.Version},
+			}
+			if err := r.Create(rec.Ctx, env); err != nil {
+				rec.Object.Status.SetMaybeStaleDueToInternalError("Failed creating pinned environment '%s': %+v", pinned.Name, err)
+				if result := rec.UpdateStatus(); result != nil {
+					return result
+				}
+				return k8s.Requeue()
+			}
+			existingPinnedEnvironments[pinned.Name] = env
+		}
+		pinnedEnvsToRetain = append(pinnedEnvsToRetain, pinned.Name)
+	}
+
 	// Ensure all branches from all participating repositories are mapped to an environment
 	for _, repoRef := range rec.Object.Spec.Repositories {
 		repoKey := repoRef.GetObjectKey(rec.Object.Namespace)
@@ -132,8 +220,13 @@ func (r *ApplicationReconciler) executeReconciliation(ctx context.Context, req c
 			}
 		}
 
-		// For every repository branch, ensure there's an environment for it
-		for branch := range repo.Status.Revisions {
+		// For every branch requiring an environment, ensure there's an environment for it
+		for _, branch := range getEnvironmentBranches(rec.Object, repo) {
+
+			// Skip branches shadowed by pinned environments
+			if slices.ContainsFunc(rec.Object.Spec.PinnedEnvironments, func(p apiv1.ApplicationSpecPinnedEnvironment) bool { return p.Name == branch }) {
+				continue
+			}
 
 			// Skip branches that don't match any of the allowed branch expressions
 			if len(allowedBranches) > 0 {
@@ -174,9 +267,10 @@ func (r *ApplicationReconciler) executeReconciliation(ctx context.Context, req c
 		}
 	}
 
-	// Prune environments with no matching branch names in any of the app's repositories
+	// Prune environments with no matching branch names in any of the app's repositories, or pinned environments removed
+	// from the application
 	for _, env := range envsList.Items {
-		if !slices.Contains(namesOfEnvsToRetain, env.Spec.PreferredBranch) {
+		if env.Spec.Pinned != "" && !slices.Contains(pinnedEnvsToRetain, env.Spec.Pinned) || env.Spec.Pinned == "" && !slices.Contains(namesOfEnvsToRetain, env.Spec.PreferredBranch) {
 			if err := r.Delete(rec.Ctx, &env); err != nil {
 				if !apierrors.IsNotFound(err) {
 					rec.Object.Status.SetStaleDueToInternalError("Failed deleting environment '%s': %+v", env.Name, err)
@@ -241,6 +335,25 @@ func (r *ApplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				}
 			}
 			return requests
-		})).
+		}), builder.WithPredicates(repositoryUpdateRelevantToDependents)).
 		Complete(r)
 }
+
+// getEnvironmentBranches returns the branches of the given repository requiring an environment of the given
+// application: every branch in branch-driven mode, or the head branches of open pull requests (bearing the configured
+// label, if any) in pull-request-driven mode.
+func getEnvironmentBranches(app *apiv1.Application, repo *apiv1.Repository) []string {
+	var branches []string
+	if prCfg := app.Spec.PullRequests; prCfg != nil {
+		for _, pr := range repo.Status.PullRequests {
+			if prCfg.Label == "" || slices.Contains(pr.Labels, prCfg.Label) {
+				branches = append(branches, pr.HeadBranch)
+			}
+		}
+	} else {
+		for branch := range repo.Status.Revisions {
+			branches = append(branches, branch)
+		}
+	}
+	return branches
+}
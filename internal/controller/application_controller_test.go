@@ -0,0 +1,32 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+var _ = Describe("Pull-request-driven environments", func() {
+	repo := &apiv1.Repository{Status: apiv1.RepositoryStatus{
+		Revisions: map[string]string{"main": "aaaa", "feature-a": "bbbb", "feature-b": "cccc", "feature-c": "dddd"},
+		PullRequests: []apiv1.RepositoryPullRequest{
+			{Number: 1, HeadBranch: "feature-a", HeadSHA: "bbbb", Labels: []string{"preview"}},
+			{Number: 2, HeadBranch: "feature-b", HeadSHA: "cccc"},
+		},
+	}}
+
+	It("should create environments for all branches in branch-driven mode", func() {
+		Expect(getEnvironmentBranches(&apiv1.Application{}, repo)).To(ConsistOf("main", "feature-a", "feature-b", "feature-c"))
+	})
+
+	It("should create environments for open pull requests in pull-request-driven mode", func() {
+		app := &apiv1.Application{Spec: apiv1.ApplicationSpec{PullRequests: &apiv1.ApplicationSpecPullRequests{}}}
+		Expect(getEnvironmentBranches(app, repo)).To(ConsistOf("feature-a", "feature-b"))
+	})
+
+	It("should only create environments for labeled pull requests", func() {
+		app := &apiv1.Application{Spec: apiv1.ApplicationSpec{PullRequests: &apiv1.ApplicationSpecPullRequests{Label: "preview"}}}
+		Expect(getEnvironmentBranches(app, repo)).To(ConsistOf("feature-a"))
+	})
+})
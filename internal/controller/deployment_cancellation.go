@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+// supersededJobPollInterval is the interval of checking whether active jobs of superseded revisions have finished.
+const supersededJobPollInterval = 5 * time.Second
+
+// cancellationPolicy returns the effective policy for active jobs of superseded revisions of the given application's
+// deployments.
+func (r *DeploymentReconciler) cancellationPolicy(app *apiv1.Application) string {
+	if app.Spec.CancellationPolicy != "" {
+		return app.Spec.CancellationPolicy
+	} else if r.DefaultCancellationPolicy != "" {
+		return r.DefaultCancellationPolicy
+	}
+	return apiv1.CancelUnlessApplyingCancellationPolicy
+}
+
+// cancelSupersededJob deletes the given active job if the revision it was started for is superseded by the given
+// revision, as permitted by the application's cancellation policy, recording the superseded revision in the deployment
+// status. Either way, waits for the job to finish or be gone (along with its pods, which may still be using the work
+// volume) before the superseding revision may start.
+func (r *DeploymentReconciler) cancelSupersededJob(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, job *batchv1.Job, revision string) *k8s.Result {
+	supersededRevision := rec.Object.Status.LastAttemptedRevision
+	if revision == supersededRevision || job.DeletionTimestamp != nil {
+		return k8s.RequeueAfter(supersededJobPollInterval)
+	}
+
+	switch r.cancellationPolicy(app) {
+	case apiv1.WaitCancellationPolicy:
+		return k8s.RequeueAfter(supersededJobPollInterval)
+	case apiv1.CancelUnlessApplyingCancellationPolicy:
+		phase := Phase(job.Labels[PhaseLabel])
+		if isSinglePodJob(job) {
+			if p, err := r.getSinglePodPhase(rec, job); err != nil {
+				rec.Object.Status.SetMaybeStaleDueToInternalError("Failed inspecting pods of job '%s': %+v", job.Name, err)
+				if result := rec.UpdateStatus(); result != nil {
+					return result
+				}
+				return k8s.Requeue()
+			} else {
+				phase = p
+			}
+		}
+		if phase == PhaseApply {
+			rec.Object.Status.SetMaybeStaleDueToApplying("Waiting for job '%s' to finish applying superseded revision '%s'", job.Name, supersededRevision)
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			return k8s.RequeueAfter(supersededJobPollInterval)
+		}
+	}
+
+	if err := r.Client.Delete(rec.Ctx, job, client.PropagationPolicy(metav1.DeletePropagationForeground)); client.IgnoreNotFound(err) != nil {
+		rec.Object.Status.SetMaybeStaleDueToInternalError("Failed cancelling job '%s' of superseded revision '%s': %+v", job.Name, supersededRevision, err)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.Requeue()
+	}
+	rec.Object.Status.SupersededRevision = supersededRevision
+	if result := rec.UpdateStatus(); result != nil {
+		return result
+	}
+	return k8s.RequeueAfter(supersededJobPollInterval)
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+var _ = Describe("Superseded jobs", func() {
+	const (
+		oldRevision = "1111111111111111111111111111111111111111"
+		newRevision = "2222222222222222222222222222222222222222"
+	)
+
+	newRec := func(phase Phase) (*DeploymentReconciler, *k8s.Reconciliation[*apiv1.Deployment], *batchv1.Job) {
+		deployment := &apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"}}
+		deployment.Status.LastAttemptedRevision = oldRevision
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "job1", Labels: map[string]string{PhaseLabel: string(phase)}},
+			Status:     batchv1.JobStatus{Active: 1},
+		}
+		r, rec := newDeploymentReconciliation(deployment, job)
+		return r, rec, job
+	}
+	jobExists := func(r *DeploymentReconciler, job *batchv1.Job) bool {
+		err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(job), &batchv1.Job{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+	withPolicy := func(policy string) *apiv1.Application {
+		return &apiv1.Application{Spec: apiv1.ApplicationSpec{CancellationPolicy: policy}}
+	}
+
+	It("should layer the application's policy over the controller default", func() {
+		Expect((&DeploymentReconciler{}).cancellationPolicy(&apiv1.Application{})).To(Equal(apiv1.CancelUnlessApplyingCancellationPolicy))
+		Expect((&DeploymentReconciler{DefaultCancellationPolicy: apiv1.WaitCancellationPolicy}).cancellationPolicy(&apiv1.Application{})).To(Equal(apiv1.WaitCancellationPolicy))
+		Expect((&DeploymentReconciler{DefaultCancellationPolicy: apiv1.WaitCancellationPolicy}).cancellationPolicy(withPolicy(apiv1.CancelCancellationPolicy))).To(Equal(apiv1.CancelCancellationPolicy))
+	})
+
+	It("should wait for jobs of the target revision", func() {
+		r, rec, job := newRec(PhaseBake)
+		Expect(r.cancelSupersededJob(rec, withPolicy(apiv1.CancelCancellationPolicy), job, oldRevision)).NotTo(BeNil())
+		Expect(jobExists(r, job)).To(BeTrue())
+		Expect(rec.Object.Status.SupersededRevision).To(BeEmpty())
+	})
+
+	It("should cancel jobs of superseded revisions", func() {
+		r, rec, job := newRec(PhaseBake)
+		Expect(r.cancelSupersededJob(rec, withPolicy(apiv1.CancelUnlessApplyingCancellationPolicy), job, newRevision)).To(Equal(k8s.RequeueAfter(supersededJobPollInterval)))
+		Expect(jobExists(r, job)).To(BeFalse())
+		Expect(rec.Object.Status.SupersededRevision).To(Equal(oldRevision))
+	})
+
+	It("should let superseded revisions finish applying, unless cancelling always", func() {
+		r, rec, job := newRec(PhaseApply)
+		Expect(r.cancelSupersededJob(rec, withPolicy(apiv1.CancelUnlessApplyingCancellationPolicy), job, newRevision)).NotTo(BeNil())
+		Expect(jobExists(r, job)).To(BeTrue())
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.Applying))
+
+		Expect(r.cancelSupersededJob(rec, withPolicy(apiv1.CancelCancellationPolicy), job, newRevision)).To(Equal(k8s.RequeueAfter(supersededJobPollInterval)))
+		Expect(jobExists(r, job)).To(BeFalse())
+	})
+
+	It("should wait for superseded jobs when configured to", func() {
+		r, rec, job := newRec(PhaseClone)
+		Expect(r.cancelSupersededJob(rec, withPolicy(apiv1.WaitCancellationPolicy), job, newRevision)).NotTo(BeNil())
+		Expect(jobExists(r, job)).To(BeTrue())
+	})
+})
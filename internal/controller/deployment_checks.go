@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+const (
+	// checksPollInterval is how often checks of a revision are polled while waiting for them to complete.
+	checksPollInterval = 30 * time.Second
+
+	// failedChecksPollInterval is how often checks of a revision are polled after they failed, in case they are re-run.
+	failedChecksPollInterval = 5 * time.Minute
+)
+
+type ChecksState string
+
+const (
+	ChecksPending ChecksState = "pending"
+	ChecksSuccess ChecksState = "success"
+	ChecksFailure ChecksState = "failure"
+)
+
+// checksResult is the aggregated result of the checks of a single commit.
+type checksResult struct {
+	State   ChecksState
+	Pending []string
+	Failed  []string
+}
+
+// add adds the given check result, ignoring checks not included in the given names (unless no names are given).
+func (r *checksResult) add(names []string, name string, state ChecksState) {
+	if len(names) > 0 && !slices.Contains(names, name) {
+		return
+	}
+	switch state {
+	case ChecksPending:
+		r.Pending = append(r.Pending, name)
+	case ChecksFailure:
+		r.Failed = append(r.Failed, name)
+	}
+}
+
+// getGitHubCommitChecks aggregates the check runs & commit statuses of the given commit in the given GitHub repository.
+// If names are given, only checks with these names are considered, and missing checks are considered pending. Commit
+// statuses reported by devbot itself are ignored.
+func getGitHubCommitChecks(ctx context.Context, ghc *github.Client, owner, name, sha string, names []string) (*checksResult, error) {
+	result := &checksResult{}
+	seen := make(map[string]bool)
+
+	// Collect check runs
+	checkRunsOpts := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		checkRuns, resp, err := ghc.Checks.ListCheckRunsForRef(ctx, owner, name, sha, checkRunsOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed listing check runs of '%s/%s@%s': %w", owner, name, sha, err)
+		}
+		for _, run := range checkRuns.CheckRuns {
+			seen[run.GetName()] = true
+			if run.GetStatus() != "completed" {
+				result.add(names, run.GetName(), ChecksPending)
+			} else if c := run.GetConclusion(); c == "success" || c == "neutral" || c == "skipped" {
+				result.add(names, run.GetName(), ChecksSuccess)
+			} else {
+				result.add(names, run.GetName(), ChecksFailure)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		checkRunsOpts.Page = resp.NextPage
+	}
+
+	// Collect commit statuses
+	statusesOpts := &github.ListOptions{PerPage: 100}
+	for {
+		combined, resp, err := ghc.Repositories.GetCombinedStatus(ctx, owner, name, sha, statusesOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting combined status of '%s/%s@%s': %w", owner, name, sha, err)
+		}
+		for _, status := range combined.Statuses {
+			if strings.HasPrefix(status.GetContext(), commitStatusContextPrefix+"/") {
+				continue
+			}
+			seen[status.GetContext()] = true
+			switch status.GetState() {
+			case "success":
+				result.add(names, status.GetContext(), ChecksSuccess)
+			case "pending":
+				result.add(names, status.GetContext(), ChecksPending)
+			default:
+				result.add(names, status.GetContext(), ChecksFailure)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		statusesOpts.Page = resp.NextPage
+	}
+
+	// Required checks that were not reported yet are pending
+	for _, n := range names {
+		if !seen[n] {
+			result.Pending = append(result.Pending, n)
+		}
+	}
+
+	switch {
+	case len(result.Failed) > 0:
+		result.State = ChecksFailure
+	case len(result.Pending) > 0:
+		result.State = ChecksPending
+	default:
+		result.State = ChecksSuccess
+	}
+	return result, nil
+}
+
+// waitForChecks returns a non-nil result if the given revision should not be deployed yet, because the repository
+// settings require its GitHub checks to succeed and they are either still pending or failed.
+func (r *DeploymentReconciler) waitForChecks(rec *k8s.Reconciliation[*apiv1.Deployment], repo *apiv1.Repository, repoSettings *apiv1.ApplicationSpecRepository, revision string) *k8s.Result {
+	if repoSettings == nil || repoSettings.Checks == nil || repo.Spec.GitHub == nil {
+		return nil
+	}
+
+	ghc, err := newGitHubClientForRepository(rec.Ctx, r.Client, r.GitHubClientFactory, repo)
+	if err != nil {
+		rec.Object.Status.SetMaybeStaleDueToWaitingForChecks("Failed connecting to GitHub: %+v", err)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.RequeueAfter(checksPollInterval)
+	}
+
+	owner, name := repo.Spec.GitHub.Owner, repo.Spec.GitHub.Name
+	checks, err := getGitHubCommitChecks(rec.Ctx, ghc, owner, name, revision, repoSettings.Checks.Names)
+	if err != nil {
+		rec.Object.Status.SetMaybeStaleDueToWaitingForChecks("Failed fetching checks of revision '%s': %+v", revision, err)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.RequeueAfter(checksPollInterval)
+	}
+
+	switch checks.State {
+	case ChecksFailure:
+		rec.Object.Status.SetMaybeStaleDueToChecksFailed("Checks of revision '%s' failed: %s", revision, strings.Join(checks.Failed, ", "))
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.RequeueAfter(failedChecksPollInterval)
+	case ChecksPending:
+		rec.Object.Status.SetMaybeStaleDueToWaitingForChecks("Waiting for checks of revision '%s': %s", revision, strings.Join(checks.Pending, ", "))
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.RequeueAfter(checksPollInterval)
+	default:
+		return nil
+	}
+}
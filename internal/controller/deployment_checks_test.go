@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/google/go-github/v56/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Commit checks", func() {
+	var server *httptest.Server
+	var checkRuns, statuses string
+	var ghc *github.Client
+
+	BeforeEach(func() {
+		checkRuns, statuses = `{"check_runs":[]}`, `{"statuses":[]}`
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v3/repos/owner/name/commits/abc123/check-runs":
+				_, _ = w.Write([]byte(checkRuns))
+			case "/api/v3/repos/owner/name/commits/abc123/status":
+				_, _ = w.Write([]byte(statuses))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		DeferCleanup(server.Close)
+
+		var err error
+		ghc, err = NewGitHubClientFactoryForURL(server.URL + "/api/v3").newClient("s3cr3t")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	getChecks := func(names ...string) *checksResult {
+		result, err := getGitHubCommitChecks(context.Background(), ghc, "owner", "name", "abc123", names)
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	It("should succeed when no checks are reported", func() {
+		Expect(getChecks().State).To(Equal(ChecksSuccess))
+	})
+
+	It("should succeed when all checks succeed", func() {
+		checkRuns = `{"check_runs":[{"name":"build","status":"completed","conclusion":"success"},{"name":"lint","status":"completed","conclusion":"skipped"}]}`
+		statuses = `{"statuses":[{"context":"ci/test","state":"success"}]}`
+		Expect(getChecks().State).To(Equal(ChecksSuccess))
+	})
+
+	It("should be pending while checks are in progress", func() {
+		checkRuns = `{"check_runs":[{"name":"build","status":"in_progress"}]}`
+		statuses = `{"statuses":[{"context":"ci/test","state":"pending"}]}`
+		result := getChecks()
+		Expect(result.State).To(Equal(ChecksPending))
+		Expect(result.Pending).To(ConsistOf("build", "ci/test"))
+	})
+
+	It("should fail when any check fails, even if others are pending", func() {
+		checkRuns = `{"check_runs":[{"name":"build","status":"completed","conclusion":"failure"},{"name":"lint","status":"queued"}]}`
+		statuses = `{"statuses":[{"context":"ci/test","state":"error"}]}`
+		result := getChecks()
+		Expect(result.State).To(Equal(ChecksFailure))
+		Expect(result.Failed).To(ConsistOf("build", "ci/test"))
+	})
+
+	It("should only consider the given checks", func() {
+		checkRuns = `{"check_runs":[{"name":"build","status":"completed","conclusion":"success"},{"name":"flaky","status":"completed","conclusion":"failure"}]}`
+		Expect(getChecks("build").State).To(Equal(ChecksSuccess))
+	})
+
+	It("should wait for given checks that were not reported yet", func() {
+		checkRuns = `{"check_runs":[{"name":"build","status":"completed","conclusion":"success"}]}`
+		result := getChecks("build", "image")
+		Expect(result.State).To(Equal(ChecksPending))
+		Expect(result.Pending).To(ConsistOf("image"))
+	})
+
+	It("should ignore commit statuses reported by devbot", func() {
+		statuses = `{"statuses":[{"context":"devbot/my-app/main","state":"pending"}]}`
+		Expect(getChecks().State).To(Equal(ChecksSuccess))
+	})
+})
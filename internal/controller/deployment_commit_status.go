@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v56/github"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	stringsutil "github.com/arikkfir/devbot/internal/util/strings"
+)
+
+const (
+	// commitStatusContextPrefix prefixes the context of every commit status devbot reports.
+	commitStatusContextPrefix = "devbot"
+
+	// maxCommitStatusDescriptionLength is the maximum length GitHub accepts for commit status descriptions.
+	maxCommitStatusDescriptionLength = 140
+)
+
+type CommitStatusState string
+
+const (
+	CommitStatusPending CommitStatusState = "pending"
+	CommitStatusSuccess CommitStatusState = "success"
+	CommitStatusFailure CommitStatusState = "failure"
+)
+
+// CommitStatus is a single status reported for a commit in the source repository.
+type CommitStatus struct {
+	State       CommitStatusState
+	Context     string
+	Description string
+	TargetURL   string
+}
+
+// postGitHubCommitStatus creates the given commit status for the given commit SHA in the given GitHub repository.
+func postGitHubCommitStatus(ctx context.Context, ghc *github.Client, owner, name, sha string, status CommitStatus) error {
+	description := status.Description
+	if len(description) > maxCommitStatusDescriptionLength {
+		description = description[:maxCommitStatusDescriptionLength-3] + "..."
+	}
+
+	repoStatus := &github.RepoStatus{
+		State:       github.String(string(status.State)),
+		Context:     github.String(status.Context),
+		Description: github.String(description),
+	}
+	if status.TargetURL != "" {
+		repoStatus.TargetURL = github.String(status.TargetURL)
+	}
+
+	if _, _, err := ghc.Repositories.CreateStatus(ctx, owner, name, sha, repoStatus); err != nil {
+		return fmt.Errorf("failed creating commit status for '%s/%s@%s': %w", owner, name, sha, err)
+	}
+	return nil
+}
+
+// expandEnvironmentURL expands the given environment URL template with the variables available to deployments.
+func expandEnvironmentURL(template string, app *apiv1.Application, env *apiv1.Environment, d *apiv1.Deployment) string {
+	if template == "" {
+		return ""
+	}
+	variables := map[string]string{
+		"ACTUAL_BRANCH":    stringsutil.Slugify(d.Status.Branch),
+		"APPLICATION":      stringsutil.Slugify(app.Name),
+		"COMMIT_SHA":       d.Status.LastAttemptedRevision,
+		"ENVIRONMENT":      stringsutil.Slugify(env.Spec.GetDisplayName()),
+		"PREFERRED_BRANCH": stringsutil.Slugify(env.Spec.PreferredBranch),
+		"TAG":              d.Status.Tag,
+	}
+	return os.Expand(template, func(name string) string { return variables[name] })
+}
+
+// reportCommitStatus reports the given deployment state for the last attempted revision to the source repository.
+// Reporting is best-effort: failures are logged, but never fail the reconciliation.
+func (r *DeploymentReconciler) reportCommitStatus(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, env *apiv1.Environment, repo *apiv1.Repository, state CommitStatusState, description string, args ...interface{}) {
+	if repo.Spec.GitHub == nil || rec.Object.Status.LastAttemptedRevision == "" {
+		return
+	}
+
+	status := CommitStatus{
+		State:       state,
+		Context:     fmt.Sprintf("%s/%s/%s", commitStatusContextPrefix, app.Name, env.Spec.GetDisplayName()),
+		Description: fmt.Sprintf(description, args...),
+	}
+	if state == CommitStatusSuccess {
+		status.TargetURL = expandEnvironmentURL(app.Spec.EnvironmentURL, app, env, rec.Object)
+	}
+
+	logger := log.FromContext(rec.Ctx).WithValues("state", state, "sha", rec.Object.Status.LastAttemptedRevision)
+	ghc, err := newGitHubClientForRepository(rec.Ctx, r.Client, r.GitHubClientFactory, repo)
+	if err != nil {
+		logger.Error(err, "Failed creating GitHub client for reporting commit status")
+		return
+	}
+
+	owner, name, sha := repo.Spec.GitHub.Owner, repo.Spec.GitHub.Name, rec.Object.Status.LastAttemptedRevision
+	if err := postGitHubCommitStatus(rec.Ctx, ghc, owner, name, sha, status); err != nil {
+		logger.Error(err, "Failed reporting commit status")
+	}
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+type fakeGitHubStatusRequest struct {
+	Path          string
+	Authorization string
+	Body          map[string]string
+}
+
+var _ = Describe("Commit statuses", func() {
+	var server *httptest.Server
+	var requests []fakeGitHubStatusRequest
+	var mu sync.Mutex
+
+	BeforeEach(func() {
+		requests = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPost))
+			body := make(map[string]string)
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			mu.Lock()
+			requests = append(requests, fakeGitHubStatusRequest{Path: r.URL.Path, Authorization: r.Header.Get("Authorization"), Body: body})
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	newReconciler := func(objects ...runtime.Object) *DeploymentReconciler {
+		scheme := newTestScheme()
+		return &DeploymentReconciler{
+			Client:              newFakeClient(scheme, objects...),
+			Scheme:              scheme,
+			GitHubClientFactory: NewGitHubClientFactoryForURL(server.URL + "/api/v3"),
+		}
+	}
+
+	app := &apiv1.Application{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-app"},
+		Spec:       apiv1.ApplicationSpec{EnvironmentURL: "https://${ENVIRONMENT}.${APPLICATION}.example.com/${COMMIT_SHA}"},
+	}
+	env := &apiv1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-env"},
+		Spec:       apiv1.EnvironmentSpec{PreferredBranch: "feature/x"},
+	}
+	repo := &apiv1.Repository{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-repo"},
+		Spec: apiv1.RepositorySpec{
+			GitHub: &apiv1.GitHubRepositorySpec{
+				Owner: "owner",
+				Name:  "name",
+				PersonalAccessToken: apiv1.GitHubRepositoryPersonalAccessToken{
+					Secret: apiv1.SecretReferenceWithOptionalNamespace{Name: "pat"},
+					Key:    "token",
+				},
+			},
+		},
+	}
+	patSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pat"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	newRec := func(r *DeploymentReconciler) *k8s.Reconciliation[*apiv1.Deployment] {
+		return &k8s.Reconciliation[*apiv1.Deployment]{
+			Ctx:    context.Background(),
+			Client: r.Client,
+			Object: &apiv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"},
+				Status:     apiv1.DeploymentStatus{Branch: "feature/x", LastAttemptedRevision: "abc123"},
+			},
+		}
+	}
+
+	It("should report pending statuses with the repository credentials", func() {
+		r := newReconciler(patSecret)
+		r.reportCommitStatus(newRec(r), app, env, repo, CommitStatusPending, "Cloning revision")
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Path).To(Equal("/api/v3/repos/owner/name/statuses/abc123"))
+		Expect(requests[0].Authorization).To(Equal("Bearer s3cr3t"))
+		Expect(requests[0].Body).To(Equal(map[string]string{
+			"state":       "pending",
+			"context":     "devbot/my-app/feature/x",
+			"description": "Cloning revision",
+		}))
+	})
+
+	It("should report successful statuses with the environment URL", func() {
+		r := newReconciler(patSecret)
+		r.reportCommitStatus(newRec(r), app, env, repo, CommitStatusSuccess, "Deployed")
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Body).To(HaveKeyWithValue("state", "success"))
+		Expect(requests[0].Body).To(HaveKeyWithValue("target_url", "https://feature-x.my-app.example.com/abc123"))
+	})
+
+	It("should truncate long failure descriptions", func() {
+		r := newReconciler(patSecret)
+		r.reportCommitStatus(newRec(r), app, env, repo, CommitStatusFailure, "The %s phase failed: %s", PhaseBake, strings.Repeat("x", 200))
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Body).To(HaveKeyWithValue("state", "failure"))
+		Expect(requests[0].Body["description"]).To(HaveLen(maxCommitStatusDescriptionLength))
+		Expect(requests[0].Body["description"]).To(HavePrefix("The bake phase failed: xxx"))
+		Expect(requests[0].Body["description"]).To(HaveSuffix("..."))
+	})
+
+	It("should not report statuses when credentials are missing", func() {
+		r := newReconciler()
+		r.reportCommitStatus(newRec(r), app, env, repo, CommitStatusPending, "Cloning revision")
+		Expect(requests).To(BeEmpty())
+	})
+})
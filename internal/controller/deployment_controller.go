@@ -2,7 +2,6 @@ package controller
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"slices"
 	"strconv"
@@ -39,9 +38,19 @@ var (
 
 type DeploymentReconciler struct {
 	client.Client
-	Scheme             *runtime.Scheme
-	DisableJSONLogging bool
-	LogLevel           string
+	Scheme                                 *runtime.Scheme
+	DisableJSONLogging                     bool
+	LogLevel                               string
+	GitHubClientFactory                    GitHubClientFactory
+	DefaultWorkVolume                      apiv1.ApplicationSpecWorkVolume
+	JobTemplate                            *runtime.RawExtension
+	DefaultExecutionMode                   string
+	DefaultMaxAttempts                     int
+	DefaultCancellationPolicy              string
+	MaxConcurrentJobs                      int
+	DefaultMaxConcurrentJobsPerApplication int
+	DefaultMaxConcurrentJobsPerRepository  int
+	scheduler                              jobScheduler
 }
 
 func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -120,11 +129,15 @@ func (r *DeploymentReconciler) executeReconciliation(ctx context.Context, req ct
 		if result := rec.UpdateStatus(); result != nil {
 			return result
 		}
+		return k8s.DoNotRequeue()
 	}
 
-	// Ensure a persistent volume claim was created
-	if result := r.ensurePersistentVolumeClaim(rec); result != nil {
-		return result
+	// Ensure a persistent volume claim was created, unless the work volume is ephemeral
+	workVolume := r.workVolumeSettings(app)
+	if workVolume.Mode != apiv1.EmptyDirWorkVolumeMode {
+		if result := r.ensurePersistentVolumeClaim(rec, workVolume); result != nil {
+			return result
+		}
 	}
 
 	// Get the current job, if any
@@ -133,29 +146,71 @@ func (r *DeploymentReconciler) executeReconciliation(ctx context.Context, req ct
 		return result
 	}
 
-	// Infer the branch to deploy
-	var branch, revision string
-	if r, ok := repo.Status.Revisions[env.Spec.PreferredBranch]; ok {
-		branch = env.Spec.PreferredBranch
-		revision = r
-	} else if r, ok := repo.Status.Revisions[repo.Status.DefaultBranch]; ok {
-		branch = repo.Status.DefaultBranch
-		revision = r
-	} else {
-		rec.Object.Status.SetMaybeStaleDueToBranchNotFound("Neither branch '%s' nor '%s' found in repository '%s'", env.Spec.PreferredBranch, repo.Status.DefaultBranch, client.ObjectKeyFromObject(repo))
-		if result := rec.UpdateStatus(); result != nil {
-			return result
+	// Reset failed attempts if a retry was requested
+	if result := handleRetryRequest(rec); result != nil {
+		return result
+	}
+
+	// Infer the tag to deploy, if the repository or environment follow a version constraint
+	var branch, tag, revision string
+	if repoSettings.Version != "" {
+		if t, r, err := findHighestMatchingTag(repo.Status.Tags, repoSettings.Version); err != nil {
+			rec.Object.Status.SetMaybeStaleDueToTagNotFound("Failed resolving tag: %+v", err)
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			return k8s.DoNotRequeue()
+		} else if t == "" {
+			rec.Object.Status.SetMaybeStaleDueToTagNotFound("No tag matching '%s' found in repository '%s'", repoSettings.Version, client.ObjectKeyFromObject(repo))
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			return k8s.Requeue()
+		} else {
+			tag, revision = t, r
+		}
+	} else if env.Spec.Version != "" {
+		if t, r, err := findHighestMatchingTag(repo.Status.Tags, env.Spec.Version); err != nil {
+			rec.Object.Status.SetMaybeStaleDueToTagNotFound("Failed resolving tag: %+v", err)
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			return k8s.DoNotRequeue()
+		} else if t != "" {
+			tag, revision = t, r
+		}
+	}
+
+	// Infer the branch to deploy, unless a tag is deployed
+	if tag == "" {
+		if r, ok := repo.Status.Revisions[env.Spec.PreferredBranch]; ok {
+			branch = env.Spec.PreferredBranch
+			revision = r
+		} else if r, ok := repo.Status.Revisions[repo.Status.DefaultBranch]; ok {
+			branch = repo.Status.DefaultBranch
+			revision = r
+		} else {
+			rec.Object.Status.SetMaybeStaleDueToBranchNotFound("Neither branch '%s' nor '%s' found in repository '%s'", env.Spec.PreferredBranch, repo.Status.DefaultBranch, client.ObjectKeyFromObject(repo))
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			return k8s.Requeue()
 		}
-		return k8s.Requeue()
 	}
 
-	// If no current job is running, we may want to start from scratch (clone->bake->apply) if branch/revision changed
+	// Check whether a redeploy of the current revision was requested for this deployment, its environment or application
+	redeployRequest := getRedeployRequest(app, env, rec.Object)
+	redeployRequested := redeployRequest != rec.Object.Status.LastRedeployRequest
+
+	// If no current job is running, we may want to start from scratch (clone->bake->apply) if branch/revision changed,
+	// or if a redeploy was requested
 	if job == nil {
 
-		// If either branch or revision changed, update the status & create a new clone job
-		branchChanged := branch != rec.Object.Status.Branch
+		// If either branch, tag or revision changed, update the status & create a new clone job
+		branchChanged := branch != rec.Object.Status.Branch || tag != rec.Object.Status.Tag
 		if branchChanged {
 			rec.Object.Status.Branch = branch
+			rec.Object.Status.Tag = tag
 			if result := rec.UpdateStatus(); result != nil {
 				return result
 			}
@@ -167,28 +222,85 @@ func (r *DeploymentReconciler) executeReconciliation(ctx context.Context, req ct
 				return result
 			}
 		}
-		if branchChanged || revisionChanged {
-			return r.createNewCloneJob(rec, app, repo)
+		if branchChanged || revisionChanged || redeployRequested {
+			// The revision may have failed before & its last job deleted (e.g. after its TTL); unless a redeploy was
+			// requested, restart it only once its retry is due, and never once it exhausted its attempts
+			if attempts := rec.Object.Status.FailedAttempts; attempts != nil && attempts.Revision == revision && !redeployRequested {
+				if result := r.waitForRetry(rec, app); result != nil {
+					return result
+				}
+			}
+			if result := r.waitForChecks(rec, repo, repoSettings, revision); result != nil {
+				return result
+			}
+			if result := r.scheduleJob(rec, app, env, repo); result != nil {
+				return result
+			}
+			rec.Object.Status.LastAttemptedRevision = revision
+			acceptRedeployRequest(&rec.Object.Status, redeployRequest)
+			startRolloutTrace(rec, repo)
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			return r.createNewCloneJob(rec, app, env, repo, repoSettings)
+		}
+
+		// The last job may have failed & been deleted (e.g. after its TTL) before being retried; retry once due
+		if attempts := rec.Object.Status.FailedAttempts; attempts != nil && attempts.Revision == revision {
+			if result := r.waitForRetry(rec, app); result != nil {
+				return result
+			}
+			if result := r.scheduleJob(rec, app, env, repo); result != nil {
+				return result
+			}
+			return r.createNewCloneJob(rec, app, env, repo, repoSettings)
 		}
 		return k8s.DoNotRequeue()
 	}
 
-	// If branch/revision changed we should wait for the current job to complete, abandon it, update our status, and start from scratch
-	if branch != rec.Object.Status.Branch || revision != rec.Object.Status.LastAttemptedRevision {
-		if job.Status.Active > 0 {
-			// Wait until the currently running job is finished (successfully or not)
-			return k8s.RequeueAfter(5 * time.Second)
+	// If branch/tag/revision changed, or a redeploy was requested, we should wait for the current job to complete,
+	// abandon it, update our status, and start from scratch
+	if branch != rec.Object.Status.Branch || tag != rec.Object.Status.Tag || revision != rec.Object.Status.LastAttemptedRevision || redeployRequested {
+		if job.Status.Active > 0 || job.DeletionTimestamp != nil {
+			// Cancel the currently running job if its revision was superseded, or wait until it's finished (successfully
+			// or not) or gone
+			if result := r.cancelSupersededJob(rec, app, job, revision); result != nil {
+				return result
+			}
+		}
+		// Wait for checks & a job slot before updating the status, so that the previous job is not mistaken for this
+		// revision's
+		if result := r.waitForChecks(rec, repo, repoSettings, revision); result != nil {
+			return result
+		}
+		if result := r.scheduleJob(rec, app, env, repo); result != nil {
+			return result
 		}
 		rec.Object.Status.Branch = branch
+		rec.Object.Status.Tag = tag
 		rec.Object.Status.LastAttemptedRevision = revision
+		acceptRedeployRequest(&rec.Object.Status, redeployRequest)
+		startRolloutTrace(rec, repo)
 		if result := rec.UpdateStatus(); result != nil {
 			return result
 		}
-		return r.createNewCloneJob(rec, app, repo)
+		return r.createNewCloneJob(rec, app, env, repo, repoSettings)
 	}
 
-	// We have a job - progress accordingly
+	// We have a job - progress accordingly; the phase of single-pod jobs is derived from their containers' statuses
 	phase := Phase(job.Labels[PhaseLabel])
+	singlePod := isSinglePodJob(job)
+	if singlePod {
+		if p, err := r.getSinglePodPhase(rec, job); err != nil {
+			rec.Object.Status.SetMaybeStaleDueToInternalError("Failed inspecting pods of job '%s': %+v", job.Name, err)
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			return k8s.Requeue()
+		} else {
+			phase = p
+		}
+	}
 	for _, c := range job.Status.Conditions {
 		switch c.Type {
 
@@ -232,14 +344,27 @@ func (r *DeploymentReconciler) executeReconciliation(ctx context.Context, req ct
 				return k8s.DoNotRequeue()
 
 			case corev1.ConditionTrue:
-				// Job failed - recreate it
+				// Job failed - report & recreate it after backing off, unless giving up (single-pod jobs always start
+				// over, as they retain no work)
+				if result := r.reportJobFailure(rec, app, env, repo, phase, job, c.Message); result != nil {
+					return result
+				}
+				if result := r.waitForRetry(rec, app); result != nil {
+					return result
+				}
+				if result := r.scheduleJob(rec, app, env, repo); result != nil {
+					return result
+				}
+				if singlePod {
+					return r.createNewCloneJob(rec, app, env, repo, repoSettings)
+				}
 				switch phase {
 				case PhaseClone:
-					return r.createNewCloneJob(rec, app, repo)
+					return r.createNewCloneJob(rec, app, env, repo, repoSettings)
 				case PhaseBake:
 					return r.createNewBakeJob(rec, app, env, repo, *repoSettings)
 				case PhaseApply:
-					return r.createNewApplyJob(rec, app, env)
+					return r.createNewApplyJob(rec, app, env, repo, repoSettings)
 				default:
 					panic("unsupported phase: " + phase)
 				}
@@ -268,15 +393,23 @@ func (r *DeploymentReconciler) executeReconciliation(ctx context.Context, req ct
 				// Job completed successfully - create the next one (or clear it entirely; we're done)
 				switch phase {
 				case PhaseClone:
+					if result := r.scheduleJob(rec, app, env, repo); result != nil {
+						return result
+					}
 					return r.createNewBakeJob(rec, app, env, repo, *repoSettings)
 				case PhaseBake:
-					return r.createNewApplyJob(rec, app, env)
+					if result := r.scheduleJob(rec, app, env, repo); result != nil {
+						return result
+					}
+					return r.createNewApplyJob(rec, app, env, repo, repoSettings)
 				case PhaseApply:
 					rec.Object.Status.SetCurrent()
 					rec.Object.Status.LastAppliedRevision = rec.Object.Status.LastAttemptedRevision
+					rec.Object.Status.FailedAttempts = nil
 					if result := rec.UpdateStatus(); result != nil {
 						return result
 					}
+					r.reportCommitStatus(rec, app, env, repo, CommitStatusSuccess, "Deployed to environment '%s'", env.Spec.GetDisplayName())
 					return k8s.DoNotRequeue()
 				default:
 					panic("unsupported phase: " + phase)
@@ -285,6 +418,28 @@ func (r *DeploymentReconciler) executeReconciliation(ctx context.Context, req ct
 		}
 	}
 
+	// An active job may be retrying a failed phase; poll it to reflect such failures in our status
+	if job.Status.Active > 0 {
+		if result := r.reportRetriedJobFailure(rec, phase, job); result != nil {
+			return result
+		}
+	}
+
+	// An active apply job may be waiting for container images; poll it to reflect the missing images in our status
+	if phase == PhaseApply && job.Status.Active > 0 && repoSettings.WaitForImages != nil {
+		return r.reportMissingImages(rec, job)
+	}
+
+	// An active single-pod job progresses through phases without changing itself; poll it to reflect its phase
+	if singlePod && job.Status.Active > 0 {
+		return r.reportSinglePodPhase(rec, job, phase)
+	}
+
+	// Keep polling active jobs for failures
+	if job.Status.Active > 0 {
+		return k8s.RequeueAfter(jobFailurePollInterval)
+	}
+
 	return k8s.DoNotRequeue()
 }
 
@@ -334,7 +489,7 @@ func (r *DeploymentReconciler) getApplication(rec *k8s.Reconciliation[*apiv1.Dep
 	return k8s.Continue()
 }
 
-func (r *DeploymentReconciler) ensurePersistentVolumeClaim(rec *k8s.Reconciliation[*apiv1.Deployment]) *k8s.Result {
+func (r *DeploymentReconciler) ensurePersistentVolumeClaim(rec *k8s.Reconciliation[*apiv1.Deployment], workVolume apiv1.ApplicationSpecWorkVolume) *k8s.Result {
 	if rec.Object.Status.PersistentVolumeClaimName == "" {
 		rec.Object.Status.SetMaybeStaleDueToPersistentVolumeMissing("Persistent volume claim name not set yet")
 		if result := rec.UpdateStatus(); result != nil {
@@ -355,18 +510,15 @@ func (r *DeploymentReconciler) ensurePersistentVolumeClaim(rec *k8s.Reconciliati
 				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(rec.Object, apiv1.DeploymentGVK)},
 			},
 			Spec: corev1.PersistentVolumeClaimSpec{
-				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				AccessModes: []corev1.PersistentVolumeAccessMode{workVolume.AccessMode},
 				Resources: corev1.VolumeResourceRequirements{
-					Requests: corev1.ResourceList{
-						corev1.ResourceStorage: *resource.NewScaledQuantity(5, resource.Giga),
-					},
-					Limits: corev1.ResourceList{
-						corev1.ResourceStorage: *resource.NewScaledQuantity(15, resource.Giga),
-					},
+					Requests: corev1.ResourceList{corev1.ResourceStorage: *workVolume.Size},
 				},
-				StorageClassName: lang.Ptr("standard"), // TODO: storage-class should be user-configurable
 			},
 		}
+		if workVolume.StorageClassName != "" {
+			pvc.Spec.StorageClassName = lang.Ptr(workVolume.StorageClassName)
+		}
 		if err := rec.Client.Create(rec.Ctx, pvc); err != nil {
 			rec.Object.Status.SetMaybeStaleDueToPersistentVolumeCreationFailed("Failed creating persistent volume claim: %+v", err)
 			if result := rec.UpdateStatus(); result != nil {
@@ -410,9 +562,7 @@ func (r *DeploymentReconciler) getLastJob(rec *k8s.Reconciliation[*apiv1.Deploym
 	return nil
 }
 
-func (r *DeploymentReconciler) createNewJobSpec(rec *k8s.Reconciliation[*apiv1.Deployment], image string, phase Phase, app *apiv1.Application, envVars ...corev1.EnvVar) (batchv1.Job, error) {
-	log.FromContext(rec.Ctx).WithValues("phase", phase).Info("Creating new job")
-
+func (r *DeploymentReconciler) newJobContainer(phase Phase, image string, envVars ...corev1.EnvVar) corev1.Container {
 	// Prepare job environment variables
 	jobEnvVars := []corev1.EnvVar{
 		{Name: "DISABLE_JSON_LOGGING", Value: strconv.FormatBool(r.DisableJSONLogging)},
@@ -430,7 +580,37 @@ func (r *DeploymentReconciler) createNewJobSpec(rec *k8s.Reconciliation[*apiv1.D
 	}
 	jobEnvVars = append(jobEnvVars, envVars...)
 
-	return batchv1.Job{
+	return corev1.Container{
+		Name:       string(phase),
+		Image:      image,
+		WorkingDir: "/data",
+		Env:        jobEnvVars,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
+				corev1.ResourceMemory: *resource.NewScaledQuantity(128, resource.Mega),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    *resource.NewScaledQuantity(200, resource.Milli),
+				corev1.ResourceMemory: *resource.NewScaledQuantity(256, resource.Mega),
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+		// Failures not described by a termination message are described by the end of the container's logs instead
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	}
+}
+
+func (r *DeploymentReconciler) createNewJobSpec(rec *k8s.Reconciliation[*apiv1.Deployment], phase Phase, app *apiv1.Application, initContainers []corev1.Container, container corev1.Container) (batchv1.Job, error) {
+	log.FromContext(rec.Ctx).WithValues("phase", phase).Info("Creating new job")
+
+	// Continue the rollout's trace in all job containers
+	for i := range initContainers {
+		initContainers[i].Env = append(initContainers[i].Env, traceParentEnvVars(rec)...)
+	}
+	container.Env = append(container.Env, traceParentEnvVars(rec)...)
+
+	job := batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            stringsutil.RandomHash(7),
 			Namespace:       rec.Object.Namespace,
@@ -438,54 +618,97 @@ func (r *DeploymentReconciler) createNewJobSpec(rec *k8s.Reconciliation[*apiv1.D
 			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(rec.Object, apiv1.DeploymentGVK)},
 		},
 		Spec: batchv1.JobSpec{
-			BackoffLimit:            lang.Ptr(int32(10)),                          // TODO: make number of retries configurable
-			TTLSecondsAfterFinished: lang.Ptr(int32((5 * time.Minute).Seconds())), // TODO: make TTL configurable
+			// Failed jobs are retried by the reconciler (with backoff & counted as failed attempts), not by Kubernetes
+			BackoffLimit:            lang.Ptr(jobBackoffLimit),
+			TTLSecondsAfterFinished: lang.Ptr(int32((5 * time.Minute).Seconds())),
 			Template: corev1.PodTemplateSpec{
 				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:       string(phase),
-							Image:      image,
-							WorkingDir: "/data",
-							Env:        jobEnvVars,
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
-									corev1.ResourceMemory: *resource.NewScaledQuantity(128, resource.Mega),
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceCPU:    *resource.NewScaledQuantity(200, resource.Milli),
-									corev1.ResourceMemory: *resource.NewScaledQuantity(256, resource.Mega),
-								},
-							},
-							VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
-						},
-					},
-					RestartPolicy:      corev1.RestartPolicyOnFailure,
+					InitContainers:     initContainers,
+					Containers:         []corev1.Container{container},
+					RestartPolicy:      jobRestartPolicy,
 					ServiceAccountName: app.Spec.ServiceAccountName,
-					Volumes: []corev1.Volume{
-						{
-							Name: "data",
-							VolumeSource: corev1.VolumeSource{
-								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-									ClaimName: rec.Object.Status.PersistentVolumeClaimName,
-								},
-							},
-						},
-					},
+					Volumes:            []corev1.Volume{{Name: "data", VolumeSource: r.workVolumeSource(rec, app)}},
 				},
 			},
 		},
-	}, nil
+	}
+	if err := r.applyJobTemplates(&job, app); err != nil {
+		return batchv1.Job{}, err
+	}
+	return job, nil
+}
+
+// cloneJobEnvVars returns the environment variables of the clone phase container.
+func cloneJobEnvVars(rec *k8s.Reconciliation[*apiv1.Deployment], url string, repoSettings *apiv1.ApplicationSpecRepository) []corev1.EnvVar {
+	envVars := []corev1.EnvVar{
+		{Name: "BRANCH", Value: rec.Object.Status.Branch},
+		{Name: "GIT_URL", Value: url},
+		{Name: "SHA", Value: rec.Object.Status.LastAttemptedRevision},
+		{Name: "TAG", Value: rec.Object.Status.Tag},
+	}
+	if repoSettings != nil && repoSettings.Clone != nil {
+		if repoSettings.Clone.Depth > 0 {
+			envVars = append(envVars, corev1.EnvVar{Name: "DEPTH", Value: strconv.Itoa(repoSettings.Clone.Depth)})
+		}
+		if repoSettings.Clone.Sparse {
+			sparseDirs := append([]string{repoSettings.Path}, repoSettings.Clone.SparsePaths...)
+			envVars = append(envVars, corev1.EnvVar{Name: "SPARSE_DIRS", Value: strings.Join(sparseDirs, ",")})
+		}
+		if repoSettings.Clone.Submodules {
+			envVars = append(envVars, corev1.EnvVar{Name: "SUBMODULES", Value: "true"})
+		}
+		if repoSettings.Clone.LFS {
+			envVars = append(envVars, corev1.EnvVar{Name: "LFS", Value: "true"})
+		}
+	}
+	return envVars
+}
+
+// bakeJobEnvVars returns the environment variables of the bake phase container.
+func bakeJobEnvVars(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, env *apiv1.Environment, repo *apiv1.Repository, repoSettings apiv1.ApplicationSpecRepository) []corev1.EnvVar {
+	// When deploying a tag, manifests are baked as if deploying the default branch
+	actualBranch := rec.Object.Status.Branch
+	if actualBranch == "" {
+		actualBranch = repo.Status.DefaultBranch
+	}
+
+	return []corev1.EnvVar{
+		{Name: "ACTUAL_BRANCH", Value: actualBranch},
+		{Name: "APPLICATION_NAME", Value: app.Name},
+		{Name: "BASE_DEPLOY_DIR", Value: repoSettings.Path},
+		{Name: "ENVIRONMENT", Value: env.Spec.GetDisplayName()},
+		{Name: "ENVIRONMENT_NAME", Value: env.Name},
+		{Name: "DEPLOYMENT_NAME", Value: rec.Object.Name},
+		{Name: "MANIFEST_FILE", Value: ".devbot.yaml"},
+		{Name: "PREFERRED_BRANCH", Value: env.Spec.PreferredBranch},
+		{Name: "REPO_DEFAULT_BRANCH", Value: repo.Status.DefaultBranch},
+		{Name: "SHA", Value: rec.Object.Status.LastAttemptedRevision},
+		{Name: "TAG", Value: rec.Object.Status.Tag},
+	}
+}
+
+// addCloneCredentials updates the deployment's Git credentials secret from the repository's credentials, and projects it
+// into the clone container of the given job.
+func (r *DeploymentReconciler) addCloneCredentials(rec *k8s.Reconciliation[*apiv1.Deployment], job *batchv1.Job, repo *apiv1.Repository) *k8s.Result {
+	secretName := rec.Object.Name + "-git-credentials"
+	if err := ensureGitCredentialsSecret(rec.Ctx, r.Client, rec.Object, apiv1.DeploymentGVK, secretName, repo); err != nil {
+		rec.Object.Status.SetMaybeStaleDueToRepositoryNotAccessible("Failed preparing clone credentials: %+v", err)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.Requeue()
+	}
+	addGitCredentialsVolume(job, secretName, string(PhaseClone))
+	return nil
 }
 
-func (r *DeploymentReconciler) createNewCloneJob(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, repo *apiv1.Repository) *k8s.Result {
+// createNewCloneJob creates the clone job of the deployment (or its single-pod job). Callers must have obtained a job
+// slot via scheduleJob.
+func (r *DeploymentReconciler) createNewCloneJob(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, env *apiv1.Environment, repo *apiv1.Repository, repoSettings *apiv1.ApplicationSpecRepository) *k8s.Result {
 	var url string
 
 	// Calculate Git URL based on repository type
-	if repo.Spec.GitHub != nil {
-		url = fmt.Sprintf("https://github.com/%s/%s", repo.Spec.GitHub.Owner, repo.Spec.GitHub.Name)
-	} else {
+	if url = gitURL(repo); url == "" {
 		rec.Object.Status.SetInvalidDueToRepositoryNotSupported("Unsupported repository")
 		rec.Object.Status.SetMaybeStaleDueToInvalid(rec.Object.Status.GetInvalidMessage())
 		if result := rec.UpdateStatus(); result != nil {
@@ -494,6 +717,11 @@ func (r *DeploymentReconciler) createNewCloneJob(rec *k8s.Reconciliation[*apiv1.
 		return k8s.DoNotRequeue()
 	}
 
+	// In single-pod mode, all phases run in a single job
+	if r.executionMode(app) == apiv1.SinglePodExecutionMode {
+		return r.createNewApplyJob(rec, app, env, repo, repoSettings)
+	}
+
 	// Set cloning status
 	rec.Object.Status.SetMaybeStaleDueToCloning("Launching clone job")
 	if result := rec.UpdateStatus(); result != nil {
@@ -501,7 +729,7 @@ func (r *DeploymentReconciler) createNewCloneJob(rec *k8s.Reconciliation[*apiv1.
 	}
 
 	// Create the job object
-	job, err := r.createNewJobSpec(rec, CloneJobImage, PhaseClone, app, corev1.EnvVar{Name: "BRANCH", Value: rec.Object.Status.Branch}, corev1.EnvVar{Name: "GIT_URL", Value: url}, corev1.EnvVar{Name: "SHA", Value: rec.Object.Status.LastAttemptedRevision})
+	job, err := r.createNewJobSpec(rec, PhaseClone, app, nil, r.newJobContainer(PhaseClone, CloneJobImage, cloneJobEnvVars(rec, url, repoSettings)...))
 	if err != nil {
 		rec.Object.Status.SetMaybeStaleDueToInternalError("Failed creating clone job spec: %+v", err)
 		if result := rec.UpdateStatus(); result != nil {
@@ -509,6 +737,10 @@ func (r *DeploymentReconciler) createNewCloneJob(rec *k8s.Reconciliation[*apiv1.
 		}
 		return k8s.Requeue()
 	}
+	addMirrorVolume(&job, repo)
+	if result := r.addCloneCredentials(rec, &job, repo); result != nil {
+		return result
+	}
 
 	// Create the job in the cluster
 	log.FromContext(rec.Ctx).WithValues("jobName", job.Name).Info("Creating clone job")
@@ -525,28 +757,16 @@ func (r *DeploymentReconciler) createNewCloneJob(rec *k8s.Reconciliation[*apiv1.
 	if result := rec.UpdateStatus(); result != nil {
 		return result
 	}
+	r.reportCommitStatus(rec, app, env, repo, CommitStatusPending, "Cloning revision")
 
 	// No requeue necessary - job completion/failure/suspension will trigger reconciliation
 	return k8s.DoNotRequeue()
 }
 
+// createNewBakeJob creates the bake job of the deployment. Callers must have obtained a job slot via scheduleJob.
 func (r *DeploymentReconciler) createNewBakeJob(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, env *apiv1.Environment, repo *apiv1.Repository, repoSettings apiv1.ApplicationSpecRepository) *k8s.Result {
 	// Create the job object
-	job, err := r.createNewJobSpec(
-		rec,
-		BakeJobImage,
-		PhaseBake,
-		app,
-		corev1.EnvVar{Name: "ACTUAL_BRANCH", Value: rec.Object.Status.Branch},
-		corev1.EnvVar{Name: "APPLICATION_NAME", Value: app.Name},
-		corev1.EnvVar{Name: "BASE_DEPLOY_DIR", Value: repoSettings.Path},
-		corev1.EnvVar{Name: "ENVIRONMENT_NAME", Value: env.Name},
-		corev1.EnvVar{Name: "DEPLOYMENT_NAME", Value: rec.Object.Name},
-		corev1.EnvVar{Name: "MANIFEST_FILE", Value: ".devbot.yaml"},
-		corev1.EnvVar{Name: "PREFERRED_BRANCH", Value: env.Spec.PreferredBranch},
-		corev1.EnvVar{Name: "REPO_DEFAULT_BRANCH", Value: repo.Status.DefaultBranch},
-		corev1.EnvVar{Name: "SHA", Value: rec.Object.Status.LastAttemptedRevision},
-	)
+	job, err := r.createNewJobSpec(rec, PhaseBake, app, nil, r.newJobContainer(PhaseBake, BakeJobImage, bakeJobEnvVars(rec, app, env, repo, repoSettings)...))
 	if err != nil {
 		rec.Object.Status.SetMaybeStaleDueToInternalError("Failed creating bake job spec: %+v", err)
 		if result := rec.UpdateStatus(); result != nil {
@@ -576,19 +796,41 @@ func (r *DeploymentReconciler) createNewBakeJob(rec *k8s.Reconciliation[*apiv1.D
 	if result := rec.UpdateStatus(); result != nil {
 		return result
 	}
+	r.reportCommitStatus(rec, app, env, repo, CommitStatusPending, "Baking deployment manifest")
 
 	// No requeue necessary - job completion/failure/suspension will trigger reconciliation
 	return k8s.DoNotRequeue()
 }
 
-func (r *DeploymentReconciler) createNewApplyJob(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, env *apiv1.Environment) *k8s.Result {
-	// Create the job object
-	job, err := r.createNewJobSpec(rec, ApplyJobImage, PhaseApply, app,
-		corev1.EnvVar{Name: "APPLICATION_NAME", Value: app.Name},
-		corev1.EnvVar{Name: "ENVIRONMENT_NAME", Value: env.Name},
-		corev1.EnvVar{Name: "DEPLOYMENT_NAME", Value: rec.Object.Name},
-		corev1.EnvVar{Name: "MANIFEST_FILE", Value: ".devbot.yaml"},
-	)
+// createNewApplyJob creates the apply job of the deployment (or its single-pod job). Callers must have obtained a job
+// slot via scheduleJob.
+func (r *DeploymentReconciler) createNewApplyJob(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, env *apiv1.Environment, repo *apiv1.Repository, repoSettings *apiv1.ApplicationSpecRepository) *k8s.Result {
+	// Create the job object; in single-pod mode, the clone & bake phases run as its init containers
+	envVars := []corev1.EnvVar{
+		{Name: "APPLICATION_NAME", Value: app.Name},
+		{Name: "ENVIRONMENT", Value: env.Spec.GetDisplayName()},
+		{Name: "ENVIRONMENT_NAME", Value: env.Name},
+		{Name: "DEPLOYMENT_NAME", Value: rec.Object.Name},
+		{Name: "MANIFEST_FILE", Value: ".devbot.yaml"},
+	}
+	var waitForImages *apiv1.ApplicationSpecRepositoryWaitForImages
+	if repoSettings.WaitForImages != nil {
+		waitForImages = repoSettings.WaitForImages
+		envVars = append(envVars,
+			corev1.EnvVar{Name: "WAIT_FOR_IMAGES", Value: "true"},
+			corev1.EnvVar{Name: "IMAGES_TIMEOUT", Value: waitForImages.Timeout},
+			corev1.EnvVar{Name: "PULL_SECRETS_DIR", Value: pullSecretsMountPath},
+		)
+	}
+	singlePod := r.executionMode(app) == apiv1.SinglePodExecutionMode
+	var initContainers []corev1.Container
+	if singlePod {
+		initContainers = []corev1.Container{
+			r.newJobContainer(PhaseClone, CloneJobImage, cloneJobEnvVars(rec, gitURL(repo), repoSettings)...),
+			r.newJobContainer(PhaseBake, BakeJobImage, bakeJobEnvVars(rec, app, env, repo, *repoSettings)...),
+		}
+	}
+	job, err := r.createNewJobSpec(rec, PhaseApply, app, initContainers, r.newJobContainer(PhaseApply, ApplyJobImage, envVars...))
 	if err != nil {
 		rec.Object.Status.SetMaybeStaleDueToInternalError("Failed creating apply job spec: %+v", err)
 		if result := rec.UpdateStatus(); result != nil {
@@ -596,6 +838,15 @@ func (r *DeploymentReconciler) createNewApplyJob(rec *k8s.Reconciliation[*apiv1.
 		}
 		return k8s.Requeue()
 	}
+	if waitForImages != nil {
+		addPullSecretsVolume(&job, waitForImages.PullSecrets)
+	}
+	if singlePod {
+		addMirrorVolume(&job, repo)
+		if result := r.addCloneCredentials(rec, &job, repo); result != nil {
+			return result
+		}
+	}
 
 	// Set a cloning status
 	rec.Object.Status.SetMaybeStaleDueToCloning("Launching apply job")
@@ -614,10 +865,15 @@ func (r *DeploymentReconciler) createNewApplyJob(rec *k8s.Reconciliation[*apiv1.
 	}
 
 	// Update status to reflect we're waiting for the clone job to finish
-	rec.Object.Status.SetMaybeStaleDueToBaking("Waiting for apply job '%s' to finish", job.Name)
+	if singlePod {
+		rec.Object.Status.SetMaybeStaleDueToCloning("Waiting for job '%s' to clone, bake & apply", job.Name)
+	} else {
+		rec.Object.Status.SetMaybeStaleDueToBaking("Waiting for apply job '%s' to finish", job.Name)
+	}
 	if result := rec.UpdateStatus(); result != nil {
 		return result
 	}
+	r.reportCommitStatus(rec, app, env, repo, CommitStatusPending, "Applying deployment manifest")
 
 	// No requeue necessary - job completion/failure/suspension will trigger reconciliation
 	return k8s.DoNotRequeue()
@@ -629,10 +885,21 @@ func (r *DeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&apiv1.Deployment{}, builder.WithPredicates(predicate.Funcs{
 			UpdateFunc: func(e event.UpdateEvent) bool {
-				// Only reconcile if the generation has changed
-				return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+				// Only reconcile if the generation has changed, or if a retry or redeploy was requested
+				if e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() {
+					return true
+				} else if isRedeployAnnotationChanged(e.ObjectOld, e.ObjectNew) {
+					return true
+				}
+				return e.ObjectOld.GetAnnotations()[apiv1.RetryAnnotation] != e.ObjectNew.GetAnnotations()[apiv1.RetryAnnotation]
 			},
 		})).
+		Watches(&apiv1.Environment{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+			return r.getControlledDeploymentRequests(ctx, obj.(*apiv1.Environment))
+		}), builder.WithPredicates(redeployAnnotationChanged)).
+		Watches(&apiv1.Application{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+			return r.getApplicationDeploymentRequests(ctx, obj.(*apiv1.Application))
+		}), builder.WithPredicates(redeployAnnotationChanged)).
 		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
 			job := obj.(*batchv1.Job)
 			controllerRef := metav1.GetControllerOf(job)
@@ -666,6 +933,6 @@ func (r *DeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				}
 			}
 			return requests
-		})).
+		}), builder.WithPredicates(repositoryUpdateRelevantToDependents)).
 		Complete(r)
 }
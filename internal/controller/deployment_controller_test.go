@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/lang"
+	"github.com/arikkfir/devbot/internal/util/registry"
+	"github.com/arikkfir/devbot/internal/util/termination"
+)
+
+var _ = Describe("Clone job environment", func() {
+	rec := &k8s.Reconciliation[*apiv1.Deployment]{
+		Ctx: context.Background(),
+		Object: &apiv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"},
+			Status:     apiv1.DeploymentStatus{Branch: "main", LastAttemptedRevision: "abc"},
+		},
+	}
+
+	It("should clone the full tree & history by default", func() {
+		envVars := cloneJobEnvVars(rec, "https://github.com/owner/repo", &apiv1.ApplicationSpecRepository{Path: "deploy"})
+		Expect(envVars).To(ConsistOf(
+			corev1.EnvVar{Name: "BRANCH", Value: "main"},
+			corev1.EnvVar{Name: "GIT_URL", Value: "https://github.com/owner/repo"},
+			corev1.EnvVar{Name: "SHA", Value: "abc"},
+			corev1.EnvVar{Name: "TAG", Value: ""},
+		))
+	})
+
+	It("should pass the clone depth & sparse directories", func() {
+		repoSettings := &apiv1.ApplicationSpecRepository{
+			Path:  "deploy",
+			Clone: &apiv1.ApplicationSpecRepositoryClone{Depth: 1, Sparse: true, SparsePaths: []string{"base"}},
+		}
+		envVars := cloneJobEnvVars(rec, "https://github.com/owner/repo", repoSettings)
+		Expect(envVars).To(ContainElements(
+			corev1.EnvVar{Name: "DEPTH", Value: "1"},
+			corev1.EnvVar{Name: "SPARSE_DIRS", Value: "deploy,base"},
+		))
+	})
+
+	It("should enable submodules & LFS", func() {
+		repoSettings := &apiv1.ApplicationSpecRepository{
+			Path:  "deploy",
+			Clone: &apiv1.ApplicationSpecRepositoryClone{Submodules: true, LFS: true},
+		}
+		envVars := cloneJobEnvVars(rec, "https://github.com/owner/repo", repoSettings)
+		Expect(envVars).To(ContainElements(
+			corev1.EnvVar{Name: "SUBMODULES", Value: "true"},
+			corev1.EnvVar{Name: "LFS", Value: "true"},
+		))
+	})
+})
+
+var _ = Describe("Job termination messages", func() {
+	It("should prefer the current termination over the last one", func() {
+		cs := corev1.ContainerStatus{
+			State:                corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: registry.MissingImagesMessagePrefix + "new\n"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: registry.MissingImagesMessagePrefix + "old"}},
+		}
+		Expect(getTerminationMessage(cs, registry.MissingImagesMessagePrefix)).To(Equal(registry.MissingImagesMessagePrefix + "new"))
+	})
+
+	It("should match the error of structured messages", func() {
+		message := termination.Message{Step: "wait for images", Error: registry.MissingImagesMessagePrefix + "app:1"}
+		cs := corev1.ContainerStatus{
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: string(message.Encode())}},
+		}
+		Expect(getTerminationMessage(cs, registry.MissingImagesMessagePrefix)).To(Equal(registry.MissingImagesMessagePrefix + "app:1"))
+	})
+
+	It("should ignore messages without the given prefixes", func() {
+		cs := corev1.ContainerStatus{
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: "panic"}},
+		}
+		Expect(getTerminationMessage(cs, registry.MissingImagesMessagePrefix)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Deployment reconciliation", func() {
+	var app *apiv1.Application
+	var env *apiv1.Environment
+	var deployment *apiv1.Deployment
+	var repo *apiv1.Repository
+	BeforeEach(func() {
+		app = &apiv1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app", UID: "app-uid"}}
+		env = &apiv1.Environment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "ns",
+				Name:            "env",
+				UID:             "env-uid",
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: apiv1.GroupVersion.String(), Kind: apiv1.ApplicationGVK.Kind, Name: "app", UID: "app-uid", Controller: lang.Ptr(true)}},
+			},
+			Spec: apiv1.EnvironmentSpec{PreferredBranch: "main"},
+		}
+		deployment = &apiv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "ns",
+				Name:            "my-deployment",
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: apiv1.GroupVersion.String(), Kind: apiv1.EnvironmentGVK.Kind, Name: "env", UID: "env-uid", Controller: lang.Ptr(true)}},
+			},
+			Spec: apiv1.DeploymentSpec{Repository: apiv1.DeploymentRepositoryReference{Namespace: "ns", Name: "repo"}},
+		}
+		repo = &apiv1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "repo"},
+			Status:     apiv1.RepositoryStatus{DefaultBranch: "main", Revisions: map[string]string{"main": "abc"}},
+		}
+	})
+	reconcile := func(r *DeploymentReconciler) *k8s.Result {
+		var result *k8s.Result
+		for range 5 {
+			if result = r.executeReconciliation(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}); result == nil || result.RequeueAfter == nil && !result.Requeue {
+				break
+			}
+		}
+		Expect(r.Client.Get(context.Background(), client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+		return result
+	}
+	listJobs := func(r *DeploymentReconciler) []batchv1.Job {
+		jobs := &batchv1.JobList{}
+		Expect(r.Client.List(context.Background(), jobs)).To(Succeed())
+		return jobs.Items
+	}
+	deployable := func() *corev1.Secret {
+		app.Spec.Repositories = []apiv1.ApplicationSpecRepository{{Namespace: "ns", Name: "repo"}}
+		app.Spec.WorkVolume = &apiv1.ApplicationSpecWorkVolume{Mode: apiv1.EmptyDirWorkVolumeMode}
+		repo.Spec.GitHub = &apiv1.GitHubRepositorySpec{
+			Owner: "owner",
+			Name:  "repo",
+			PersonalAccessToken: apiv1.GitHubRepositoryPersonalAccessToken{
+				Secret: apiv1.SecretReferenceWithOptionalNamespace{Name: "github"},
+				Key:    "token",
+			},
+		}
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "github"},
+			Data:       map[string][]byte{"token": []byte("t0k3n")},
+		}
+	}
+	failedAttempts := func(failures int, failedAt time.Time) *apiv1.DeploymentStatusFailedAttempts {
+		return &apiv1.DeploymentStatusFailedAttempts{
+			Revision:        "abc",
+			Phases:          map[string]int{string(PhaseBake): failures},
+			LastFailedJob:   "bake1",
+			LastFailedPhase: string(PhaseBake),
+			LastFailure:     "kustomize build: exit status 1",
+			LastFailureTime: &metav1.Time{Time: failedAt},
+		}
+	}
+
+	It("should stop if the application lacks the deployment's repository settings", func() {
+		r, _ := newDeploymentReconciliation(deployment, app, env, repo)
+		Expect(reconcile(r)).To(Equal(k8s.DoNotRequeue()))
+		Expect(deployment.Status.IsInvalid()).To(BeTrue())
+		Expect(listJobs(r)).To(BeEmpty())
+	})
+
+	It("should not restart a revision that exhausted its attempts once its failed job is deleted", func() {
+		secret := deployable()
+		deployment.Status.LastAttemptedRevision = "abc"
+		deployment.Status.FailedAttempts = failedAttempts(defaultMaxAttempts, time.Now().Add(-time.Hour))
+		r, _ := newDeploymentReconciliation(deployment, app, env, repo, secret)
+		Expect(reconcile(r)).To(Equal(k8s.DoNotRequeue()))
+		Expect(deployment.Status.GetStaleReason()).To(Equal(apiv1.AttemptsExhausted))
+		Expect(listJobs(r)).To(BeEmpty())
+	})
+
+	It("should restart a failed revision whose failed job was deleted once its retry is due", func() {
+		secret := deployable()
+		deployment.Status.LastAttemptedRevision = "abc"
+		deployment.Status.FailedAttempts = failedAttempts(1, time.Now())
+		r, _ := newDeploymentReconciliation(deployment, app, env, repo, secret)
+		Expect(reconcile(r).RequeueAfter).NotTo(BeNil())
+		Expect(listJobs(r)).To(BeEmpty())
+
+		deployment.Status.FailedAttempts.LastFailureTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+		Expect(r.Client.Status().Update(context.Background(), deployment)).To(Succeed())
+		reconcile(r)
+		Expect(listJobs(r)).To(HaveLen(1))
+	})
+
+	It("should not start a superseding revision while the superseded job still exists", func() {
+		secret := deployable()
+		deployment.Status.Branch = "main"
+		deployment.Status.LastAttemptedRevision = "old"
+		superseded := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "ns",
+				Name:            "superseded",
+				Labels:          map[string]string{PhaseLabel: string(PhaseBake)},
+				Finalizers:      []string{"test.devbot.com/pods"},
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: apiv1.GroupVersion.String(), Kind: apiv1.DeploymentGVK.Kind, Name: "my-deployment", UID: "deployment-uid", Controller: lang.Ptr(true)}},
+			},
+			Status: batchv1.JobStatus{Active: 1},
+		}
+		r, _ := newDeploymentReconciliation(deployment, app, env, repo, secret, superseded)
+
+		// The superseded job is cancelled, but lingers until its pods are gone
+		Expect(reconcile(r).RequeueAfter).NotTo(BeNil())
+		Expect(deployment.Status.SupersededRevision).To(Equal("old"))
+		jobs := listJobs(r)
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].Name).To(Equal("superseded"))
+		Expect(jobs[0].DeletionTimestamp).NotTo(BeNil())
+
+		// Once it's gone, the superseding revision starts
+		jobs[0].Finalizers = nil
+		Expect(r.Client.Update(context.Background(), &jobs[0])).To(Succeed())
+		reconcile(r)
+		jobs = listJobs(r)
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].Name).NotTo(Equal("superseded"))
+		Expect(deployment.Status.LastAttemptedRevision).To(Equal("abc"))
+	})
+})
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/lang"
+	"github.com/arikkfir/devbot/internal/util/registry"
+)
+
+const (
+	// pullSecretsMountPath is where registry pull secrets are mounted in apply jobs waiting for images.
+	pullSecretsMountPath = "/pull-secrets"
+
+	// imagesPollInterval is how often an apply job waiting for images is checked for missing images.
+	imagesPollInterval = 30 * time.Second
+)
+
+// addPullSecretsVolume mounts the given pull secrets into the given job's container, each secret's Docker config in its
+// own subdirectory. Missing secrets do not prevent the job from starting; checking the images will fail instead.
+func addPullSecretsVolume(job *batchv1.Job, pullSecrets []corev1.LocalObjectReference) {
+	if len(pullSecrets) == 0 {
+		return
+	}
+	var sources []corev1.VolumeProjection
+	for _, secret := range pullSecrets {
+		sources = append(sources, corev1.VolumeProjection{
+			Secret: &corev1.SecretProjection{
+				LocalObjectReference: secret,
+				Items:                []corev1.KeyToPath{{Key: corev1.DockerConfigJsonKey, Path: secret.Name + "/" + corev1.DockerConfigJsonKey}},
+				Optional:             lang.Ptr(true),
+			},
+		})
+	}
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         "pull-secrets",
+		VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: sources}},
+	})
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].VolumeMounts = append(podSpec.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name:      "pull-secrets",
+			MountPath: pullSecretsMountPath,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// reportMissingImages reflects the images the given active apply job is waiting for in the deployment status, as
+// reported by the termination message of the job's last failed attempt, and polls the job until it finishes.
+func (r *DeploymentReconciler) reportMissingImages(rec *k8s.Reconciliation[*apiv1.Deployment], job *batchv1.Job) *k8s.Result {
+	statuses, err := r.getJobContainerStatuses(rec, job, string(PhaseApply))
+	if err != nil {
+		rec.Object.Status.SetMaybeStaleDueToInternalError("Failed inspecting pods of apply job '%s': %+v", job.Name, err)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.RequeueAfter(imagesPollInterval)
+	}
+
+	message := ""
+	for _, cs := range statuses {
+		if m := getTerminationMessage(cs, registry.MissingImagesMessagePrefix); m != "" {
+			message = m
+		}
+	}
+	if message != "" {
+		rec.Object.Status.SetMaybeStaleDueToWaitingForImages("%s", message)
+	} else {
+		rec.Object.Status.SetMaybeStaleDueToWaitingForImages("Waiting for apply job '%s' to verify container images exist", job.Name)
+	}
+	if result := rec.UpdateStatus(); result != nil {
+		return result
+	}
+	return k8s.RequeueAfter(imagesPollInterval)
+}
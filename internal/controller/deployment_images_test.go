@@ -0,0 +1,60 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+var _ = Describe("Waiting for images", func() {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "apply"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"batch.kubernetes.io/job-name": "apply"}},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "apply"}}}},
+		},
+	}
+
+	newRec := func(objects ...runtime.Object) (*DeploymentReconciler, *k8s.Reconciliation[*apiv1.Deployment]) {
+		return newDeploymentReconciliation(&apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"}}, objects...)
+	}
+
+	It("should mount pull secrets into the job container", func() {
+		j := job.DeepCopy()
+		addPullSecretsVolume(j, []corev1.LocalObjectReference{{Name: "ghcr"}})
+		Expect(j.Spec.Template.Spec.Volumes).To(HaveLen(1))
+		Expect(j.Spec.Template.Spec.Volumes[0].Projected.Sources[0].Secret.Items[0].Path).To(Equal("ghcr/.dockerconfigjson"))
+		Expect(j.Spec.Template.Spec.Containers[0].VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: "pull-secrets", MountPath: pullSecretsMountPath, ReadOnly: true}))
+	})
+
+	It("should report missing images from the last termination message", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "apply-xyz", Labels: map[string]string{"batch.kubernetes.io/job-name": "apply"}},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: "apply",
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Message: "Missing images: ghcr.io/owner/app:abc123"},
+					},
+				}},
+			},
+		}
+		r, rec := newRec(pod)
+		Expect(r.reportMissingImages(rec, job)).NotTo(BeNil())
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.WaitingForImages))
+		Expect(rec.Object.Status.GetStaleMessage()).To(Equal("Missing images: ghcr.io/owner/app:abc123"))
+	})
+
+	It("should report waiting for images before the first attempt failed", func() {
+		r, rec := newRec()
+		Expect(r.reportMissingImages(rec, job)).NotTo(BeNil())
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.WaitingForImages))
+		Expect(rec.Object.Status.GetStaleMessage()).To(ContainSubstring("apply job 'apply'"))
+	})
+})
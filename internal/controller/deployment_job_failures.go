@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/registry"
+	"github.com/arikkfir/devbot/internal/util/termination"
+)
+
+// jobFailurePollInterval is how often an active job is checked for failures of its containers, which it retries.
+const jobFailurePollInterval = 15 * time.Second
+
+// getJobPods returns the pods of the given job.
+func (r *DeploymentReconciler) getJobPods(rec *k8s.Reconciliation[*apiv1.Deployment], job *batchv1.Job) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(rec.Ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// getJobContainerStatuses returns the statuses of the named container (or init container) in the pods of the given job.
+func (r *DeploymentReconciler) getJobContainerStatuses(rec *k8s.Reconciliation[*apiv1.Deployment], job *batchv1.Job, containerName string) ([]corev1.ContainerStatus, error) {
+	pods, err := r.getJobPods(rec, job)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []corev1.ContainerStatus
+	for _, pod := range pods {
+		for _, cs := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			if cs.Name == containerName {
+				statuses = append(statuses, cs)
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// getTerminationMessage returns the error of the given container's current or last termination message, if it carries
+// one of the given prefixes.
+func getTerminationMessage(cs corev1.ContainerStatus, prefixes ...string) string {
+	for _, t := range []*corev1.ContainerStateTerminated{cs.State.Terminated, cs.LastTerminationState.Terminated} {
+		if t == nil {
+			continue
+		}
+		message := termination.Parse(t.Message)
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(message.Error, prefix) {
+				return message.Error
+			}
+		}
+	}
+	return ""
+}
+
+// getJobFailure returns the termination message of the most recent failure of the given phase's container in the pods
+// of the given job, if any. Containers that eventually succeeded are ignored, as are failures reporting missing images,
+// which are reported separately.
+func (r *DeploymentReconciler) getJobFailure(rec *k8s.Reconciliation[*apiv1.Deployment], job *batchv1.Job, phase Phase) (*termination.Message, error) {
+	statuses, err := r.getJobContainerStatuses(rec, job, string(phase))
+	if err != nil {
+		return nil, err
+	}
+
+	var failure *termination.Message
+	var failedAt time.Time
+	for _, cs := range statuses {
+		if t := cs.State.Terminated; t != nil && t.ExitCode == 0 {
+			continue
+		}
+		for _, t := range []*corev1.ContainerStateTerminated{cs.State.Terminated, cs.LastTerminationState.Terminated} {
+			if t == nil || t.ExitCode == 0 || strings.TrimSpace(t.Message) == "" {
+				continue
+			} else if failure != nil && !t.FinishedAt.Time.After(failedAt) {
+				continue
+			}
+			message := termination.Parse(t.Message)
+			if strings.HasPrefix(message.Error, registry.MissingImagesMessagePrefix) {
+				continue
+			}
+			failure, failedAt = &message, t.FinishedAt.Time
+		}
+	}
+	return failure, nil
+}
+
+// setPhaseFailed sets the failure condition of the given phase with the given message.
+func setPhaseFailed(status *apiv1.DeploymentStatus, phase Phase, format string, args ...any) {
+	switch phase {
+	case PhaseClone:
+		status.SetMaybeStaleDueToCloneFailed(format, args...)
+	case PhaseBake:
+		status.SetMaybeStaleDueToBakingFailed(format, args...)
+	case PhaseApply:
+		status.SetMaybeStaleDueToApplyFailed(format, args...)
+	default:
+		panic("unsupported phase: " + phase)
+	}
+}
+
+// reportJobFailure records the failure of the given phase of the given failed job as a failed attempt, and reflects it
+// in the deployment status & the commit status of the revision, as reported by the termination message of the failed
+// container. If no failure details are available, the given fallback message is used instead. Failures of jobs that
+// were already recorded are ignored.
+func (r *DeploymentReconciler) reportJobFailure(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, env *apiv1.Environment, repo *apiv1.Repository, phase Phase, job *batchv1.Job, fallback string) *k8s.Result {
+	if isFailedAttemptCounted(&rec.Object.Status, job) {
+		return nil
+	}
+
+	message := fallback
+	if failure, err := r.getJobFailure(rec, job, phase); err != nil {
+		rec.Object.Status.SetMaybeStaleDueToInternalError("Failed inspecting pods of job '%s': %+v", job.Name, err)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.Requeue()
+	} else if failure != nil {
+		message = failure.String()
+	}
+
+	countFailedAttempt(&rec.Object.Status, phase, job, message)
+	setPhaseFailed(&rec.Object.Status, phase, "%s", message)
+	if result := rec.UpdateStatus(); result != nil {
+		return result
+	}
+	r.reportCommitStatus(rec, app, env, repo, CommitStatusFailure, "The %s phase failed: %s", phase, message)
+	return nil
+}
+
+// reportRetriedJobFailure reflects the last failure of the given phase's container of the given active job, which the
+// job retries, in the deployment status, and polls the job to track its retries. Once the container succeeds, a
+// previously reported failure is replaced with a progress status, and nil is returned, leaving the job's tracking to the
+// caller.
+func (r *DeploymentReconciler) reportRetriedJobFailure(rec *k8s.Reconciliation[*apiv1.Deployment], phase Phase, job *batchv1.Job) *k8s.Result {
+	failure, err := r.getJobFailure(rec, job, phase)
+	if err != nil {
+		rec.Object.Status.SetMaybeStaleDueToInternalError("Failed inspecting pods of job '%s': %+v", job.Name, err)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.RequeueAfter(jobFailurePollInterval)
+	}
+
+	if failure != nil {
+		setPhaseFailed(&rec.Object.Status, phase, "Retrying after failure: %s", failure.String())
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.RequeueAfter(jobFailurePollInterval)
+	}
+
+	switch rec.Object.Status.GetStaleReason() {
+	case apiv1.CloneFailed, apiv1.BakingFailed, apiv1.ApplyFailed:
+		switch phase {
+		case PhaseClone:
+			rec.Object.Status.SetMaybeStaleDueToCloning("Waiting for job '%s' to clone", job.Name)
+		case PhaseBake:
+			rec.Object.Status.SetMaybeStaleDueToBaking("Waiting for job '%s' to bake", job.Name)
+		case PhaseApply:
+			rec.Object.Status.SetMaybeStaleDueToApplying("Waiting for job '%s' to apply", job.Name)
+		default:
+			panic("unsupported phase: " + phase)
+		}
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+	}
+	return nil
+}
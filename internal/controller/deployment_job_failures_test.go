@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/registry"
+	"github.com/arikkfir/devbot/internal/util/termination"
+)
+
+var _ = Describe("Job failures", func() {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "bake"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"batch.kubernetes.io/job-name": "bake"}},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "bake"}}}},
+		},
+	}
+	app := &apiv1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"}}
+	env := &apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "env"}}
+	repo := &apiv1.Repository{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "repo"}}
+	kustomizeFailure := termination.Message{Step: "kustomize build", Error: "exit status 1", Output: "Error: missing kustomization.yaml"}
+
+	newPod := func(cs corev1.ContainerStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "bake-xyz", Labels: map[string]string{"batch.kubernetes.io/job-name": "bake"}},
+			Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{cs}},
+		}
+	}
+	failed := func(message string, finishedAt time.Time) *corev1.ContainerStateTerminated {
+		return &corev1.ContainerStateTerminated{ExitCode: 1, Message: message, FinishedAt: metav1.NewTime(finishedAt)}
+	}
+
+	newRec := func(objects ...runtime.Object) (*DeploymentReconciler, *k8s.Reconciliation[*apiv1.Deployment]) {
+		return newDeploymentReconciliation(&apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"}}, objects...)
+	}
+
+	It("should report the failure of a failed job's phase", func() {
+		r, rec := newRec(newPod(corev1.ContainerStatus{
+			Name:  "bake",
+			State: corev1.ContainerState{Terminated: failed(string(kustomizeFailure.Encode()), time.Now())},
+		}))
+		Expect(r.reportJobFailure(rec, app, env, repo, PhaseBake, job, "BackoffLimitExceeded")).To(BeNil())
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.BakingFailed))
+		Expect(rec.Object.Status.GetStaleMessage()).To(Equal("kustomize build: exit status 1\nError: missing kustomization.yaml"))
+		Expect(rec.Object.Status.FailedAttempts.Phases).To(Equal(map[string]int{"bake": 1}))
+		Expect(rec.Object.Status.FailedAttempts.LastFailure).To(Equal(rec.Object.Status.GetStaleMessage()))
+
+		// The same job is counted once
+		Expect(r.reportJobFailure(rec, app, env, repo, PhaseBake, job, "BackoffLimitExceeded")).To(BeNil())
+		Expect(rec.Object.Status.FailedAttempts.Phases).To(Equal(map[string]int{"bake": 1}))
+	})
+
+	It("should fall back to the job's failure message", func() {
+		r, rec := newRec()
+		Expect(r.reportJobFailure(rec, app, env, repo, PhaseApply, job, "BackoffLimitExceeded")).To(BeNil())
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.ApplyFailed))
+		Expect(rec.Object.Status.GetStaleMessage()).To(Equal("BackoffLimitExceeded"))
+	})
+
+	It("should report the most recent failure of retried phases", func() {
+		r, rec := newRec(newPod(corev1.ContainerStatus{
+			Name:                 "bake",
+			State:                corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			LastTerminationState: corev1.ContainerState{Terminated: failed(string(kustomizeFailure.Encode()), time.Now())},
+		}))
+		Expect(r.reportRetriedJobFailure(rec, PhaseBake, job)).NotTo(BeNil())
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.BakingFailed))
+		Expect(rec.Object.Status.GetStaleMessage()).To(HavePrefix("Retrying after failure: kustomize build: exit status 1"))
+	})
+
+	It("should report unstructured failures verbatim", func() {
+		r, rec := newRec(newPod(corev1.ContainerStatus{
+			Name:                 "bake",
+			LastTerminationState: corev1.ContainerState{Terminated: failed("panic: oops\n", time.Now())},
+		}))
+		Expect(r.reportRetriedJobFailure(rec, PhaseBake, job)).NotTo(BeNil())
+		Expect(rec.Object.Status.GetStaleMessage()).To(Equal("Retrying after failure: panic: oops"))
+	})
+
+	It("should leave missing images to the images report", func() {
+		message := termination.Message{Step: "wait for images", Error: registry.MissingImagesMessagePrefix + "app:1"}
+		r, rec := newRec(newPod(corev1.ContainerStatus{
+			Name:                 "bake",
+			LastTerminationState: corev1.ContainerState{Terminated: failed(string(message.Encode()), time.Now())},
+		}))
+		Expect(r.reportRetriedJobFailure(rec, PhaseBake, job)).To(BeNil())
+	})
+
+	It("should restore the progress status once the phase succeeds", func() {
+		r, rec := newRec(newPod(corev1.ContainerStatus{
+			Name:                 "bake",
+			State:                corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}},
+			LastTerminationState: corev1.ContainerState{Terminated: failed(string(kustomizeFailure.Encode()), time.Now())},
+		}))
+		rec.Object.Status.SetMaybeStaleDueToBakingFailed("Retrying after failure: oops")
+		Expect(r.reportRetriedJobFailure(rec, PhaseBake, job)).To(BeNil())
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.Baking))
+	})
+})
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/lang"
+)
+
+// ParseJobTemplate parses the given YAML (or JSON) partial Job into a job template, verifying it can be merged onto
+// jobs.
+func ParseJobTemplate(data []byte) (*runtime.RawExtension, error) {
+	raw, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing job template: %w", err)
+	}
+	template := &runtime.RawExtension{Raw: raw}
+	if err := applyJobTemplate(&batchv1.Job{}, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// applyJobTemplates merges the controller's job template, followed by the given application's job template, onto the
+// given job. The job's identity (name, namespace, owner & phase label) is retained, as are its backoff limit & restart
+// policy, since failed jobs are retried by the reconciler.
+func (r *DeploymentReconciler) applyJobTemplates(job *batchv1.Job, app *apiv1.Application) error {
+	name, namespace, ownerReferences, phase := job.Name, job.Namespace, job.OwnerReferences, job.Labels[PhaseLabel]
+	if err := applyJobTemplate(job, r.JobTemplate); err != nil {
+		return fmt.Errorf("failed applying controller job template: %w", err)
+	}
+	if err := applyJobTemplate(job, app.Spec.JobTemplate); err != nil {
+		return fmt.Errorf("failed applying job template of application '%s': %w", app.Name, err)
+	}
+	job.Name, job.Namespace, job.OwnerReferences = name, namespace, ownerReferences
+	if job.Labels == nil {
+		job.Labels = make(map[string]string)
+	}
+	job.Labels[PhaseLabel] = phase
+	job.Spec.BackoffLimit = lang.Ptr(jobBackoffLimit)
+	job.Spec.Template.Spec.RestartPolicy = jobRestartPolicy
+	return nil
+}
+
+// applyJobTemplate merges the given job template (if any) onto the given job using strategic-merge semantics.
+func applyJobTemplate(job *batchv1.Job, template *runtime.RawExtension) error {
+	if template == nil || len(template.Raw) == 0 {
+		return nil
+	}
+
+	original, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed encoding job: %w", err)
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, template.Raw, batchv1.Job{})
+	if err != nil {
+		return fmt.Errorf("failed merging job template: %w", err)
+	}
+	result := batchv1.Job{}
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return fmt.Errorf("failed decoding merged job: %w", err)
+	}
+	*job = result
+	return nil
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/lang"
+)
+
+var _ = Describe("Job templates", func() {
+	rec := &k8s.Reconciliation[*apiv1.Deployment]{
+		Ctx:    context.Background(),
+		Object: &apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"}},
+	}
+
+	It("should merge controller & application templates onto jobs", func() {
+		controllerTemplate, err := ParseJobTemplate([]byte(`
+spec:
+  backoffLimit: 3
+  template:
+    spec:
+      nodeSelector:
+        pool: general
+      securityContext:
+        runAsNonRoot: true
+      imagePullSecrets:
+        - name: registry
+`))
+		Expect(err).NotTo(HaveOccurred())
+		r := &DeploymentReconciler{JobTemplate: controllerTemplate}
+		app := &apiv1.Application{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-app"},
+			Spec: apiv1.ApplicationSpec{
+				ServiceAccountName: "deployer",
+				JobTemplate: &runtime.RawExtension{Raw: []byte(`{
+					"metadata": {"name": "hijacked", "labels": {"team": "a"}},
+					"spec": {
+						"ttlSecondsAfterFinished": 60,
+						"template": {"spec": {
+							"tolerations": [{"key": "dedicated", "operator": "Exists"}],
+							"containers": [{"name": "clone", "resources": {"limits": {"memory": "1Gi"}}}]
+						}}
+					}
+				}`)},
+			},
+		}
+
+		job, err := r.createNewJobSpec(rec, PhaseClone, app, nil, r.newJobContainer(PhaseClone, CloneJobImage))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Name).NotTo(Equal("hijacked"))
+		Expect(job.Labels).To(HaveKeyWithValue(PhaseLabel, string(PhaseClone)))
+		Expect(job.Labels).To(HaveKeyWithValue("team", "a"))
+		Expect(job.OwnerReferences).To(HaveLen(1))
+		Expect(job.Spec.BackoffLimit).To(Equal(lang.Ptr(int32(0))))
+		Expect(job.Spec.TTLSecondsAfterFinished).To(Equal(lang.Ptr(int32(60))))
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"pool": "general"}))
+		Expect(podSpec.SecurityContext.RunAsNonRoot).To(Equal(lang.Ptr(true)))
+		Expect(podSpec.ImagePullSecrets).To(ConsistOf(corev1.LocalObjectReference{Name: "registry"}))
+		Expect(podSpec.Tolerations).To(ConsistOf(corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists}))
+		Expect(podSpec.ServiceAccountName).To(Equal("deployer"))
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Containers).To(HaveLen(1))
+		Expect(podSpec.Containers[0].Image).To(Equal(CloneJobImage))
+		Expect(podSpec.Containers[0].Resources.Limits.Memory().Cmp(resource.MustParse("1Gi"))).To(Equal(0))
+		Expect(podSpec.Containers[0].Resources.Requests.Memory().IsZero()).To(BeFalse())
+	})
+
+	It("should leave jobs untouched without templates", func() {
+		r := &DeploymentReconciler{}
+		job, err := r.createNewJobSpec(rec, PhaseBake, &apiv1.Application{}, nil, r.newJobContainer(PhaseBake, BakeJobImage))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.BackoffLimit).To(Equal(lang.Ptr(int32(0))))
+		Expect(job.Spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+		Expect(job.Spec.Template.Spec.NodeSelector).To(BeEmpty())
+	})
+
+	It("should reject invalid templates", func() {
+		_, err := ParseJobTemplate([]byte("spec: [1, 2]"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+// getRedeployRequest returns the redeploy requests currently made on the given deployment, its environment & its
+// application, via their redeploy annotations.
+func getRedeployRequest(app *apiv1.Application, env *apiv1.Environment, deployment *apiv1.Deployment) apiv1.DeploymentStatusRedeployRequest {
+	return apiv1.DeploymentStatusRedeployRequest{
+		Application: app.Annotations[apiv1.RedeployAnnotation],
+		Environment: env.Annotations[apiv1.RedeployAnnotation],
+		Deployment:  deployment.Annotations[apiv1.RedeployAnnotation],
+	}
+}
+
+// acceptRedeployRequest records the given redeploy request as handled, granting the redeployed revision a fresh set of
+// attempts.
+func acceptRedeployRequest(status *apiv1.DeploymentStatus, request apiv1.DeploymentStatusRedeployRequest) {
+	if status.LastRedeployRequest != request {
+		status.LastRedeployRequest = request
+		status.FailedAttempts = nil
+	}
+}
+
+// isRedeployAnnotationChanged checks whether the redeploy annotation differs between the given objects.
+func isRedeployAnnotationChanged(oldObj, newObj client.Object) bool {
+	return oldObj.GetAnnotations()[apiv1.RedeployAnnotation] != newObj.GetAnnotations()[apiv1.RedeployAnnotation]
+}
+
+// redeployAnnotationChanged is a predicate passing only updates that change the redeploy annotation.
+var redeployAnnotationChanged = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return isRedeployAnnotationChanged(e.ObjectOld, e.ObjectNew)
+	},
+}
+
+// getApplicationDeploymentRequests returns reconciliation requests for the deployments of the environments controlled
+// by the given application.
+func (r *DeploymentReconciler) getApplicationDeploymentRequests(ctx context.Context, app *apiv1.Application) []reconcile.Request {
+	envsList := &apiv1.EnvironmentList{}
+	if err := r.List(ctx, envsList, client.InNamespace(app.Namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list environments")
+		return nil
+	}
+
+	var envs []*apiv1.Environment
+	for i := range envsList.Items {
+		env := &envsList.Items[i]
+		if appRef := metav1.GetControllerOf(env); appRef != nil && appRef.Kind == apiv1.ApplicationGVK.Kind && appRef.Name == app.Name {
+			envs = append(envs, env)
+		}
+	}
+	return r.getControlledDeploymentRequests(ctx, envs...)
+}
+
+// getControlledDeploymentRequests returns reconciliation requests for the deployments controlled by the given
+// environments.
+func (r *DeploymentReconciler) getControlledDeploymentRequests(ctx context.Context, envs ...*apiv1.Environment) []reconcile.Request {
+	if len(envs) == 0 {
+		return nil
+	}
+
+	deploymentsList := &apiv1.DeploymentList{}
+	if err := r.List(ctx, deploymentsList, client.InNamespace(envs[0].Namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list deployments")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, d := range deploymentsList.Items {
+		if envRef := metav1.GetControllerOf(&d); envRef != nil && envRef.Kind == apiv1.EnvironmentGVK.Kind {
+			for _, env := range envs {
+				if envRef.Name == env.Name {
+					requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&d)})
+				}
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/lang"
+)
+
+var _ = Describe("Redeploy requests", func() {
+	controllerRef := func(gvk string, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: apiv1.GroupVersion.String(), Kind: gvk, Name: name, UID: "uid", Controller: lang.Ptr(true)}}
+	}
+	redeploy := func(token string) map[string]string {
+		return map[string]string{apiv1.RedeployAnnotation: token}
+	}
+
+	It("should combine the requests of the deployment, environment & application", func() {
+		app := &apiv1.Application{ObjectMeta: metav1.ObjectMeta{Annotations: redeploy("a1")}}
+		env := &apiv1.Environment{}
+		deployment := &apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Annotations: redeploy("d1")}}
+		request := getRedeployRequest(app, env, deployment)
+		Expect(request).To(Equal(apiv1.DeploymentStatusRedeployRequest{Application: "a1", Deployment: "d1"}))
+		Expect(getRedeployRequest(&apiv1.Application{}, &apiv1.Environment{}, &apiv1.Deployment{})).To(BeZero())
+	})
+
+	It("should grant a fresh set of attempts upon a new request", func() {
+		status := &apiv1.DeploymentStatus{FailedAttempts: &apiv1.DeploymentStatusFailedAttempts{Revision: "abc"}}
+		acceptRedeployRequest(status, status.LastRedeployRequest)
+		Expect(status.FailedAttempts).NotTo(BeNil())
+
+		acceptRedeployRequest(status, apiv1.DeploymentStatusRedeployRequest{Environment: "e1"})
+		Expect(status.LastRedeployRequest.Environment).To(Equal("e1"))
+		Expect(status.FailedAttempts).To(BeNil())
+	})
+
+	It("should only pass updates of the redeploy annotation", func() {
+		oldEnv := &apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Annotations: redeploy("e1")}}
+		Expect(redeployAnnotationChanged.Update(event.UpdateEvent{ObjectOld: oldEnv, ObjectNew: oldEnv.DeepCopy()})).To(BeFalse())
+		Expect(redeployAnnotationChanged.Update(event.UpdateEvent{ObjectOld: oldEnv, ObjectNew: &apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Annotations: redeploy("e2")}}})).To(BeTrue())
+		Expect(redeployAnnotationChanged.Create(event.CreateEvent{Object: oldEnv})).To(BeFalse())
+	})
+
+	It("should fan out environment & application requests to their deployments", func(ctx context.Context) {
+		scheme := newTestScheme()
+		app := &apiv1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"}}
+		c := newFakeClient(scheme,
+			&apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "main", OwnerReferences: controllerRef("Application", "app")}},
+			&apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "feature", OwnerReferences: controllerRef("Application", "app")}},
+			&apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other", OwnerReferences: controllerRef("Application", "other")}},
+			&apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "main-1", OwnerReferences: controllerRef("Environment", "main")}},
+			&apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "main-2", OwnerReferences: controllerRef("Environment", "main")}},
+			&apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "feature-1", OwnerReferences: controllerRef("Environment", "feature")}},
+			&apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other-1", OwnerReferences: controllerRef("Environment", "other")}},
+		)
+		r := &DeploymentReconciler{Client: c, Scheme: scheme}
+		request := func(name string) reconcile.Request {
+			return reconcile.Request{NamespacedName: client.ObjectKey{Namespace: "ns", Name: name}}
+		}
+
+		env := &apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "main"}}
+		Expect(r.getControlledDeploymentRequests(ctx, env)).To(ConsistOf(request("main-1"), request("main-2")))
+		Expect(r.getApplicationDeploymentRequests(ctx, app)).To(ConsistOf(request("main-1"), request("main-2"), request("feature-1")))
+	})
+})
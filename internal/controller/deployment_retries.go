@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+const (
+	// defaultMaxAttempts is the default number of failed attempts of each phase before giving up on a revision.
+	defaultMaxAttempts = 3
+
+	// retryInitialBackoff is the delay before the second attempt of a failed phase; it doubles with each failed attempt.
+	retryInitialBackoff = 30 * time.Second
+
+	// retryMaxBackoff caps the delay between attempts of a failed phase.
+	retryMaxBackoff = 10 * time.Minute
+
+	// jobBackoffLimit is the backoff limit of deployment jobs; each job runs its pod once, so that failed attempts
+	// count what actually ran.
+	jobBackoffLimit int32 = 0
+
+	// jobRestartPolicy is the restart policy of deployment job pods; failed pods are retained (until their job's TTL)
+	// so that their termination messages can be reported.
+	jobRestartPolicy = corev1.RestartPolicyNever
+)
+
+// maxAttempts returns the effective number of failed attempts of each phase of deploying a revision, after which
+// deployments of the given application give up on the revision.
+func (r *DeploymentReconciler) maxAttempts(app *apiv1.Application) int {
+	if app.Spec.MaxAttempts > 0 {
+		return app.Spec.MaxAttempts
+	} else if r.DefaultMaxAttempts > 0 {
+		return r.DefaultMaxAttempts
+	}
+	return defaultMaxAttempts
+}
+
+// retryBackoff returns the delay before retrying a phase that failed the given number of times.
+func retryBackoff(failures int) time.Duration {
+	backoff := time.Duration(0)
+	for i := 0; i < failures && backoff < retryMaxBackoff; i++ {
+		backoff = max(backoff*2, retryInitialBackoff)
+	}
+	return min(backoff, retryMaxBackoff)
+}
+
+// getFailedAttempts returns the failed attempts of deploying the last attempted revision, starting afresh if the
+// recorded attempts were made for another revision.
+func getFailedAttempts(status *apiv1.DeploymentStatus) *apiv1.DeploymentStatusFailedAttempts {
+	if attempts := status.FailedAttempts; attempts == nil || attempts.Revision != status.LastAttemptedRevision {
+		status.FailedAttempts = &apiv1.DeploymentStatusFailedAttempts{Revision: status.LastAttemptedRevision}
+	}
+	return status.FailedAttempts
+}
+
+// isFailedAttemptCounted checks whether the failure of the given job was already recorded as a failed attempt.
+func isFailedAttemptCounted(status *apiv1.DeploymentStatus, job *batchv1.Job) bool {
+	attempts := status.FailedAttempts
+	return attempts != nil && attempts.Revision == status.LastAttemptedRevision && attempts.LastFailedJob == job.Name
+}
+
+// countFailedAttempt records the failure of the given phase of the given job as a failed attempt.
+func countFailedAttempt(status *apiv1.DeploymentStatus, phase Phase, job *batchv1.Job, failure string) {
+	attempts := getFailedAttempts(status)
+	if attempts.Phases == nil {
+		attempts.Phases = make(map[string]int)
+	}
+	attempts.Phases[string(phase)]++
+	attempts.LastFailedJob = job.Name
+	attempts.LastFailedPhase = string(phase)
+	attempts.LastFailure = failure
+	attempts.LastFailureTime = &metav1.Time{Time: time.Now()}
+}
+
+// handleRetryRequest resets the failed attempts of the last attempted revision if a retry was requested via the retry
+// annotation since the last request, allowing the failed phase to be retried immediately.
+func handleRetryRequest(rec *k8s.Reconciliation[*apiv1.Deployment]) *k8s.Result {
+	request := rec.Object.Annotations[apiv1.RetryAnnotation]
+	if request == rec.Object.Status.LastRetryRequest {
+		return nil
+	}
+	if attempts := rec.Object.Status.FailedAttempts; attempts != nil {
+		attempts.Phases = nil
+	}
+	rec.Object.Status.LastRetryRequest = request
+	return rec.UpdateStatus()
+}
+
+// waitForRetry backs off retrying the last failed phase of the last attempted revision, reflecting the pending retry
+// in the deployment status; or gives up on the revision once the phase has exhausted its attempts. Returns nil when the
+// phase is due to be retried.
+func (r *DeploymentReconciler) waitForRetry(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application) *k8s.Result {
+	attempts := getFailedAttempts(&rec.Object.Status)
+	phase := Phase(attempts.LastFailedPhase)
+	failures, maxAttempts := attempts.Phases[string(phase)], r.maxAttempts(app)
+	if failures == 0 {
+		return nil
+	}
+
+	if failures >= maxAttempts {
+		rec.Object.Status.SetStaleDueToAttemptsExhausted("Gave up after %d failed attempts of the %s phase: %s", failures, phase, attempts.LastFailure)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.DoNotRequeue()
+	}
+
+	if remaining := time.Until(attempts.LastFailureTime.Add(retryBackoff(failures))); remaining > 0 {
+		setPhaseFailed(&rec.Object.Status, phase, "Attempt %d of %d failed, retrying in %s: %s", failures, maxAttempts, remaining.Round(time.Second), attempts.LastFailure)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.RequeueAfter(remaining)
+	}
+	return nil
+}
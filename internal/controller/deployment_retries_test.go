@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/lang"
+)
+
+var _ = Describe("Retries", func() {
+	const revision = "1111111111111111111111111111111111111111"
+	app := &apiv1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"}}
+
+	newRec := func() (*DeploymentReconciler, *k8s.Reconciliation[*apiv1.Deployment]) {
+		deployment := &apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"}}
+		deployment.Status.LastAttemptedRevision = revision
+		return newDeploymentReconciliation(deployment)
+	}
+	fail := func(rec *k8s.Reconciliation[*apiv1.Deployment], jobName string, failedAt time.Time) {
+		countFailedAttempt(&rec.Object.Status, PhaseBake, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: jobName}}, "kustomize build: exit status 1")
+		rec.Object.Status.FailedAttempts.LastFailureTime = &metav1.Time{Time: failedAt}
+	}
+
+	It("should back off exponentially up to a limit", func() {
+		Expect(retryBackoff(0)).To(Equal(time.Duration(0)))
+		Expect(retryBackoff(1)).To(Equal(30 * time.Second))
+		Expect(retryBackoff(2)).To(Equal(time.Minute))
+		Expect(retryBackoff(4)).To(Equal(4 * time.Minute))
+		Expect(retryBackoff(100)).To(Equal(10 * time.Minute))
+	})
+
+	It("should layer the application's max attempts over the controller default", func() {
+		Expect((&DeploymentReconciler{}).maxAttempts(&apiv1.Application{})).To(Equal(defaultMaxAttempts))
+		Expect((&DeploymentReconciler{DefaultMaxAttempts: 5}).maxAttempts(&apiv1.Application{})).To(Equal(5))
+		Expect((&DeploymentReconciler{DefaultMaxAttempts: 5}).maxAttempts(&apiv1.Application{Spec: apiv1.ApplicationSpec{MaxAttempts: 1}})).To(Equal(1))
+	})
+
+	It("should run each job's pod once, even under job templates", func() {
+		template, err := ParseJobTemplate([]byte("spec: {backoffLimit: 6, template: {spec: {restartPolicy: OnFailure}}}"))
+		Expect(err).NotTo(HaveOccurred())
+		r, rec := newRec()
+		r.JobTemplate = template
+		job, err := r.createNewJobSpec(rec, PhaseBake, app, nil, r.newJobContainer(PhaseBake, BakeJobImage))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.BackoffLimit).To(Equal(lang.Ptr(int32(0))))
+		Expect(job.Spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+	})
+
+	It("should back off retrying failed phases", func() {
+		r, rec := newRec()
+		fail(rec, "job1", time.Now())
+		result := r.waitForRetry(rec, app)
+		Expect(result).NotTo(BeNil())
+		Expect(*result.RequeueAfter).To(BeNumerically("~", 30*time.Second, time.Second))
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.BakingFailed))
+		Expect(rec.Object.Status.GetStaleMessage()).To(HavePrefix("Attempt 1 of 3 failed, retrying in 30s: kustomize build"))
+
+		fail(rec, "job2", time.Now().Add(-time.Minute))
+		Expect(r.waitForRetry(rec, app)).To(BeNil())
+	})
+
+	It("should give up once attempts are exhausted, until a retry is requested", func() {
+		r, rec := newRec()
+		for _, name := range []string{"job1", "job2", "job3"} {
+			fail(rec, name, time.Now().Add(-time.Hour))
+		}
+		Expect(r.waitForRetry(rec, app)).NotTo(BeNil())
+		Expect(rec.Object.Status.IsStale()).To(BeTrue())
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.AttemptsExhausted))
+		Expect(rec.Object.Status.GetStaleMessage()).To(Equal("Gave up after 3 failed attempts of the bake phase: kustomize build: exit status 1"))
+
+		rec.Object.Annotations = map[string]string{apiv1.RetryAnnotation: "1"}
+		Expect(handleRetryRequest(rec)).To(BeNil())
+		Expect(rec.Object.Status.LastRetryRequest).To(Equal("1"))
+		Expect(r.waitForRetry(rec, app)).To(BeNil())
+		Expect(isFailedAttemptCounted(&rec.Object.Status, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job3"}})).To(BeTrue())
+	})
+
+	It("should count attempts per revision", func() {
+		_, rec := newRec()
+		fail(rec, "job1", time.Now())
+		rec.Object.Status.LastAttemptedRevision = "2222222222222222222222222222222222222222"
+		Expect(isFailedAttemptCounted(&rec.Object.Status, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job1"}})).To(BeFalse())
+		fail(rec, "job2", time.Now())
+		Expect(rec.Object.Status.FailedAttempts.Revision).To(Equal(rec.Object.Status.LastAttemptedRevision))
+		Expect(rec.Object.Status.FailedAttempts.Phases).To(Equal(map[string]int{"bake": 1}))
+	})
+})
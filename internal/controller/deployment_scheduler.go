@@ -0,0 +1,268 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+const (
+	// queuePollInterval is the interval at which queued deployments check whether a job slot became available.
+	queuePollInterval = 10 * time.Second
+
+	// queueEntryTTL is the time after which deployments that stopped polling (e.g. deleted) are removed from the queue.
+	queueEntryTTL = 6 * queuePollInterval
+
+	// reservationTTL is the time a job slot remains reserved for an admitted deployment, until its job is observed.
+	reservationTTL = 30 * time.Second
+)
+
+// scheduledDeployment is a deployment either running (or about to run) a job, or waiting for a job slot.
+type scheduledDeployment struct {
+	deployment    client.ObjectKey
+	application   client.ObjectKey
+	repository    client.ObjectKey
+	maxPerApp     int
+	maxPerRepo    int
+	defaultBranch bool
+	since         time.Time
+	lastSeen      time.Time
+}
+
+// jobSlots counts the job slots occupied globally, per application & per repository.
+type jobSlots struct {
+	total   int
+	perApp  map[client.ObjectKey]int
+	perRepo map[client.ObjectKey]int
+}
+
+func (s *jobSlots) add(d *scheduledDeployment) {
+	s.total++
+	s.perApp[d.application]++
+	s.perRepo[d.repository]++
+}
+
+// jobScheduler bounds the number of concurrently running deployment jobs globally, per application & per repository,
+// queueing deployments waiting for a job slot. Deployments of environments tracking their repository's default branch
+// take precedence, followed by the order in which deployments were queued.
+type jobScheduler struct {
+	mu           sync.Mutex
+	queue        map[client.ObjectKey]*scheduledDeployment
+	reservations map[client.ObjectKey]*scheduledDeployment
+}
+
+// maxConcurrentJobsPerApplication returns the effective limit of concurrently running jobs of the given application's
+// deployments, or zero if unlimited.
+func (r *DeploymentReconciler) maxConcurrentJobsPerApplication(app *apiv1.Application) int {
+	if app.Spec.MaxConcurrentJobs > 0 {
+		return app.Spec.MaxConcurrentJobs
+	}
+	return r.DefaultMaxConcurrentJobsPerApplication
+}
+
+// maxConcurrentJobsPerRepository returns the effective limit of concurrently running jobs of deployments of the given
+// repository, or zero if unlimited.
+func (r *DeploymentReconciler) maxConcurrentJobsPerRepository(repo *apiv1.Repository) int {
+	if repo.Spec.MaxConcurrentJobs > 0 {
+		return repo.Spec.MaxConcurrentJobs
+	}
+	return r.DefaultMaxConcurrentJobsPerRepository
+}
+
+// fits checks whether the given deployment can run a job, given the occupied job slots.
+func (r *DeploymentReconciler) fits(d *scheduledDeployment, slots *jobSlots) bool {
+	if r.MaxConcurrentJobs > 0 && slots.total >= r.MaxConcurrentJobs {
+		return false
+	} else if d.maxPerApp > 0 && slots.perApp[d.application] >= d.maxPerApp {
+		return false
+	} else if d.maxPerRepo > 0 && slots.perRepo[d.repository] >= d.maxPerRepo {
+		return false
+	}
+	return true
+}
+
+// scheduleJob admits the deployment to run a job if a job slot is available for it, and no deployment ahead of it in
+// the queue is waiting for that slot; otherwise, the deployment is queued and its queue position is reflected in its
+// status. Returns nil once admitted.
+func (r *DeploymentReconciler) scheduleJob(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application, env *apiv1.Environment, repo *apiv1.Repository) *k8s.Result {
+	now := time.Now()
+	candidate := &scheduledDeployment{
+		deployment:    client.ObjectKeyFromObject(rec.Object),
+		application:   client.ObjectKeyFromObject(app),
+		repository:    client.ObjectKeyFromObject(repo),
+		maxPerApp:     r.maxConcurrentJobsPerApplication(app),
+		maxPerRepo:    r.maxConcurrentJobsPerRepository(repo),
+		defaultBranch: env.Spec.PreferredBranch == repo.Status.DefaultBranch,
+		since:         now,
+		lastSeen:      now,
+	}
+	if r.MaxConcurrentJobs <= 0 && candidate.maxPerApp <= 0 && candidate.maxPerRepo <= 0 {
+		rec.Object.Status.QueuePosition = 0
+		return nil
+	}
+
+	r.scheduler.mu.Lock()
+	defer r.scheduler.mu.Unlock()
+	if r.scheduler.queue == nil {
+		r.scheduler.queue = make(map[client.ObjectKey]*scheduledDeployment)
+		r.scheduler.reservations = make(map[client.ObjectKey]*scheduledDeployment)
+	}
+
+	// A deployment admitted earlier (e.g. earlier in this reconciliation) retains its slot until its job is observed
+	if reservation, ok := r.scheduler.reservations[candidate.deployment]; ok && now.Sub(reservation.since) < reservationTTL {
+		rec.Object.Status.QueuePosition = 0
+		return nil
+	}
+
+	// Count the occupied job slots
+	slots, err := r.getOccupiedJobSlots(rec.Ctx, candidate.deployment, now)
+	if err != nil {
+		rec.Object.Status.SetMaybeStaleDueToInternalError("Failed counting running jobs: %+v", err)
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+		return k8s.Requeue()
+	}
+
+	// Refresh the deployment's queue entry, retaining its original queueing time, and prune abandoned entries
+	if queued, ok := r.scheduler.queue[candidate.deployment]; ok {
+		candidate.since = queued.since
+	}
+	r.scheduler.queue[candidate.deployment] = candidate
+	for key, queued := range r.scheduler.queue {
+		if now.Sub(queued.lastSeen) > queueEntryTTL {
+			delete(r.scheduler.queue, key)
+		}
+	}
+
+	// Rank queued deployments, and let deployments ahead of this one take the slots available to them
+	queue := make([]*scheduledDeployment, 0, len(r.scheduler.queue))
+	for _, queued := range r.scheduler.queue {
+		queue = append(queue, queued)
+	}
+	slices.SortFunc(queue, compareQueuedDeployments)
+	position := 0
+	for i, queued := range queue {
+		if queued == candidate {
+			position = i + 1
+			break
+		} else if r.fits(queued, slots) {
+			slots.add(queued)
+		}
+	}
+
+	// Admit the deployment if a slot is available for it, reserving the slot until its job is observed
+	if r.fits(candidate, slots) {
+		delete(r.scheduler.queue, candidate.deployment)
+		candidate.since = now
+		r.scheduler.reservations[candidate.deployment] = candidate
+		rec.Object.Status.QueuePosition = 0
+		return nil
+	}
+
+	rec.Object.Status.QueuePosition = position
+	rec.Object.Status.SetMaybeStaleDueToQueued("Waiting for a job slot (position %d in queue)", position)
+	if result := rec.UpdateStatus(); result != nil {
+		return result
+	}
+	return k8s.RequeueAfter(queuePollInterval)
+}
+
+// compareQueuedDeployments orders queued deployments by precedence: deployments of default-branch environments first,
+// then by queueing time.
+func compareQueuedDeployments(a, b *scheduledDeployment) int {
+	if a.defaultBranch != b.defaultBranch {
+		if a.defaultBranch {
+			return -1
+		}
+		return 1
+	}
+	return cmp.Or(a.since.Compare(b.since), cmp.Compare(a.deployment.String(), b.deployment.String()))
+}
+
+// getOccupiedJobSlots counts the job slots occupied by running deployment jobs, as well as by slots reserved for
+// admitted deployments whose jobs were not observed yet, excluding the given deployment. Reservations of observed jobs
+// and expired reservations are released.
+func (r *DeploymentReconciler) getOccupiedJobSlots(ctx context.Context, exclude client.ObjectKey, now time.Time) (*jobSlots, error) {
+	jobsList := &batchv1.JobList{}
+	if err := r.Client.List(ctx, jobsList, client.HasLabels{PhaseLabel}); err != nil {
+		return nil, fmt.Errorf("failed listing jobs: %w", err)
+	}
+
+	slots := &jobSlots{perApp: make(map[client.ObjectKey]int), perRepo: make(map[client.ObjectKey]int)}
+	running := make(map[client.ObjectKey]bool)
+	for _, job := range jobsList.Items {
+		deploymentRef := metav1.GetControllerOf(&job)
+		if deploymentRef == nil || deploymentRef.Kind != apiv1.DeploymentGVK.Kind || isJobFinished(&job) {
+			continue
+		}
+		key := client.ObjectKey{Namespace: job.Namespace, Name: deploymentRef.Name}
+		running[key] = true
+		if key == exclude {
+			continue
+		} else if d, err := r.getScheduledDeployment(ctx, key); err != nil {
+			return nil, err
+		} else {
+			slots.add(d)
+		}
+	}
+
+	for key, reservation := range r.scheduler.reservations {
+		if running[key] || now.Sub(reservation.since) >= reservationTTL {
+			delete(r.scheduler.reservations, key)
+		} else if key != exclude {
+			slots.add(reservation)
+		}
+	}
+	return slots, nil
+}
+
+// getScheduledDeployment resolves the application & repository of the given deployment running a job. Deployments that
+// no longer exist are only counted globally.
+func (r *DeploymentReconciler) getScheduledDeployment(ctx context.Context, key client.ObjectKey) (*scheduledDeployment, error) {
+	scheduled := &scheduledDeployment{deployment: key}
+
+	d := &apiv1.Deployment{}
+	if err := r.Client.Get(ctx, key, d); err != nil {
+		if apierrors.IsNotFound(err) {
+			return scheduled, nil
+		}
+		return nil, fmt.Errorf("failed getting deployment '%s': %w", key, err)
+	}
+	scheduled.repository = d.Spec.Repository.GetObjectKey()
+
+	envRef := metav1.GetControllerOf(d)
+	if envRef == nil {
+		return scheduled, nil
+	}
+	env := &apiv1.Environment{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: d.Namespace, Name: envRef.Name}, env); err != nil {
+		if apierrors.IsNotFound(err) {
+			return scheduled, nil
+		}
+		return nil, fmt.Errorf("failed getting environment '%s/%s': %w", d.Namespace, envRef.Name, err)
+	}
+	if appRef := metav1.GetControllerOf(env); appRef != nil && appRef.Kind == apiv1.ApplicationGVK.Kind {
+		scheduled.application = client.ObjectKey{Namespace: env.Namespace, Name: appRef.Name}
+	}
+	return scheduled, nil
+}
+
+// isJobFinished checks whether the given job completed or failed.
+func isJobFinished(job *batchv1.Job) bool {
+	return slices.ContainsFunc(job.Status.Conditions, func(c batchv1.JobCondition) bool {
+		return (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue
+	})
+}
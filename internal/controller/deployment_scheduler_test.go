@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/lang"
+)
+
+var _ = Describe("Job scheduler", func() {
+	var c client.Client
+	var app, otherApp *apiv1.Application
+	var main, feature *apiv1.Environment
+	var repo, otherRepo *apiv1.Repository
+
+	controllerRef := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: apiv1.GroupVersion.String(), Kind: kind, Name: name, UID: "uid", Controller: lang.Ptr(true)}}
+	}
+	newDeployment := func(name string, env *apiv1.Environment, repo *apiv1.Repository) *apiv1.Deployment {
+		return &apiv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, OwnerReferences: controllerRef("Environment", env.Name)},
+			Spec:       apiv1.DeploymentSpec{Repository: apiv1.DeploymentRepositoryReference{Namespace: repo.Namespace, Name: repo.Name}},
+		}
+	}
+	runningJob := func(name string, d *apiv1.Deployment) *batchv1.Job {
+		return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Labels: map[string]string{PhaseLabel: string(PhaseClone)}, OwnerReferences: controllerRef("Deployment", d.Name)}}
+	}
+	newRec := func(d *apiv1.Deployment) *k8s.Reconciliation[*apiv1.Deployment] {
+		Expect(c.Create(context.Background(), d)).To(Succeed())
+		return &k8s.Reconciliation[*apiv1.Deployment]{Ctx: context.Background(), Client: c, Object: d}
+	}
+
+	BeforeEach(func() {
+		scheme := newTestScheme()
+		app = &apiv1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"}}
+		otherApp = &apiv1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other"}}
+		main = &apiv1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "main", OwnerReferences: controllerRef("Application", "app")},
+			Spec:       apiv1.EnvironmentSpec{PreferredBranch: "main"},
+		}
+		feature = &apiv1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "feature", OwnerReferences: controllerRef("Application", "app")},
+			Spec:       apiv1.EnvironmentSpec{PreferredBranch: "feature"},
+		}
+		repo = &apiv1.Repository{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "repo"}, Status: apiv1.RepositoryStatus{DefaultBranch: "main"}}
+		otherRepo = &apiv1.Repository{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other-repo"}, Status: apiv1.RepositoryStatus{DefaultBranch: "main"}}
+		c = newFakeClient(scheme, main, feature)
+	})
+
+	It("should not queue deployments without limits", func() {
+		r := &DeploymentReconciler{Client: c}
+		rec := newRec(newDeployment("d1", main, repo))
+		Expect(r.scheduleJob(rec, app, main, repo)).To(BeNil())
+		Expect(r.scheduler.queue).To(BeEmpty())
+	})
+
+	It("should bound running jobs globally", func() {
+		r := &DeploymentReconciler{Client: c, MaxConcurrentJobs: 1}
+		running := newDeployment("running", main, otherRepo)
+		Expect(c.Create(context.Background(), running)).To(Succeed())
+		Expect(c.Create(context.Background(), runningJob("job1", running))).To(Succeed())
+
+		rec := newRec(newDeployment("d1", main, repo))
+		result := r.scheduleJob(rec, app, main, repo)
+		Expect(result).NotTo(BeNil())
+		Expect(*result.RequeueAfter).To(Equal(queuePollInterval))
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.Queued))
+		Expect(rec.Object.Status.QueuePosition).To(Equal(1))
+
+		// Once the running job is gone, the queued deployment is admitted & holds the slot until its job appears
+		Expect(c.Delete(context.Background(), runningJob("job1", running))).To(Succeed())
+		Expect(r.scheduleJob(rec, app, main, repo)).To(BeNil())
+		Expect(rec.Object.Status.QueuePosition).To(BeZero())
+
+		other := newRec(newDeployment("d2", main, otherRepo))
+		Expect(r.scheduleJob(other, app, main, otherRepo)).NotTo(BeNil())
+	})
+
+	It("should ignore jobs that are not deployment phase jobs", func() {
+		r := &DeploymentReconciler{Client: c, MaxConcurrentJobs: 1}
+		running := newDeployment("running", main, otherRepo)
+		Expect(c.Create(context.Background(), running)).To(Succeed())
+		job := runningJob("job1", running)
+		job.Labels = nil
+		Expect(c.Create(context.Background(), job)).To(Succeed())
+
+		Expect(r.scheduleJob(newRec(newDeployment("d1", main, repo)), app, main, repo)).To(BeNil())
+	})
+
+	It("should bound running jobs per application & repository", func() {
+		r := &DeploymentReconciler{Client: c, DefaultMaxConcurrentJobsPerRepository: 1}
+		otherEnv := &apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other-env", OwnerReferences: controllerRef("Application", "other")}}
+		Expect(c.Create(context.Background(), otherEnv)).To(Succeed())
+		running := newDeployment("running", main, repo)
+		Expect(c.Create(context.Background(), running)).To(Succeed())
+		Expect(c.Create(context.Background(), runningJob("job1", running))).To(Succeed())
+
+		// Repository limits apply across applications
+		Expect(r.scheduleJob(newRec(newDeployment("d1", otherEnv, repo)), otherApp, otherEnv, repo)).NotTo(BeNil())
+		Expect(r.scheduleJob(newRec(newDeployment("d2", otherEnv, otherRepo)), otherApp, otherEnv, otherRepo)).To(BeNil())
+
+		// Application limits override the controller default
+		limitedApp := app.DeepCopy()
+		limitedApp.Spec.MaxConcurrentJobs = 1
+		Expect(r.scheduleJob(newRec(newDeployment("d3", feature, otherRepo)), limitedApp, feature, otherRepo)).NotTo(BeNil())
+	})
+
+	It("should not count finished jobs", func() {
+		job := &batchv1.Job{}
+		Expect(isJobFinished(job)).To(BeFalse())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionUnknown}}
+		Expect(isJobFinished(job)).To(BeFalse())
+		job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue})
+		Expect(isJobFinished(job)).To(BeTrue())
+	})
+
+	It("should give precedence to default-branch environments", func() {
+		r := &DeploymentReconciler{Client: c, MaxConcurrentJobs: 1}
+		running := newDeployment("running", main, otherRepo)
+		Expect(c.Create(context.Background(), running)).To(Succeed())
+		Expect(c.Create(context.Background(), runningJob("job1", running))).To(Succeed())
+
+		featureRec := newRec(newDeployment("d1", feature, repo))
+		Expect(r.scheduleJob(featureRec, app, feature, repo)).NotTo(BeNil())
+		Expect(featureRec.Object.Status.QueuePosition).To(Equal(1))
+
+		mainRec := newRec(newDeployment("d2", main, repo))
+		Expect(r.scheduleJob(mainRec, app, main, repo)).NotTo(BeNil())
+		Expect(mainRec.Object.Status.QueuePosition).To(Equal(1))
+		Expect(r.scheduleJob(featureRec, app, feature, repo)).NotTo(BeNil())
+		Expect(featureRec.Object.Status.QueuePosition).To(Equal(2))
+
+		// Once a slot is available, it goes to the default-branch deployment
+		Expect(c.Delete(context.Background(), runningJob("job1", running))).To(Succeed())
+		Expect(r.scheduleJob(featureRec, app, feature, repo)).NotTo(BeNil())
+		Expect(r.scheduleJob(mainRec, app, main, repo)).To(BeNil())
+	})
+
+	It("should drop abandoned queue entries", func() {
+		r := &DeploymentReconciler{Client: c, MaxConcurrentJobs: 1}
+		r.scheduler.queue = map[client.ObjectKey]*scheduledDeployment{
+			{Namespace: "ns", Name: "deleted"}: {defaultBranch: true, lastSeen: time.Now().Add(-2 * queueEntryTTL)},
+		}
+		r.scheduler.reservations = map[client.ObjectKey]*scheduledDeployment{}
+		Expect(r.scheduleJob(newRec(newDeployment("d1", feature, repo)), app, feature, repo)).To(BeNil())
+	})
+})
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"slices"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+// singlePodPollInterval is how often an active single-pod job is checked for the phase it is running.
+const singlePodPollInterval = 10 * time.Second
+
+// executionMode returns the effective execution mode of deployments of the given application. Ephemeral work volumes
+// are not shared between jobs, and therefore always run in a single pod.
+func (r *DeploymentReconciler) executionMode(app *apiv1.Application) string {
+	if r.workVolumeSettings(app).Mode == apiv1.EmptyDirWorkVolumeMode {
+		return apiv1.SinglePodExecutionMode
+	} else if app.Spec.ExecutionMode != "" {
+		return app.Spec.ExecutionMode
+	} else if r.DefaultExecutionMode != "" {
+		return r.DefaultExecutionMode
+	}
+	return apiv1.JobsExecutionMode
+}
+
+// isSinglePodJob checks whether the given job runs all phases, cloning & baking in its init containers.
+func isSinglePodJob(job *batchv1.Job) bool {
+	return slices.ContainsFunc(job.Spec.Template.Spec.InitContainers, func(c corev1.Container) bool {
+		return c.Name == string(PhaseClone)
+	})
+}
+
+// getSinglePodPhase returns the phase the given single-pod job is running (or failed in), derived from the container
+// statuses of its most recent pod: the first phase whose container has not yet succeeded.
+func (r *DeploymentReconciler) getSinglePodPhase(rec *k8s.Reconciliation[*apiv1.Deployment], job *batchv1.Job) (Phase, error) {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobComplete && c.Status == corev1.ConditionTrue {
+			return PhaseApply, nil
+		}
+	}
+
+	pods, err := r.getJobPods(rec, job)
+	if err != nil {
+		return "", err
+	} else if len(pods) == 0 {
+		return PhaseClone, nil
+	}
+	pod := slices.MaxFunc(pods, func(a, b corev1.Pod) int {
+		return a.CreationTimestamp.Time.Compare(b.CreationTimestamp.Time)
+	})
+
+	for _, phase := range []Phase{PhaseClone, PhaseBake} {
+		i := slices.IndexFunc(pod.Status.InitContainerStatuses, func(cs corev1.ContainerStatus) bool {
+			return cs.Name == string(phase)
+		})
+		if i < 0 {
+			return phase, nil
+		} else if t := pod.Status.InitContainerStatuses[i].State.Terminated; t == nil || t.ExitCode != 0 {
+			return phase, nil
+		}
+	}
+	return PhaseApply, nil
+}
+
+// reportSinglePodPhase reflects the phase the given active single-pod job is running in the deployment status, and
+// polls the job to track its progress, since pod changes do not trigger reconciliation.
+func (r *DeploymentReconciler) reportSinglePodPhase(rec *k8s.Reconciliation[*apiv1.Deployment], job *batchv1.Job, phase Phase) *k8s.Result {
+	switch phase {
+	case PhaseClone:
+		rec.Object.Status.SetMaybeStaleDueToCloning("Job '%s' is cloning", job.Name)
+	case PhaseBake:
+		rec.Object.Status.SetMaybeStaleDueToBaking("Job '%s' is baking", job.Name)
+	case PhaseApply:
+		rec.Object.Status.SetMaybeStaleDueToApplying("Job '%s' is applying", job.Name)
+	default:
+		panic("unsupported phase: " + phase)
+	}
+	if result := rec.UpdateStatus(); result != nil {
+		return result
+	}
+	return k8s.RequeueAfter(singlePodPollInterval)
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+var _ = Describe("Single-pod execution", func() {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "deploy"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"batch.kubernetes.io/job-name": "deploy"}},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "clone"}, {Name: "bake"}},
+				Containers:     []corev1.Container{{Name: "apply"}},
+			}},
+		},
+		Status: batchv1.JobStatus{Active: 1},
+	}
+
+	newPod := func(name string, created time.Time, initStatuses ...corev1.ContainerStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "ns",
+				Name:              name,
+				Labels:            map[string]string{"batch.kubernetes.io/job-name": "deploy"},
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Status: corev1.PodStatus{InitContainerStatuses: initStatuses},
+		}
+	}
+	succeeded := func(name string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}}
+	}
+	running := func(name string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+	}
+	failed := func(name string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}}}
+	}
+
+	newRec := func(objects ...runtime.Object) (*DeploymentReconciler, *k8s.Reconciliation[*apiv1.Deployment]) {
+		deployment := &apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"}}
+		return newDeploymentReconciliation(deployment, append(objects, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "github"},
+			Data:       map[string][]byte{"token": []byte("t0k3n")},
+		})...)
+	}
+
+	It("should layer the application's execution mode over the controller default", func() {
+		r := &DeploymentReconciler{}
+		Expect(r.executionMode(&apiv1.Application{})).To(Equal(apiv1.JobsExecutionMode))
+
+		r.DefaultExecutionMode = apiv1.SinglePodExecutionMode
+		Expect(r.executionMode(&apiv1.Application{})).To(Equal(apiv1.SinglePodExecutionMode))
+
+		app := &apiv1.Application{Spec: apiv1.ApplicationSpec{ExecutionMode: apiv1.JobsExecutionMode}}
+		Expect(r.executionMode(app)).To(Equal(apiv1.JobsExecutionMode))
+
+		app.Spec.WorkVolume = &apiv1.ApplicationSpecWorkVolume{Mode: apiv1.EmptyDirWorkVolumeMode}
+		Expect(r.executionMode(app)).To(Equal(apiv1.SinglePodExecutionMode))
+	})
+
+	It("should run all phases in a single job on the persistent work volume", func() {
+		r, rec := newRec()
+		rec.Object.Status.PersistentVolumeClaimName = "my-deployment-work"
+		app := &apiv1.Application{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+			Spec: apiv1.ApplicationSpec{
+				ExecutionMode: apiv1.SinglePodExecutionMode,
+				Repositories:  []apiv1.ApplicationSpecRepository{{Name: "repo", Path: "deploy"}},
+			},
+		}
+		env := &apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "env"}}
+		repo := &apiv1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "repo"},
+			Spec: apiv1.RepositorySpec{
+				GitHub: &apiv1.GitHubRepositorySpec{
+					Owner: "owner",
+					Name:  "repo",
+					PersonalAccessToken: apiv1.GitHubRepositoryPersonalAccessToken{
+						Secret: apiv1.SecretReferenceWithOptionalNamespace{Name: "github"},
+						Key:    "token",
+					},
+				},
+			},
+		}
+		Expect(r.createNewCloneJob(rec, app, env, repo, &app.Spec.Repositories[0])).ToNot(BeNil())
+
+		jobs := &batchv1.JobList{}
+		Expect(r.List(rec.Ctx, jobs, client.InNamespace("ns"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(isSinglePodJob(&jobs.Items[0])).To(BeTrue())
+		Expect(jobs.Items[0].Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("my-deployment-work"))
+	})
+
+	DescribeTable("should derive the phase from the latest pod's container statuses",
+		func(expected Phase, pods ...runtime.Object) {
+			r, rec := newRec(pods...)
+			Expect(r.getSinglePodPhase(rec, job)).To(Equal(expected))
+		},
+		Entry("no pods", PhaseClone),
+		Entry("initializing", PhaseClone, newPod("p1", time.Now())),
+		Entry("cloning", PhaseClone, newPod("p1", time.Now(), running("clone"))),
+		Entry("clone failed", PhaseClone, newPod("p1", time.Now(), failed("clone"))),
+		Entry("baking", PhaseBake, newPod("p1", time.Now(), succeeded("clone"), running("bake"))),
+		Entry("applying", PhaseApply, newPod("p1", time.Now(), succeeded("clone"), succeeded("bake"))),
+		Entry("retrying in a new pod", PhaseClone,
+			newPod("p1", time.Now().Add(-time.Minute), succeeded("clone"), failed("bake")),
+			newPod("p2", time.Now(), running("clone")),
+		),
+	)
+
+	It("should consider completed jobs as applied", func() {
+		r, rec := newRec()
+		completed := job.DeepCopy()
+		completed.Status = batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}}
+		Expect(r.getSinglePodPhase(rec, completed)).To(Equal(PhaseApply))
+	})
+
+	It("should reflect the running phase in the status", func() {
+		r, rec := newRec()
+		Expect(r.reportSinglePodPhase(rec, job, PhaseBake)).NotTo(BeNil())
+		Expect(rec.Object.Status.GetStaleReason()).To(Equal(apiv1.Baking))
+		Expect(rec.Object.Status.GetStaleMessage()).To(Equal("Job 'deploy' is baking"))
+	})
+
+	It("should not consider multi-job jobs as single-pod jobs", func() {
+		Expect(isSinglePodJob(&batchv1.Job{})).To(BeFalse())
+	})
+})
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/tracing"
+)
+
+var DeploymentTracer = otel.Tracer("devbot.kfirs.com/controller/Deployment")
+
+// startRolloutTrace records a span for the rollout of the deployment's attempted revision, continuing the trace of the
+// webhook delivery that introduced it (if any), and stores its trace context in the deployment status so that the
+// rollout's jobs continue it as well. The status is persisted by the caller.
+func startRolloutTrace(rec *k8s.Reconciliation[*apiv1.Deployment], repo *apiv1.Repository) {
+	ctx := tracing.ContextWithTraceParent(rec.Ctx, repo.Annotations[apiv1.TraceParentAnnotation])
+	ctx, span := DeploymentTracer.Start(ctx, "Deploy revision", trace.WithAttributes(
+		attribute.String("deployment", rec.Object.Namespace+"/"+rec.Object.Name),
+		attribute.String("branch", rec.Object.Status.Branch),
+		attribute.String("tag", rec.Object.Status.Tag),
+		attribute.String("revision", rec.Object.Status.LastAttemptedRevision),
+	))
+	defer span.End()
+	rec.Object.Status.TraceParent = tracing.TraceParent(ctx)
+}
+
+// traceParentEnvVars returns the environment variables propagating the deployment's rollout trace into job containers.
+func traceParentEnvVars(rec *k8s.Reconciliation[*apiv1.Deployment]) []corev1.EnvVar {
+	if rec.Object.Status.TraceParent == "" {
+		return nil
+	}
+	return []corev1.EnvVar{{Name: tracing.TraceParentEnvVar, Value: rec.Object.Status.TraceParent}}
+}
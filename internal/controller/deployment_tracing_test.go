@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/tracing"
+)
+
+var _ = Describe("Rollout tracing", func() {
+	const traceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+	It("should continue the repository's trace in job containers", func() {
+		rec := &k8s.Reconciliation[*apiv1.Deployment]{
+			Ctx:    context.Background(),
+			Object: &apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"}},
+		}
+		repo := &apiv1.Repository{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{apiv1.TraceParentAnnotation: traceParent},
+		}}
+		startRolloutTrace(rec, repo)
+		Expect(rec.Object.Status.TraceParent).To(HavePrefix("00-0af7651916cd43dd8448eb211c80319c-"))
+
+		r := &DeploymentReconciler{}
+		initContainers := []corev1.Container{r.newJobContainer(PhaseClone, CloneJobImage)}
+		job, err := r.createNewJobSpec(rec, PhaseApply, &apiv1.Application{}, initContainers, r.newJobContainer(PhaseApply, ApplyJobImage))
+		Expect(err).NotTo(HaveOccurred())
+		expected := corev1.EnvVar{Name: tracing.TraceParentEnvVar, Value: rec.Object.Status.TraceParent}
+		Expect(job.Spec.Template.Spec.InitContainers[0].Env).To(ContainElement(expected))
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(expected))
+	})
+
+	It("should not inject a trace context without one", func() {
+		rec := &k8s.Reconciliation[*apiv1.Deployment]{Ctx: context.Background(), Object: &apiv1.Deployment{}}
+		startRolloutTrace(rec, &apiv1.Repository{})
+		Expect(rec.Object.Status.TraceParent).To(BeEmpty())
+
+		r := &DeploymentReconciler{}
+		job, err := r.createNewJobSpec(rec, PhaseClone, &apiv1.Application{}, nil, r.newJobContainer(PhaseClone, CloneJobImage))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", tracing.TraceParentEnvVar)))
+	})
+})
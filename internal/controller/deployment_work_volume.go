@@ -0,0 +1,51 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+var defaultWorkVolumeSize = resource.MustParse("5Gi")
+
+// workVolumeSettings returns the effective work volume settings for deployments of the given application, layering the
+// application's settings over the controller defaults, and those over the built-in defaults.
+func (r *DeploymentReconciler) workVolumeSettings(app *apiv1.Application) apiv1.ApplicationSpecWorkVolume {
+	settings := apiv1.ApplicationSpecWorkVolume{
+		Mode:       apiv1.PersistentVolumeClaimWorkVolumeMode,
+		Size:       &defaultWorkVolumeSize,
+		AccessMode: corev1.ReadWriteOnce,
+	}
+	for _, layer := range []*apiv1.ApplicationSpecWorkVolume{&r.DefaultWorkVolume, app.Spec.WorkVolume} {
+		if layer == nil {
+			continue
+		}
+		if layer.Mode != "" {
+			settings.Mode = layer.Mode
+		}
+		if layer.StorageClassName != "" {
+			settings.StorageClassName = layer.StorageClassName
+		}
+		if layer.Size != nil {
+			settings.Size = layer.Size
+		}
+		if layer.AccessMode != "" {
+			settings.AccessMode = layer.AccessMode
+		}
+	}
+	return settings
+}
+
+// workVolumeSource returns the source of the work volume shared by the deployment's jobs: its persistent volume claim,
+// or a fresh, size-limited empty directory when the work volume is ephemeral.
+func (r *DeploymentReconciler) workVolumeSource(rec *k8s.Reconciliation[*apiv1.Deployment], app *apiv1.Application) corev1.VolumeSource {
+	settings := r.workVolumeSettings(app)
+	if settings.Mode == apiv1.EmptyDirWorkVolumeMode {
+		return corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: settings.Size}}
+	}
+	return corev1.VolumeSource{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: rec.Object.Status.PersistentVolumeClaimName},
+	}
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/lang"
+)
+
+var _ = Describe("Work volume", func() {
+	newRec := func() (*DeploymentReconciler, *k8s.Reconciliation[*apiv1.Deployment]) {
+		deployment := &apiv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-deployment"}}
+		return newDeploymentReconciliation(deployment, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "github"},
+			Data:       map[string][]byte{"token": []byte("t0k3n")},
+		})
+	}
+
+	It("should layer application settings over controller defaults", func() {
+		size := resource.MustParse("20Gi")
+		r := &DeploymentReconciler{DefaultWorkVolume: apiv1.ApplicationSpecWorkVolume{StorageClassName: "fast", Size: &size}}
+		app := &apiv1.Application{Spec: apiv1.ApplicationSpec{WorkVolume: &apiv1.ApplicationSpecWorkVolume{AccessMode: corev1.ReadWriteMany}}}
+		settings := r.workVolumeSettings(app)
+		Expect(settings.Mode).To(Equal(apiv1.PersistentVolumeClaimWorkVolumeMode))
+		Expect(settings.StorageClassName).To(Equal("fast"))
+		Expect(settings.Size.String()).To(Equal("20Gi"))
+		Expect(settings.AccessMode).To(Equal(corev1.ReadWriteMany))
+
+		settings = (&DeploymentReconciler{}).workVolumeSettings(&apiv1.Application{})
+		Expect(settings.Size.String()).To(Equal("5Gi"))
+		Expect(settings.AccessMode).To(Equal(corev1.ReadWriteOnce))
+	})
+
+	It("should create the claim using the work volume settings", func() {
+		r, rec := newRec()
+		app := &apiv1.Application{Spec: apiv1.ApplicationSpec{WorkVolume: &apiv1.ApplicationSpecWorkVolume{StorageClassName: "fast"}}}
+		Expect(r.ensurePersistentVolumeClaim(rec, r.workVolumeSettings(app))).To(BeNil())
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(r.Get(rec.Ctx, client.ObjectKey{Namespace: "ns", Name: "my-deployment-work"}, pvc)).To(Succeed())
+		Expect(pvc.Spec.StorageClassName).To(Equal(lang.Ptr("fast")))
+		Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+		Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("5Gi"))
+		Expect(pvc.Spec.Resources.Limits).To(BeEmpty())
+	})
+
+	It("should use the cluster default storage class when none is configured", func() {
+		r, rec := newRec()
+		Expect(r.ensurePersistentVolumeClaim(rec, r.workVolumeSettings(&apiv1.Application{}))).To(BeNil())
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(r.Get(rec.Ctx, client.ObjectKey{Namespace: "ns", Name: "my-deployment-work"}, pvc)).To(Succeed())
+		Expect(pvc.Spec.StorageClassName).To(BeNil())
+	})
+
+	It("should run all phases in a single job with an empty directory volume", func() {
+		r, rec := newRec()
+		size := resource.MustParse("1Gi")
+		app := &apiv1.Application{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+			Spec: apiv1.ApplicationSpec{
+				WorkVolume:   &apiv1.ApplicationSpecWorkVolume{Mode: apiv1.EmptyDirWorkVolumeMode, Size: &size},
+				Repositories: []apiv1.ApplicationSpecRepository{{Name: "repo", Path: "deploy"}},
+			},
+		}
+		env := &apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "env"}}
+		repo := &apiv1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "repo"},
+			Spec: apiv1.RepositorySpec{
+				GitHub: &apiv1.GitHubRepositorySpec{
+					Owner: "owner",
+					Name:  "repo",
+					PersonalAccessToken: apiv1.GitHubRepositoryPersonalAccessToken{
+						Secret: apiv1.SecretReferenceWithOptionalNamespace{Name: "github"},
+						Key:    "token",
+					},
+				},
+			},
+		}
+		Expect(r.createNewCloneJob(rec, app, env, repo, &app.Spec.Repositories[0])).ToNot(BeNil())
+
+		jobs := &batchv1.JobList{}
+		Expect(r.List(rec.Ctx, jobs, client.InNamespace("ns"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		job := jobs.Items[0]
+		Expect(job.Labels).To(HaveKeyWithValue(PhaseLabel, string(PhaseApply)))
+		Expect(job.Spec.Template.Spec.InitContainers).To(HaveLen(2))
+		Expect(job.Spec.Template.Spec.InitContainers[0].Name).To(Equal(string(PhaseClone)))
+		Expect(job.Spec.Template.Spec.InitContainers[1].Name).To(Equal(string(PhaseBake)))
+		Expect(job.Spec.Template.Spec.Containers[0].Name).To(Equal(string(PhaseApply)))
+		Expect(job.Spec.Template.Spec.Volumes[0].EmptyDir).ToNot(BeNil())
+		Expect(job.Spec.Template.Spec.Volumes[0].EmptyDir.SizeLimit.String()).To(Equal("1Gi"))
+		Expect(job.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim).To(BeNil())
+
+		pvcs := &corev1.PersistentVolumeClaimList{}
+		Expect(r.List(rec.Ctx, pvcs, client.InNamespace("ns"))).To(Succeed())
+		Expect(pvcs.Items).To(BeEmpty())
+	})
+})
@@ -28,7 +28,8 @@ var (
 type (
 	EnvironmentReconciler struct {
 		client.Client
-		Scheme *runtime.Scheme
+		Scheme              *runtime.Scheme
+		GitHubClientFactory GitHubClientFactory
 	}
 )
 
@@ -37,7 +38,7 @@ func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 }
 
 func (r *EnvironmentReconciler) executeReconciliation(ctx context.Context, req ctrl.Request) *k8s.Result {
-	rec, result := k8s.NewReconciliation(ctx, r.Client, req, &apiv1.Environment{}, EnvironmentFinalizer, nil)
+	rec, result := k8s.NewReconciliation(ctx, r.Client, req, &apiv1.Environment{}, EnvironmentFinalizer, r.finalizeObject)
 	if result != nil {
 		return result
 	}
@@ -139,6 +140,11 @@ func (r *EnvironmentReconciler) executeReconciliation(ctx context.Context, req c
 		return result
 	}
 
+	// Post or update pull request comments describing this environment, if enabled
+	if result := r.syncPullRequestComments(rec, app, deployments.Items); result != nil {
+		return result
+	}
+
 	// Mark as stale if any deployment is stale; current otherwise
 	for _, deployment := range deployments.Items {
 		if deployment.Status.IsStale() {
@@ -224,6 +230,6 @@ func (r *EnvironmentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				}
 			}
 			return requests
-		})).
+		}), builder.WithPredicates(repositoryUpdateRelevantToDependents)).
 		Complete(r)
 }
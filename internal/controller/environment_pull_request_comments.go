@@ -0,0 +1,272 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v56/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+// pullRequestCommentMarker returns the hidden marker embedded in the pull request comment of the given environment,
+// used to find the comment again if its ID is lost.
+func pullRequestCommentMarker(env *apiv1.Environment) string {
+	return fmt.Sprintf("<!-- devbot:environment:%s/%s -->", env.Namespace, env.Name)
+}
+
+// renderPullRequestComment renders the body of the pull request comment describing the given environment.
+func renderPullRequestComment(app *apiv1.Application, env *apiv1.Environment, deployments []apiv1.Deployment, repos map[client.ObjectKey]*apiv1.Repository) string {
+	sb := &strings.Builder{}
+	sb.WriteString(pullRequestCommentMarker(env) + "\n")
+	sb.WriteString(fmt.Sprintf("### Environment `%s` of application `%s`\n\n", env.Spec.GetDisplayName(), app.Name))
+	sb.WriteString("| Repository | Branch | Deployed revision | Status | URL |\n")
+	sb.WriteString("|------------|--------|----------|--------|-----|\n")
+	deployments = slices.Clone(deployments)
+	slices.SortFunc(deployments, func(a, b apiv1.Deployment) int {
+		return strings.Compare(a.Spec.Repository.GetObjectKey().String(), b.Spec.Repository.GetObjectKey().String())
+	})
+	for _, d := range deployments {
+		repoKey := d.Spec.Repository.GetObjectKey()
+		repoName := repoKey.String()
+		if repo, ok := repos[repoKey]; ok && repo.Status.ResolvedName != "" {
+			repoName = repo.Status.ResolvedName
+		}
+
+		revision, status, url := "-", "Pending", "-"
+		if d.Status.LastAppliedRevision != "" {
+			revision = "`" + d.Status.LastAppliedRevision + "`"
+		}
+		if d.Status.IsInvalid() {
+			status = "Invalid: " + d.Status.GetInvalidReason()
+		} else if d.Status.IsStale() {
+			status = d.Status.GetStaleReason()
+		} else if d.Status.LastAppliedRevision != "" {
+			status = "Deployed"
+			if u := expandEnvironmentURL(app.Spec.EnvironmentURL, app, env, &d); u != "" {
+				url = u
+			}
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", repoName, d.Status.Branch, revision, status, url))
+	}
+	return sb.String()
+}
+
+// upsertGitHubPullRequestComment updates the given comment (if an ID is given) or the comment carrying the given marker
+// in the given pull request; if no such comment exists, a new one is created. The comment ID is returned.
+func upsertGitHubPullRequestComment(ctx context.Context, ghc *github.Client, owner, name string, number int, commentID int64, marker, body string) (int64, error) {
+	comment := &github.IssueComment{Body: github.String(body)}
+
+	// Update the comment we know about, unless it was deleted
+	if commentID != 0 {
+		if _, _, err := ghc.Issues.EditComment(ctx, owner, name, commentID, comment); err == nil {
+			return commentID, nil
+		} else if ghErr := (&github.ErrorResponse{}); !errors.As(err, &ghErr) || ghErr.Response.StatusCode != http.StatusNotFound {
+			return 0, fmt.Errorf("failed updating comment '%d' of pull request '%s/%s#%d': %w", commentID, owner, name, number, err)
+		}
+	}
+
+	// Search for a comment with our marker
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := ghc.Issues.ListComments(ctx, owner, name, number, opts)
+		if err != nil {
+			return 0, fmt.Errorf("failed listing comments of pull request '%s/%s#%d': %w", owner, name, number, err)
+		}
+		for _, c := range comments {
+			if strings.HasPrefix(c.GetBody(), marker) {
+				if _, _, err := ghc.Issues.EditComment(ctx, owner, name, c.GetID(), comment); err != nil {
+					return 0, fmt.Errorf("failed updating comment '%d' of pull request '%s/%s#%d': %w", c.GetID(), owner, name, number, err)
+				}
+				return c.GetID(), nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	// Not found - create it
+	created, _, err := ghc.Issues.CreateComment(ctx, owner, name, number, comment)
+	if err != nil {
+		return 0, fmt.Errorf("failed creating comment on pull request '%s/%s#%d': %w", owner, name, number, err)
+	}
+	return created.GetID(), nil
+}
+
+// deleteGitHubPullRequestComment deletes the given pull request comment, unless it was already deleted.
+func deleteGitHubPullRequestComment(ctx context.Context, ghc *github.Client, owner, name string, commentID int64) error {
+	if _, err := ghc.Issues.DeleteComment(ctx, owner, name, commentID); err != nil {
+		if ghErr := (&github.ErrorResponse{}); !errors.As(err, &ghErr) || ghErr.Response.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("failed deleting comment '%d' of '%s/%s': %w", commentID, owner, name, err)
+		}
+	}
+	return nil
+}
+
+// getPullRequestCommentRepositories fetches the participating repositories of the given application, keyed by their
+// object keys.
+func (r *EnvironmentReconciler) getPullRequestCommentRepositories(ctx context.Context, app *apiv1.Application) map[client.ObjectKey]*apiv1.Repository {
+	repos := make(map[client.ObjectKey]*apiv1.Repository)
+	for _, repoRef := range app.Spec.Repositories {
+		repoKey := repoRef.GetObjectKey(app.Namespace)
+		repo := &apiv1.Repository{}
+		if err := r.Get(ctx, repoKey, repo); err != nil {
+			log.FromContext(ctx).Error(err, "Failed fetching repository for pull request comments", "repository", repoKey)
+			continue
+		}
+		repos[repoKey] = repo
+	}
+	return repos
+}
+
+// deletePullRequestComments deletes the given pull request comments, posted on pull requests of the given repositories;
+// the comments that could not be deleted are returned, so that their deletion is retried.
+func (r *EnvironmentReconciler) deletePullRequestComments(ctx context.Context, repos map[client.ObjectKey]*apiv1.Repository, comments []apiv1.EnvironmentPullRequestComment) []apiv1.EnvironmentPullRequestComment {
+	var remaining []apiv1.EnvironmentPullRequestComment
+	for _, c := range comments {
+		var repo *apiv1.Repository
+		for _, candidate := range repos {
+			if candidate.Spec.GitHub != nil && candidate.Status.ResolvedName == c.Repository {
+				repo = candidate
+				break
+			}
+		}
+		if repo == nil {
+			// Repository no longer participates in the application; nothing we can do
+			continue
+		}
+
+		ghc, err := newGitHubClientForRepository(ctx, r.Client, r.GitHubClientFactory, repo)
+		if err == nil {
+			err = deleteGitHubPullRequestComment(ctx, ghc, repo.Spec.GitHub.Owner, repo.Spec.GitHub.Name, c.CommentID)
+		}
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Failed deleting pull request comment", "repository", c.Repository, "pullRequest", c.PullRequest)
+			remaining = append(remaining, c)
+		}
+	}
+	return remaining
+}
+
+// finalizeObject deletes the pull request comments describing the environment, on a best-effort basis.
+func (r *EnvironmentReconciler) finalizeObject(rec *k8s.Reconciliation[*apiv1.Environment]) error {
+	if len(rec.Object.Status.PullRequestComments) == 0 {
+		return nil
+	}
+	app := &apiv1.Application{}
+	if appRef := metav1.GetControllerOf(rec.Object); appRef == nil {
+		return nil
+	} else if err := r.Get(rec.Ctx, client.ObjectKey{Namespace: rec.Object.Namespace, Name: appRef.Name}, app); err != nil {
+		log.FromContext(rec.Ctx).Error(err, "Failed fetching application for deleting pull request comments")
+		return nil
+	}
+	r.deletePullRequestComments(rec.Ctx, r.getPullRequestCommentRepositories(rec.Ctx, app), rec.Object.Status.PullRequestComments)
+	return nil
+}
+
+// syncPullRequestComments posts or updates the sticky comment describing this environment on every open pull request
+// whose head branch is the environment's preferred branch, or deletes previously posted comments once comments are
+// disabled. Failures to talk to GitHub are logged but otherwise ignored.
+func (r *EnvironmentReconciler) syncPullRequestComments(rec *k8s.Reconciliation[*apiv1.Environment], app *apiv1.Application, deployments []apiv1.Deployment) *k8s.Result {
+	if !app.Spec.PullRequestComments {
+		// Delete comments posted while enabled, retaining those whose deletion failed so they can be retried
+		if comments := rec.Object.Status.PullRequestComments; len(comments) > 0 {
+			repos := r.getPullRequestCommentRepositories(rec.Ctx, app)
+			rec.Object.Status.PullRequestComments = r.deletePullRequestComments(rec.Ctx, repos, comments)
+			if len(rec.Object.Status.PullRequestComments) < len(comments) {
+				return rec.UpdateStatus()
+			}
+		}
+		return nil
+	}
+	logger := log.FromContext(rec.Ctx)
+
+	// Fetch participating repositories
+	repos := r.getPullRequestCommentRepositories(rec.Ctx, app)
+
+	body := renderPullRequestComment(app, rec.Object, deployments, repos)
+	hash := sha256.Sum256([]byte(body))
+	bodyHash := hex.EncodeToString(hash[:])
+	marker := pullRequestCommentMarker(rec.Object)
+
+	var comments []apiv1.EnvironmentPullRequestComment
+	for _, repoRef := range app.Spec.Repositories {
+		repo, ok := repos[repoRef.GetObjectKey(app.Namespace)]
+		if !ok || repo.Spec.GitHub == nil {
+			continue
+		}
+
+		var ghc *github.Client
+		for _, pr := range repo.Status.PullRequests {
+			if pr.HeadBranch != rec.Object.Spec.PreferredBranch {
+				continue
+			}
+
+			var existing *apiv1.EnvironmentPullRequestComment
+			for i, c := range rec.Object.Status.PullRequestComments {
+				if c.Repository == repo.Status.ResolvedName && c.PullRequest == pr.Number {
+					existing = &rec.Object.Status.PullRequestComments[i]
+					break
+				}
+			}
+			if existing != nil && existing.BodyHash == bodyHash {
+				comments = append(comments, *existing)
+				continue
+			}
+
+			var commentID int64
+			if existing != nil {
+				commentID = existing.CommentID
+			}
+
+			var err error
+			if ghc == nil {
+				ghc, err = newGitHubClientForRepository(rec.Ctx, r.Client, r.GitHubClientFactory, repo)
+			}
+			var id int64
+			if err == nil {
+				owner, name := repo.Spec.GitHub.Owner, repo.Spec.GitHub.Name
+				id, err = upsertGitHubPullRequestComment(rec.Ctx, ghc, owner, name, pr.Number, commentID, marker, body)
+			}
+			if err != nil {
+				logger.Error(err, "Failed posting pull request comment", "repository", repo.Status.ResolvedName, "pullRequest", pr.Number)
+				if existing != nil {
+					comments = append(comments, *existing)
+				}
+			} else {
+				comments = append(comments, apiv1.EnvironmentPullRequestComment{
+					Repository:  repo.Status.ResolvedName,
+					PullRequest: pr.Number,
+					CommentID:   id,
+					BodyHash:    bodyHash,
+				})
+			}
+		}
+	}
+
+	// Delete comments of pull requests that are no longer open (or no longer target this environment's branch)
+	var obsolete []apiv1.EnvironmentPullRequestComment
+	for _, c := range rec.Object.Status.PullRequestComments {
+		if !slices.ContainsFunc(comments, func(o apiv1.EnvironmentPullRequestComment) bool {
+			return o.Repository == c.Repository && o.PullRequest == c.PullRequest
+		}) {
+			obsolete = append(obsolete, c)
+		}
+	}
+	comments = append(comments, r.deletePullRequestComments(rec.Ctx, repos, obsolete)...)
+
+	rec.Object.Status.PullRequestComments = comments
+	return rec.UpdateStatus()
+}
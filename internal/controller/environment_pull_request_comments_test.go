@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/lang"
+)
+
+// fakeGitHubComments is a fake GitHub issue comments API of the "owner/name" repository.
+type fakeGitHubComments struct {
+	mu       sync.Mutex
+	nextID   int64
+	comments map[int64]string // comment ID -> body
+	issues   map[int64]int    // comment ID -> pull request number
+	broken   map[int64]bool   // comment IDs failing to be deleted
+	requests []string
+}
+
+func (f *fakeGitHubComments) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v3/repos/owner/name/issues/")
+	if id, ok := strings.CutPrefix(path, "comments/"); ok {
+		commentID, err := strconv.ParseInt(id, 10, 64)
+		Expect(err).NotTo(HaveOccurred())
+		if _, ok := f.comments[commentID]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			body := map[string]string{}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			f.comments[commentID] = body["body"]
+			Expect(json.NewEncoder(w).Encode(map[string]any{"id": commentID, "body": body["body"]})).To(Succeed())
+		case http.MethodDelete:
+			if f.broken[commentID] {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"message":"Internal Server Error"}`))
+				return
+			}
+			delete(f.comments, commentID)
+			delete(f.issues, commentID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			Fail("unexpected method: " + r.Method)
+		}
+		return
+	}
+
+	number, err := strconv.Atoi(strings.TrimSuffix(path, "/comments"))
+	Expect(err).NotTo(HaveOccurred())
+	switch r.Method {
+	case http.MethodGet:
+		var comments []map[string]any
+		for id, body := range f.comments {
+			if f.issues[id] == number {
+				comments = append(comments, map[string]any{"id": id, "body": body})
+			}
+		}
+		Expect(json.NewEncoder(w).Encode(comments)).To(Succeed())
+	case http.MethodPost:
+		body := map[string]string{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		f.nextID++
+		f.comments[f.nextID], f.issues[f.nextID] = body["body"], number
+		w.WriteHeader(http.StatusCreated)
+		Expect(json.NewEncoder(w).Encode(map[string]any{"id": f.nextID, "body": body["body"]})).To(Succeed())
+	default:
+		Fail("unexpected method: " + r.Method)
+	}
+}
+
+var _ = Describe("Pull request comments", func() {
+	var gh *fakeGitHubComments
+	var r *EnvironmentReconciler
+	var repo *apiv1.Repository
+
+	app := &apiv1.Application{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-app"},
+		Spec: apiv1.ApplicationSpec{
+			Repositories:        []apiv1.ApplicationSpecRepository{{Name: "my-repo", Namespace: "ns"}},
+			PullRequestComments: true,
+			EnvironmentURL:      "https://${ENVIRONMENT}.example.com",
+		},
+	}
+	deployments := []apiv1.Deployment{{
+		Spec: apiv1.DeploymentSpec{Repository: apiv1.DeploymentRepositoryReference{Namespace: "ns", Name: "my-repo"}},
+		Status: apiv1.DeploymentStatus{
+			Branch:                "feature",
+			LastAttemptedRevision: "bbbb",
+			LastAppliedRevision:   "aaaa",
+		},
+	}}
+	newRec := func(comments ...apiv1.EnvironmentPullRequestComment) *k8s.Reconciliation[*apiv1.Environment] {
+		env := &apiv1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "feature"},
+			Spec:       apiv1.EnvironmentSpec{PreferredBranch: "feature"},
+			Status:     apiv1.EnvironmentStatus{PullRequestComments: comments},
+		}
+		Expect(r.Client.Create(context.Background(), env)).To(Succeed())
+		return &k8s.Reconciliation[*apiv1.Environment]{Ctx: context.Background(), Client: r.Client, Object: env}
+	}
+
+	BeforeEach(func() {
+		gh = &fakeGitHubComments{comments: map[int64]string{}, issues: map[int64]int{}, broken: map[int64]bool{}, nextID: 100}
+		server := httptest.NewServer(gh)
+		DeferCleanup(server.Close)
+
+		repo = &apiv1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "my-repo"},
+			Spec: apiv1.RepositorySpec{GitHub: &apiv1.GitHubRepositorySpec{
+				Owner: "owner",
+				Name:  "name",
+				PersonalAccessToken: apiv1.GitHubRepositoryPersonalAccessToken{
+					Secret: apiv1.SecretReferenceWithOptionalNamespace{Name: "pat"},
+					Key:    "token",
+				},
+			}},
+			Status: apiv1.RepositoryStatus{
+				ResolvedName: "owner/name",
+				PullRequests: []apiv1.RepositoryPullRequest{{Number: 7, HeadBranch: "feature"}, {Number: 8, HeadBranch: "other"}},
+			},
+		}
+		scheme := newTestScheme()
+		r = &EnvironmentReconciler{
+			Client: newFakeClient(scheme, repo, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pat"},
+				Data:       map[string][]byte{"token": []byte("s3cr3t")},
+			}),
+			Scheme:              scheme,
+			GitHubClientFactory: NewGitHubClientFactoryForURL(server.URL + "/api/v3"),
+		}
+	})
+
+	It("should render the deployed revision, status & URL of each repository", func() {
+		env := &apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "feature"}, Spec: apiv1.EnvironmentSpec{PreferredBranch: "feature"}}
+		body := renderPullRequestComment(app, env, deployments, map[client.ObjectKey]*apiv1.Repository{{Namespace: "ns", Name: "my-repo"}: repo})
+		Expect(body).To(HavePrefix("<!-- devbot:environment:ns/feature -->\n"))
+		Expect(body).To(ContainSubstring("| owner/name | feature | `aaaa` | Deployed | https://feature.example.com |"))
+		Expect(body).NotTo(ContainSubstring("bbbb"))
+	})
+
+	It("should create a comment on the environment's pull request once", func() {
+		rec := newRec()
+		Expect(r.syncPullRequestComments(rec, app, deployments)).To(BeNil())
+		Expect(gh.comments).To(HaveLen(1))
+		Expect(gh.issues).To(Equal(map[int64]int{101: 7}))
+		Expect(rec.Object.Status.PullRequestComments).To(ConsistOf(HaveField("CommentID", int64(101))))
+
+		// Unchanged bodies are not posted again
+		gh.requests = nil
+		Expect(r.syncPullRequestComments(rec, app, deployments)).To(BeNil())
+		Expect(gh.requests).To(BeEmpty())
+	})
+
+	It("should update the known comment", func() {
+		gh.comments[50], gh.issues[50] = "old", 7
+		rec := newRec(apiv1.EnvironmentPullRequestComment{Repository: "owner/name", PullRequest: 7, CommentID: 50, BodyHash: "old"})
+		Expect(r.syncPullRequestComments(rec, app, deployments)).To(BeNil())
+		Expect(gh.requests).To(Equal([]string{"PATCH /api/v3/repos/owner/name/issues/comments/50"}))
+		Expect(gh.comments[50]).To(ContainSubstring("`aaaa`"))
+	})
+
+	It("should find its comment by marker if its ID is lost", func() {
+		gh.comments[40], gh.issues[40] = "Looks good!", 7
+		gh.comments[50], gh.issues[50] = pullRequestCommentMarker(&apiv1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "feature"}})+"\nold", 7
+		rec := newRec()
+		Expect(r.syncPullRequestComments(rec, app, deployments)).To(BeNil())
+		Expect(gh.comments).To(HaveLen(2))
+		Expect(gh.comments[40]).To(Equal("Looks good!"))
+		Expect(gh.comments[50]).To(ContainSubstring("`aaaa`"))
+		Expect(rec.Object.Status.PullRequestComments).To(ConsistOf(HaveField("CommentID", int64(50))))
+	})
+
+	It("should delete the comment once the pull request is closed", func() {
+		gh.comments[50], gh.issues[50] = "old", 9
+		rec := newRec(apiv1.EnvironmentPullRequestComment{Repository: "owner/name", PullRequest: 9, CommentID: 50, BodyHash: "old"})
+		Expect(r.syncPullRequestComments(rec, app, deployments)).To(BeNil())
+		Expect(gh.comments).NotTo(HaveKey(int64(50)))
+		Expect(rec.Object.Status.PullRequestComments).To(ConsistOf(HaveField("PullRequest", 7)))
+	})
+
+	It("should delete its comments when the environment is deleted", func() {
+		gh.comments[50], gh.issues[50] = "old", 7
+		rec := newRec(apiv1.EnvironmentPullRequestComment{Repository: "owner/name", PullRequest: 7, CommentID: 50})
+		rec.Object.OwnerReferences = []metav1.OwnerReference{{APIVersion: apiv1.GroupVersion.String(), Kind: "Application", Name: "my-app", UID: "uid", Controller: lang.Ptr(true)}}
+		Expect(r.Client.Create(context.Background(), app.DeepCopy())).To(Succeed())
+		Expect(r.finalizeObject(rec)).To(Succeed())
+		Expect(gh.comments).To(BeEmpty())
+	})
+
+	It("should not comment unless enabled", func() {
+		rec := newRec()
+		disabled := app.DeepCopy()
+		disabled.Spec.PullRequestComments = false
+		Expect(r.syncPullRequestComments(rec, disabled, deployments)).To(BeNil())
+		Expect(gh.requests).To(BeEmpty())
+	})
+
+	It("should delete its comments once disabled, retaining those failing to be deleted", func() {
+		gh.comments[50], gh.issues[50] = "old", 7
+		gh.comments[60], gh.issues[60], gh.broken[60] = "old", 9, true
+		rec := newRec(
+			apiv1.EnvironmentPullRequestComment{Repository: "owner/name", PullRequest: 7, CommentID: 50},
+			apiv1.EnvironmentPullRequestComment{Repository: "owner/name", PullRequest: 9, CommentID: 60},
+		)
+		disabled := app.DeepCopy()
+		disabled.Spec.PullRequestComments = false
+		Expect(r.syncPullRequestComments(rec, disabled, deployments)).To(BeNil())
+		Expect(gh.comments).To(HaveLen(1))
+		Expect(gh.comments).To(HaveKey(int64(60)))
+		Expect(rec.Object.Status.PullRequestComments).To(ConsistOf(HaveField("CommentID", int64(60))))
+
+		delete(gh.broken, 60)
+		Expect(r.syncPullRequestComments(rec, disabled, deployments)).To(BeNil())
+		Expect(gh.comments).To(BeEmpty())
+		Expect(rec.Object.Status.PullRequestComments).To(BeEmpty())
+	})
+})
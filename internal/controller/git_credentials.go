@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+const (
+	// GitCredentialsTokenKey is the key of the token used to clone over HTTPS in Git credentials secrets.
+	GitCredentialsTokenKey = "token"
+
+	// GitCredentialsSSHPrivateKeyKey is the key of the SSH private key used to clone over SSH in Git credentials secrets.
+	GitCredentialsSSHPrivateKeyKey = corev1.SSHAuthPrivateKey
+
+	// GitCredentialsKnownHostsKey is the key of the known hosts used to verify SSH hosts in Git credentials secrets.
+	GitCredentialsKnownHostsKey = "known_hosts"
+
+	gitCredentialsMountPath = "/git-credentials"
+)
+
+// gitURL returns the URL to clone the given repository from, or an empty string if the repository type is unsupported.
+func gitURL(repo *apiv1.Repository) string {
+	if repo.Spec.GitHub != nil {
+		if repo.Spec.GitHub.DeployKey != nil {
+			return fmt.Sprintf("ssh://git@github.com/%s/%s.git", repo.Spec.GitHub.Owner, repo.Spec.GitHub.Name)
+		}
+		return fmt.Sprintf("https://github.com/%s/%s", repo.Spec.GitHub.Owner, repo.Spec.GitHub.Name)
+	}
+	return ""
+}
+
+// getGitCredentials collects the credentials used to clone the given repository: its deploy key & known hosts if it
+// has a deploy key, or its personal access token otherwise.
+func getGitCredentials(ctx context.Context, c client.Client, repo *apiv1.Repository) (map[string][]byte, error) {
+	if repo.Spec.GitHub == nil {
+		return nil, fmt.Errorf("repository '%s' is not a GitHub repository", client.ObjectKeyFromObject(repo))
+	}
+
+	deployKey := repo.Spec.GitHub.DeployKey
+	if deployKey == nil {
+		token, err := getGitHubPersonalAccessToken(ctx, c, repo)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{GitCredentialsTokenKey: []byte(token)}, nil
+	}
+
+	secret := &corev1.Secret{}
+	secretObjKey := deployKey.Secret.GetObjectKey(repo.Namespace)
+	if err := c.Get(ctx, secretObjKey, secret); err != nil {
+		return nil, fmt.Errorf("failed reading secret '%s': %w", secretObjKey, err)
+	}
+	privateKey, ok := secret.Data[deployKey.Key]
+	if !ok || len(privateKey) == 0 {
+		return nil, fmt.Errorf("deploy key not found in key '%s' in secret '%s'", deployKey.Key, secretObjKey)
+	}
+	knownHosts, ok := secret.Data[deployKey.KnownHostsKey]
+	if !ok || len(knownHosts) == 0 {
+		return nil, fmt.Errorf("known hosts not found in key '%s' in secret '%s'", deployKey.KnownHostsKey, secretObjKey)
+	}
+	return map[string][]byte{GitCredentialsSSHPrivateKeyKey: privateKey, GitCredentialsKnownHostsKey: knownHosts}, nil
+}
+
+// ensureGitCredentialsSecret creates (or updates) the secret with the given name, owned by the given owner & residing in
+// its namespace, holding the credentials used to clone the given repository. Credentials are copied since the
+// repository's secrets may reside in another namespace, and can therefore not be mounted directly into job pods.
+func ensureGitCredentialsSecret(ctx context.Context, c client.Client, owner client.Object, ownerGVK schema.GroupVersionKind, name string, repo *apiv1.Repository) error {
+	data, err := getGitCredentials(ctx, c, repo)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: owner.GetNamespace(), Name: name}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		secret.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, ownerGVK)}
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = data
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed updating secret '%s/%s': %w", secret.Namespace, secret.Name, err)
+	}
+	return nil
+}
+
+// addGitCredentialsVolume projects the given Git credentials secret read-only into the named container of the given
+// job, and points it at it.
+func addGitCredentialsVolume(job *batchv1.Job, secretName string, containerName string) {
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: "git-credentials",
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: secretName}}},
+				},
+			},
+		},
+	})
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			if containers[i].Name == containerName {
+				c := &containers[i]
+				c.Env = append(c.Env, corev1.EnvVar{Name: "CREDENTIALS_DIR", Value: gitCredentialsMountPath})
+				c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: "git-credentials", MountPath: gitCredentialsMountPath, ReadOnly: true})
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v56/github"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+// GitHubClientFactory creates GitHub clients authenticated with the given token. Reconcilers use it whenever they need
+// to talk to GitHub, which allows pointing them at a GitHub Enterprise installation or at a fake GitHub server.
+type GitHubClientFactory func(token string) (*github.Client, error)
+
+// NewGitHubClient creates a GitHub client for the public GitHub API, authenticated with the given token.
+func NewGitHubClient(token string) (*github.Client, error) {
+	return github.NewClient(nil).WithAuthToken(token), nil
+}
+
+// NewGitHubClientFactoryForURL returns a factory creating GitHub clients that target the given API base URL.
+func NewGitHubClientFactoryForURL(baseURL string) GitHubClientFactory {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL = baseURL + "/"
+	}
+	return func(token string) (*github.Client, error) {
+		return github.NewClient(nil).WithAuthToken(token).WithEnterpriseURLs(baseURL, baseURL)
+	}
+}
+
+func (f GitHubClientFactory) newClient(token string) (*github.Client, error) {
+	if f == nil {
+		return NewGitHubClient(token)
+	}
+	return f(token)
+}
+
+// gitHubTokenError describes why the GitHub personal access token of a repository could not be obtained. Its reason is
+// the matching reason of the repository's Unauthenticated condition, and its message is suitable for that condition.
+type gitHubTokenError struct {
+	Reason  string
+	Message string
+}
+
+func (e *gitHubTokenError) Error() string {
+	return e.Message
+}
+
+// getGitHubPersonalAccessToken fetches the GitHub personal access token configured for the given repository. Failures
+// are reported as a *gitHubTokenError.
+func getGitHubPersonalAccessToken(ctx context.Context, c client.Client, repo *apiv1.Repository) (string, error) {
+	if repo.Spec.GitHub == nil {
+		return "", &gitHubTokenError{apiv1.InternalError, fmt.Sprintf("Repository '%s' is not a GitHub repository", client.ObjectKeyFromObject(repo))}
+	}
+	patCfg := repo.Spec.GitHub.PersonalAccessToken
+
+	secret := &corev1.Secret{}
+	secretObjKey := patCfg.Secret.GetObjectKey(repo.Namespace)
+	if err := c.Get(ctx, secretObjKey, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", &gitHubTokenError{apiv1.AuthSecretNotFound, fmt.Sprintf("Secret '%s' not found", secretObjKey)}
+		} else if apierrors.IsForbidden(err) {
+			return "", &gitHubTokenError{apiv1.AuthSecretForbidden, fmt.Sprintf("Secret '%s' is not accessible: %+v", secretObjKey, err)}
+		}
+		return "", &gitHubTokenError{apiv1.InternalError, fmt.Sprintf("Failed reading secret '%s': %+v", secretObjKey, err)}
+	}
+
+	pat, ok := secret.Data[patCfg.Key]
+	if !ok {
+		return "", &gitHubTokenError{apiv1.AuthSecretKeyNotFound, fmt.Sprintf("Key '%s' not found in secret '%s'", patCfg.Key, secretObjKey)}
+	} else if string(pat) == "" {
+		return "", &gitHubTokenError{apiv1.AuthTokenEmpty, fmt.Sprintf("Token in key '%s' in secret '%s' is empty", patCfg.Key, secretObjKey)}
+	}
+	return string(pat), nil
+}
+
+// newGitHubClientForRepository creates a GitHub client authenticated with the given repository's credentials.
+func newGitHubClientForRepository(ctx context.Context, c client.Client, factory GitHubClientFactory, repo *apiv1.Repository) (*github.Client, error) {
+	token, err := getGitHubPersonalAccessToken(ctx, c, repo)
+	if err != nil {
+		return nil, err
+	}
+	return factory.newClient(token)
+}
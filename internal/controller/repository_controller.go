@@ -4,12 +4,15 @@ import (
 	"context"
 	"net/http"
 	"slices"
+	"strings"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/google/go-github/v56/github"
+	batchv1 "k8s.io/api/batch/v1"
 	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime"
@@ -25,12 +28,14 @@ import (
 
 var (
 	RepositoryFinalizer = "repository.finalizers." + v1.GroupVersion.Group
+	GitHubWebhookEvents = []string{"create", "delete", "pull_request", "push", "repository"}
 )
 
 type RepositoryReconciler struct {
 	client.Client
-	Scheme           *runtime.Scheme
-	GitHubWebhookURL string
+	Scheme              *runtime.Scheme
+	GitHubWebhookURL    string
+	GitHubClientFactory GitHubClientFactory
 }
 
 func (r *RepositoryReconciler) Reconcile(ctx context.Context, req controllerruntime.Request) (controllerruntime.Result, error) {
@@ -92,10 +97,10 @@ func (r *RepositoryReconciler) parseRefreshInterval(rec *k8s.Reconciliation[*v1.
 func (r *RepositoryReconciler) reconcileGitHubRepository(rec *k8s.Reconciliation[*v1.Repository], refreshInterval time.Duration) *k8s.Result {
 	status := &rec.Object.Status
 
-	// Update resolved-name if necessary
-	resolvedName := rec.Object.Spec.GitHub.Owner + "/" + rec.Object.Spec.GitHub.Name
-	if resolvedName != status.ResolvedName {
-		status.ResolvedName = rec.Object.Spec.GitHub.Owner + "/" + rec.Object.Spec.GitHub.Name
+	// Initialize resolved-name if necessary (it is updated to the actual name once the repository is fetched)
+	specName := rec.Object.Spec.GitHub.Owner + "/" + rec.Object.Spec.GitHub.Name
+	if status.ResolvedName == "" {
+		status.ResolvedName = specName
 		if result := rec.UpdateStatus(); result != nil {
 			return result
 		}
@@ -117,6 +122,26 @@ func (r *RepositoryReconciler) reconcileGitHubRepository(rec *k8s.Reconciliation
 		ghRepo = ghr
 	}
 
+	// Sync resolved name; GitHub redirects requests for renamed & transferred repositories to their new name, which
+	// means the spec still refers to the old name and should be updated
+	if fullName := ghRepo.GetFullName(); fullName != "" {
+		changed := false
+		if status.ResolvedName != fullName {
+			status.ResolvedName = fullName
+			changed = true
+		}
+		if !strings.EqualFold(fullName, specName) {
+			changed = status.SetInvalidDueToRepositoryMoved("Repository moved to '%s', please update the repository spec", fullName) || changed
+		} else {
+			changed = status.SetValidIfInvalidDueToAnyOf(v1.RepositoryMoved) || changed
+		}
+		if changed {
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+		}
+	}
+
 	// Sync default branch
 	if ghRepo.GetDefaultBranch() != rec.Object.Status.DefaultBranch {
 		rec.Object.Status.DefaultBranch = ghRepo.GetDefaultBranch()
@@ -125,6 +150,13 @@ func (r *RepositoryReconciler) reconcileGitHubRepository(rec *k8s.Reconciliation
 		}
 	}
 
+	// If the pending refresh request is limited to specific branches (e.g. by a CI system), refresh only those
+	if refreshRequest := rec.Object.Annotations[v1.RefreshAnnotation]; refreshRequest != status.LastRefreshRequest {
+		if branches := rec.Object.Annotations[v1.RefreshBranchesAnnotation]; branches != "" {
+			return r.refreshBranches(rec, refreshInterval, ghc, refreshRequest, strings.Split(branches, ","))
+		}
+	}
+
 	// Create missing ref objects based on current branches in the repository
 	branchesToRevisionsMap := make(map[string]string)
 	branchesListOptions := &github.BranchListOptions{}
@@ -148,6 +180,50 @@ func (r *RepositoryReconciler) reconcileGitHubRepository(rec *k8s.Reconciliation
 		branchesListOptions.Page = response.NextPage
 	}
 	status.Revisions = branchesToRevisionsMap
+
+	// Sync tags
+	tagsToRevisionsMap := make(map[string]string)
+	tagsListOptions := &github.ListOptions{PerPage: 100}
+	for {
+		tagsList, response, err := ghc.Repositories.ListTags(rec.Ctx, rec.Object.Spec.GitHub.Owner, rec.Object.Spec.GitHub.Name, tagsListOptions)
+		if err != nil {
+			rec.Object.Status.SetMaybeStaleDueToInternalError("Failed listing tags: %+v", err)
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			return k8s.RequeueAfter(refreshInterval)
+		}
+		for _, tag := range tagsList {
+			tagsToRevisionsMap[tag.GetName()] = tag.GetCommit().GetSHA()
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		tagsListOptions.Page = response.NextPage
+	}
+	status.Tags = tagsToRevisionsMap
+
+	// Sync open pull requests
+	var pullRequests []v1.RepositoryPullRequest
+	pullRequestsListOptions := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		pullRequestsList, response, err := ghc.PullRequests.List(rec.Ctx, rec.Object.Spec.GitHub.Owner, rec.Object.Spec.GitHub.Name, pullRequestsListOptions)
+		if err != nil {
+			rec.Object.Status.SetMaybeStaleDueToInternalError("Failed listing pull requests: %+v", err)
+			if result := rec.UpdateStatus(); result != nil {
+				return result
+			}
+			return k8s.RequeueAfter(refreshInterval)
+		}
+		pullRequests = append(pullRequests, toRepositoryPullRequests(ghRepo.GetID(), pullRequestsList)...)
+		if response.NextPage == 0 {
+			break
+		}
+		pullRequestsListOptions.Page = response.NextPage
+	}
+	slices.SortFunc(pullRequests, func(a, b v1.RepositoryPullRequest) int { return a.Number - b.Number })
+	status.PullRequests = pullRequests
+	status.LastRefreshRequest = rec.Object.Annotations[v1.RefreshAnnotation]
 	rec.Object.Status.SetCurrentIfStaleDueToAnyOf(v1.InternalError)
 	if result := rec.UpdateStatus(); result != nil {
 		return result
@@ -158,63 +234,93 @@ func (r *RepositoryReconciler) reconcileGitHubRepository(rec *k8s.Reconciliation
 		return result
 	}
 
+	// Keep the shared mirror up to date
+	if result := r.ensureMirror(rec, gitURL(rec.Object)); result != nil {
+		return result
+	}
+
 	// Done
 	return k8s.RequeueAfter(refreshInterval)
 }
 
-func (r *RepositoryReconciler) connectToGitHub(rec *k8s.Reconciliation[*v1.Repository], refreshInterval time.Duration) (*github.Client, *k8s.Result) {
+// refreshBranches updates the revisions of the given branches only, removing branches that no longer exist, and marks the
+// given refresh request as handled.
+func (r *RepositoryReconciler) refreshBranches(rec *k8s.Reconciliation[*v1.Repository], refreshInterval time.Duration, ghc *github.Client, refreshRequest string, branches []string) *k8s.Result {
 	status := &rec.Object.Status
-	patCfg := rec.Object.Spec.GitHub.PersonalAccessToken
-
-	// The GitHub client, to be initialized based on the authentication configuration selected
-	var ghc *github.Client
-
-	// Fetch secret
-	authSecret := &v12.Secret{}
-	secretObjKey := patCfg.Secret.GetObjectKey(rec.Object.Namespace)
-	if err := r.Client.Get(rec.Ctx, secretObjKey, authSecret); err != nil {
-		if errors.IsNotFound(err) {
-			status.SetUnauthenticatedDueToAuthSecretNotFound("Secret '%s' not found", secretObjKey)
-			status.SetMaybeStaleDueToUnauthenticated(status.GetUnauthenticatedMessage())
-			if result := rec.UpdateStatus(); result != nil {
-				return nil, result
-			}
-			return nil, k8s.RequeueAfter(refreshInterval)
-		} else if errors.IsForbidden(err) {
-			status.SetUnauthenticatedDueToAuthSecretForbidden("Secret '%s' is not accessible: %+v", secretObjKey, err)
-			status.SetMaybeStaleDueToUnauthenticated(status.GetUnauthenticatedMessage())
-			if result := rec.UpdateStatus(); result != nil {
-				return nil, result
+	for _, branchName := range branches {
+		branch, response, err := ghc.Repositories.GetBranch(rec.Ctx, rec.Object.Spec.GitHub.Owner, rec.Object.Spec.GitHub.Name, branchName, 1)
+		if err != nil {
+			if response != nil && response.StatusCode == http.StatusNotFound {
+				delete(status.Revisions, branchName)
+				continue
 			}
-			return nil, k8s.RequeueAfter(refreshInterval)
-		} else {
-			status.SetUnauthenticatedDueToInternalError("Failed reading secret '%s': %+v", secretObjKey, err)
-			status.SetMaybeStaleDueToUnauthenticated(status.GetUnauthenticatedMessage())
+			status.SetMaybeStaleDueToInternalError("Failed fetching branch '%s': %+v", branchName, err)
 			if result := rec.UpdateStatus(); result != nil {
-				return nil, result
+				return result
 			}
-			return nil, k8s.RequeueAfter(refreshInterval)
+			return k8s.RequeueAfter(refreshInterval)
 		}
+		if status.Revisions == nil {
+			status.Revisions = make(map[string]string)
+		}
+		status.Revisions[branchName] = branch.GetCommit().GetSHA()
 	}
-
-	// Revert status if auth secret fetched successfully
-	status.SetAuthenticatedIfUnauthenticatedDueToAnyOf(v1.AuthSecretNotFound, v1.AuthSecretForbidden, v1.InternalError)
-	status.SetCurrentIfStaleDueToAnyOf(v1.Unauthenticated)
+	status.LastRefreshRequest = refreshRequest
+	status.SetCurrentIfStaleDueToAnyOf(v1.InternalError)
 	if result := rec.UpdateStatus(); result != nil {
-		return nil, result
+		return result
 	}
+	return k8s.RequeueAfter(refreshInterval)
+}
 
-	// Extract & validate personal access token
-	pat, ok := authSecret.Data[patCfg.Key]
-	if !ok {
-		status.SetUnauthenticatedDueToAuthSecretKeyNotFound("Key '%s' not found in secret '%s'", patCfg.Key, secretObjKey)
-		status.SetMaybeStaleDueToUnauthenticated(status.GetUnauthenticatedMessage())
-		if result := rec.UpdateStatus(); result != nil {
-			return nil, result
+// toRepositoryPullRequests converts the given GitHub pull requests into their status representation, skipping pull
+// requests from forks (i.e. whose head repository is not the repository with the given ID), since their head branches
+// are not in this repository.
+func toRepositoryPullRequests(ghRepoID int64, pullRequests []*github.PullRequest) []v1.RepositoryPullRequest {
+	var result []v1.RepositoryPullRequest
+	for _, pr := range pullRequests {
+		if pr.GetHead().GetRepo().GetID() != ghRepoID {
+			continue
+		}
+		var labels []string
+		for _, label := range pr.Labels {
+			labels = append(labels, label.GetName())
+		}
+		result = append(result, v1.RepositoryPullRequest{
+			Number:     pr.GetNumber(),
+			HeadBranch: pr.GetHead().GetRef(),
+			HeadSHA:    pr.GetHead().GetSHA(),
+			Labels:     labels,
+		})
+	}
+	return result
+}
+
+func (r *RepositoryReconciler) connectToGitHub(rec *k8s.Reconciliation[*v1.Repository], refreshInterval time.Duration) (*github.Client, *k8s.Result) {
+	status := &rec.Object.Status
+
+	// The GitHub client, to be initialized based on the authentication configuration selected
+	var ghc *github.Client
+
+	// Fetch & validate the personal access token
+	pat, err := getGitHubPersonalAccessToken(rec.Ctx, r.Client, rec.Object)
+	if err != nil {
+		tokenErr, ok := err.(*gitHubTokenError)
+		if !ok {
+			tokenErr = &gitHubTokenError{Reason: v1.InternalError, Message: err.Error()}
+		}
+		switch tokenErr.Reason {
+		case v1.AuthSecretNotFound:
+			status.SetUnauthenticatedDueToAuthSecretNotFound("%s", tokenErr.Message)
+		case v1.AuthSecretForbidden:
+			status.SetUnauthenticatedDueToAuthSecretForbidden("%s", tokenErr.Message)
+		case v1.AuthSecretKeyNotFound:
+			status.SetUnauthenticatedDueToAuthSecretKeyNotFound("%s", tokenErr.Message)
+		case v1.AuthTokenEmpty:
+			status.SetUnauthenticatedDueToAuthTokenEmpty("%s", tokenErr.Message)
+		default:
+			status.SetUnauthenticatedDueToInternalError("%s", tokenErr.Message)
 		}
-		return nil, k8s.RequeueAfter(refreshInterval)
-	} else if string(pat) == "" {
-		status.SetUnauthenticatedDueToAuthTokenEmpty("Token in key '%s' in secret '%s' is empty", patCfg.Key, secretObjKey)
 		status.SetMaybeStaleDueToUnauthenticated(status.GetUnauthenticatedMessage())
 		if result := rec.UpdateStatus(); result != nil {
 			return nil, result
@@ -222,15 +328,24 @@ func (r *RepositoryReconciler) connectToGitHub(rec *k8s.Reconciliation[*v1.Repos
 		return nil, k8s.RequeueAfter(refreshInterval)
 	}
 
-	// Revert status if auth secret fetched successfully
-	status.SetAuthenticatedIfUnauthenticatedDueToAnyOf(v1.AuthSecretKeyNotFound, v1.AuthTokenEmpty)
+	// Revert status if the personal access token was fetched successfully
+	status.SetAuthenticatedIfUnauthenticatedDueToAnyOf(v1.AuthSecretNotFound, v1.AuthSecretForbidden, v1.AuthSecretKeyNotFound, v1.AuthTokenEmpty, v1.InternalError)
 	status.SetCurrentIfStaleDueToAnyOf(v1.Unauthenticated)
 	if result := rec.UpdateStatus(); result != nil {
 		return nil, result
 	}
 
 	// Create the GitHub client & verify it's properly authenticated
-	ghc = github.NewClient(nil).WithAuthToken(string(pat))
+	if gitHubClient, err := r.GitHubClientFactory.newClient(pat); err != nil {
+		status.SetUnauthenticatedDueToInternalError("GitHub client creation failed: %+v", err)
+		status.SetMaybeStaleDueToUnauthenticated(status.GetUnauthenticatedMessage())
+		if result := rec.UpdateStatus(); result != nil {
+			return nil, result
+		}
+		return nil, k8s.RequeueAfter(refreshInterval)
+	} else {
+		ghc = gitHubClient
+	}
 	if req, err := ghc.NewRequest("GET", "user", nil); err != nil {
 		status.SetUnauthenticatedDueToAuthenticationFailed("Validation request creation failed: %+v", err)
 		status.SetMaybeStaleDueToUnauthenticated(status.GetUnauthenticatedMessage())
@@ -409,7 +524,7 @@ func (r *RepositoryReconciler) ensureWebhook(rec *k8s.Reconciliation[*v1.Reposit
 					"content_type": "json",
 					"secret":       secretValue,
 				},
-				Events: []string{"push"},
+				Events: GitHubWebhookEvents,
 				Active: lang.Ptr(true),
 			}
 			if _, _, err := ghc.Repositories.CreateHook(rec.Ctx, *repo.Owner.Login, *repo.Name, webhook); err != nil {
@@ -428,16 +543,16 @@ func (r *RepositoryReconciler) ensureWebhook(rec *k8s.Reconciliation[*v1.Reposit
 				}
 			}
 			return k8s.Requeue()
-		} else if !slices.Contains(webhook.Events, "push") {
-			webhook = &github.Hook{
+		} else if slices.ContainsFunc(GitHubWebhookEvents, func(e string) bool { return !slices.Contains(webhook.Events, e) }) {
+			hook := &github.Hook{
 				Config: map[string]any{
 					"url":          r.GitHubWebhookURL,
 					"content_type": "json",
 					"secret":       secretValue,
 				},
-				Events: []string{"push"},
+				Events: GitHubWebhookEvents,
 			}
-			if _, _, err := ghc.Repositories.EditHook(rec.Ctx, *repo.Owner.Login, *repo.Name, *webhook.ID, webhook); err != nil {
+			if _, _, err := ghc.Repositories.EditHook(rec.Ctx, *repo.Owner.Login, *repo.Name, *webhook.ID, hook); err != nil {
 				status.SetInvalidDueToInternalError("Failed to update webhook config: %+v", err)
 				if result := rec.UpdateStatus(); result != nil {
 					return result
@@ -461,14 +576,46 @@ func (r *RepositoryReconciler) ensureWebhook(rec *k8s.Reconciliation[*v1.Reposit
 	return nil
 }
 
+// shouldReconcileRepositoryUpdate only passes repository updates that change its generation, or that request a refresh.
+// Webhook events that cannot be applied to the repository status directly (e.g. pull requests being opened, closed or
+// labeled) request a refresh via the refresh annotation; since annotations do not change the generation, refreshes
+// would otherwise wait for the next refresh interval.
+func shouldReconcileRepositoryUpdate(e event.UpdateEvent) bool {
+	if e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() {
+		return true
+	}
+	return e.ObjectOld.GetAnnotations()[v1.RefreshAnnotation] != e.ObjectNew.GetAnnotations()[v1.RefreshAnnotation]
+}
+
+// isRepositoryUpdateRelevantToDependents checks whether the given repository update may affect the applications,
+// environments & deployments depending on the repository; updates only recording webhook deliveries (or pings) don't.
+func isRepositoryUpdateRelevantToDependents(e event.UpdateEvent) bool {
+	oldRepo, oldOK := e.ObjectOld.(*v1.Repository)
+	newRepo, newOK := e.ObjectNew.(*v1.Repository)
+	if !oldOK || !newOK {
+		return true
+	}
+	return !equality.Semantic.DeepEqual(withoutWebhookAudit(oldRepo), withoutWebhookAudit(newRepo))
+}
+
+// withoutWebhookAudit returns a copy of the given repository without its webhook delivery audit & object versioning.
+func withoutWebhookAudit(repo *v1.Repository) *v1.Repository {
+	repo = repo.DeepCopy()
+	repo.ResourceVersion, repo.ManagedFields = "", nil
+	repo.Status.WebhookDeliveries, repo.Status.WebhookDeliveryCounts, repo.Status.LastWebhookPing = nil, nil, nil
+	return repo
+}
+
+// repositoryUpdateRelevantToDependents is a predicate for watches of repositories by their dependents, filtering out
+// updates that only record webhook deliveries.
+var repositoryUpdateRelevantToDependents = predicate.Funcs{UpdateFunc: isRepositoryUpdateRelevantToDependents}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *RepositoryReconciler) SetupWithManager(mgr controllerruntime.Manager) error {
 	return controllerruntime.NewControllerManagedBy(mgr).
 		For(&v1.Repository{}, builder.WithPredicates(predicate.Funcs{
-			UpdateFunc: func(e event.UpdateEvent) bool {
-				// Only reconcile if the generation has changed
-				return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
-			},
+			UpdateFunc: shouldReconcileRepositoryUpdate,
 		})).
+		Owns(&batchv1.Job{}).
 		Complete(r)
 }
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/lang"
+)
+
+var _ = Describe("Repository pull requests", func() {
+	It("should skip pull requests from forks", func() {
+		newPR := func(number int, repoID int64, branch string, labels ...string) *github.PullRequest {
+			pr := &github.PullRequest{
+				Number: lang.Ptr(number),
+				Head:   &github.PullRequestBranch{Ref: lang.Ptr(branch), SHA: lang.Ptr("abcd"), Repo: &github.Repository{ID: lang.Ptr(repoID)}},
+			}
+			for _, label := range labels {
+				pr.Labels = append(pr.Labels, &github.Label{Name: lang.Ptr(label)})
+			}
+			return pr
+		}
+		Expect(toRepositoryPullRequests(1, []*github.PullRequest{newPR(1, 1, "feature", "preview"), newPR(2, 2, "fork")})).To(Equal([]apiv1.RepositoryPullRequest{
+			{Number: 1, HeadBranch: "feature", HeadSHA: "abcd", Labels: []string{"preview"}},
+		}))
+	})
+
+	It("should reconcile repositories upon refresh requests", func() {
+		oldRepo := &apiv1.Repository{ObjectMeta: metav1.ObjectMeta{Generation: 1}}
+		refreshed := &apiv1.Repository{ObjectMeta: metav1.ObjectMeta{Generation: 1, Annotations: map[string]string{apiv1.RefreshAnnotation: "now"}}}
+		Expect(shouldReconcileRepositoryUpdate(event.UpdateEvent{ObjectOld: oldRepo, ObjectNew: oldRepo.DeepCopy()})).To(BeFalse())
+		Expect(shouldReconcileRepositoryUpdate(event.UpdateEvent{ObjectOld: oldRepo, ObjectNew: refreshed})).To(BeTrue())
+	})
+
+	It("should not notify dependents of webhook deliveries", func() {
+		oldRepo := &apiv1.Repository{
+			ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1"},
+			Status:     apiv1.RepositoryStatus{Revisions: map[string]string{"main": "aaaa"}},
+		}
+		delivered := oldRepo.DeepCopy()
+		delivered.ResourceVersion = "2"
+		delivered.Status.WebhookDeliveries = []apiv1.RepositoryWebhookDelivery{{ID: "1", Event: "ping", Result: apiv1.WebhookDeliveryAccepted}}
+		delivered.Status.WebhookDeliveryCounts = map[string]int64{apiv1.WebhookDeliveryAccepted: 1}
+		Expect(isRepositoryUpdateRelevantToDependents(event.UpdateEvent{ObjectOld: oldRepo, ObjectNew: delivered})).To(BeFalse())
+
+		pushed := delivered.DeepCopy()
+		pushed.ResourceVersion = "3"
+		pushed.Status.Revisions["main"] = "bbbb"
+		Expect(isRepositoryUpdateRelevantToDependents(event.UpdateEvent{ObjectOld: delivered, ObjectNew: pushed})).To(BeTrue())
+	})
+})
+
+var _ = Describe("Repository branch refreshes", func() {
+	It("should refresh only the requested branches", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v3/repos/owner/name/branches/main":
+				_, _ = w.Write([]byte(`{"name":"main","commit":{"sha":"bbbb"}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			}
+		}))
+		DeferCleanup(server.Close)
+		ghc, err := NewGitHubClientFactoryForURL(server.URL + "/api/v3")("s3cr3t")
+		Expect(err).NotTo(HaveOccurred())
+
+		repo := &apiv1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "repo"},
+			Spec:       apiv1.RepositorySpec{GitHub: &apiv1.GitHubRepositorySpec{Owner: "owner", Name: "name"}},
+			Status:     apiv1.RepositoryStatus{Revisions: map[string]string{"main": "aaaa", "gone": "cccc", "other": "dddd"}},
+		}
+		r, rec := newRepositoryReconciliation(repo)
+		Expect(r.refreshBranches(rec, time.Minute, ghc, "request-1", []string{"main", "gone"})).To(Equal(k8s.RequeueAfter(time.Minute)))
+		Expect(r.Client.Get(context.Background(), client.ObjectKeyFromObject(repo), repo)).To(Succeed())
+		Expect(repo.Status.Revisions).To(Equal(map[string]string{"main": "bbbb", "other": "dddd"}))
+		Expect(repo.Status.LastRefreshRequest).To(Equal("request-1"))
+	})
+})
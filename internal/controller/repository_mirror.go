@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"maps"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+	"github.com/arikkfir/devbot/internal/util/lang"
+	stringsutil "github.com/arikkfir/devbot/internal/util/strings"
+)
+
+const (
+	// MirrorJobLabel marks jobs that update a repository's shared mirror.
+	MirrorJobLabel = "devbot.kfirs.com/mirror"
+
+	mirrorMountPath = "/mirror"
+)
+
+var defaultMirrorSize = resource.MustParse("10Gi")
+
+// ensureMirror maintains the shared mirror of the repository, if enabled: it ensures the mirror's persistent volume
+// claim exists, and launches a fetch job updating the mirror whenever the repository's branches change. The mirror is
+// an optimization only, hence failures are logged and retried on the next refresh, but do not render the repository
+// stale.
+func (r *RepositoryReconciler) ensureMirror(rec *k8s.Reconciliation[*apiv1.Repository], url string) *k8s.Result {
+	logger := log.FromContext(rec.Ctx)
+	status := &rec.Object.Status
+
+	// Remove the mirror if it was disabled
+	if rec.Object.Spec.Mirror == nil {
+		if status.Mirror == nil {
+			return nil
+		}
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: rec.Object.Namespace, Name: status.Mirror.PersistentVolumeClaimName}}
+		if err := r.Delete(rec.Ctx, pvc); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "Failed deleting mirror persistent volume claim", "claim", pvc.Name)
+			return k8s.Requeue()
+		}
+		status.Mirror = nil
+		return rec.UpdateStatus()
+	}
+
+	// Ensure the mirror's persistent volume claim exists
+	if status.Mirror == nil {
+		status.Mirror = &apiv1.RepositoryStatusMirror{PersistentVolumeClaimName: rec.Object.Name + "-mirror"}
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+	}
+	pvcKey := client.ObjectKey{Namespace: rec.Object.Namespace, Name: status.Mirror.PersistentVolumeClaimName}
+	if err := r.Get(rec.Ctx, pvcKey, &corev1.PersistentVolumeClaim{}); apierrors.IsNotFound(err) {
+		pvc := newMirrorPersistentVolumeClaim(rec.Object, pvcKey.Name)
+		if err := r.Create(rec.Ctx, pvc); err != nil {
+			logger.Error(err, "Failed creating mirror persistent volume claim", "claim", pvcKey.Name)
+			return k8s.Requeue()
+		}
+	} else if err != nil {
+		logger.Error(err, "Failed looking up mirror persistent volume claim", "claim", pvcKey.Name)
+		return k8s.Requeue()
+	}
+
+	// Track the running fetch job, if any
+	if status.Mirror.FetchJobName != "" {
+		job := &batchv1.Job{}
+		if err := r.Get(rec.Ctx, client.ObjectKey{Namespace: rec.Object.Namespace, Name: status.Mirror.FetchJobName}, job); apierrors.IsNotFound(err) {
+			status.Mirror.FetchJobName = ""
+		} else if err != nil {
+			logger.Error(err, "Failed looking up mirror fetch job", "job", status.Mirror.FetchJobName)
+			return k8s.Requeue()
+		} else if isJobConditionTrue(job, batchv1.JobFailed) {
+			// Fetch again on next reconciliation
+			logger.Info("Mirror fetch job failed", "job", job.Name)
+			status.Mirror.FetchJobName = ""
+			status.Mirror.FetchedRevisions = nil
+		} else if isJobConditionTrue(job, batchv1.JobComplete) {
+			status.Mirror.FetchJobName = ""
+		} else {
+			// Still running; its completion will trigger another reconciliation
+			return nil
+		}
+		if result := rec.UpdateStatus(); result != nil {
+			return result
+		}
+	}
+
+	// Launch a fetch job if the repository's branches changed since the last fetch
+	if maps.Equal(status.Mirror.FetchedRevisions, status.Revisions) {
+		return nil
+	}
+	secretName := rec.Object.Name + "-mirror-credentials"
+	if err := ensureGitCredentialsSecret(rec.Ctx, r.Client, rec.Object, apiv1.RepositoryGVK, secretName, rec.Object); err != nil {
+		logger.Error(err, "Failed preparing mirror fetch credentials")
+		return k8s.Requeue()
+	}
+	job := newMirrorFetchJob(rec.Object, url)
+	addGitCredentialsVolume(job, secretName, "mirror")
+	logger.WithValues("jobName", job.Name).Info("Creating mirror fetch job")
+	if err := r.Create(rec.Ctx, job); err != nil {
+		logger.Error(err, "Failed creating mirror fetch job")
+		return k8s.Requeue()
+	}
+	status.Mirror.FetchJobName = job.Name
+	status.Mirror.FetchedRevisions = maps.Clone(status.Revisions)
+	return rec.UpdateStatus()
+}
+
+// newMirrorPersistentVolumeClaim creates the spec of the persistent volume claim housing the mirror of the given
+// repository.
+func newMirrorPersistentVolumeClaim(repo *apiv1.Repository, name string) *corev1.PersistentVolumeClaim {
+	size, accessMode := defaultMirrorSize, corev1.ReadWriteMany
+	if repo.Spec.Mirror.Size != nil {
+		size = *repo.Spec.Mirror.Size
+	}
+	if repo.Spec.Mirror.AccessMode != "" {
+		accessMode = repo.Spec.Mirror.AccessMode
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       repo.Namespace,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(repo, apiv1.RepositoryGVK)},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		},
+	}
+	if repo.Spec.Mirror.StorageClassName != "" {
+		pvc.Spec.StorageClassName = lang.Ptr(repo.Spec.Mirror.StorageClassName)
+	}
+	return pvc
+}
+
+// newMirrorFetchJob creates the spec of a job that updates the mirror of the given repository from the given URL,
+// creating the mirror if it does not exist yet.
+func newMirrorFetchJob(repo *apiv1.Repository, url string) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            repo.Name + "-mirror-" + stringsutil.RandomHash(5),
+			Namespace:       repo.Namespace,
+			Labels:          map[string]string{MirrorJobLabel: repo.Name},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(repo, apiv1.RepositoryGVK)},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            lang.Ptr(int32(3)),
+			TTLSecondsAfterFinished: lang.Ptr(int32((5 * time.Minute).Seconds())),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "mirror",
+							Image: CloneJobImage,
+							Env: []corev1.EnvVar{
+								{Name: "GIT_URL", Value: url},
+								{Name: "MIRROR", Value: "true"},
+								{Name: "MIRROR_DIR", Value: mirrorMountPath},
+							},
+							VolumeMounts: []corev1.VolumeMount{{Name: "mirror", MountPath: mirrorMountPath}},
+						},
+					},
+					RestartPolicy: corev1.RestartPolicyOnFailure,
+					Volumes: []corev1.Volume{
+						{
+							Name: "mirror",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: repo.Status.Mirror.PersistentVolumeClaimName},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// addMirrorVolume mounts the shared mirror of the given repository (if it has one) read-only into the clone container
+// of the given job, and points the clone container at it. Since pods can only mount claims of their own namespace, jobs
+// outside the repository's namespace clone without the mirror.
+func addMirrorVolume(job *batchv1.Job, repo *apiv1.Repository) {
+	if repo.Status.Mirror == nil || repo.Status.Mirror.PersistentVolumeClaimName == "" || repo.Namespace != job.Namespace {
+		return
+	}
+
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: "mirror",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: repo.Status.Mirror.PersistentVolumeClaimName,
+				ReadOnly:  true,
+			},
+		},
+	})
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			if containers[i].Name == string(PhaseClone) {
+				c := &containers[i]
+				c.Env = append(c.Env, corev1.EnvVar{Name: "MIRROR_DIR", Value: mirrorMountPath})
+				c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: "mirror", MountPath: mirrorMountPath, ReadOnly: true})
+			}
+		}
+	}
+}
+
+// isJobConditionTrue checks whether the given job carries the given condition with a "True" status.
+func isJobConditionTrue(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == conditionType {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+var _ = Describe("Repository mirror", func() {
+	newRec := func(repo *apiv1.Repository) (*RepositoryReconciler, *k8s.Reconciliation[*apiv1.Repository]) {
+		return newRepositoryReconciliation(repo, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "github"},
+			Data:       map[string][]byte{"token": []byte("t0k3n")},
+		})
+	}
+	newRepo := func() *apiv1.Repository {
+		return &apiv1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "repo"},
+			Spec: apiv1.RepositorySpec{
+				GitHub: &apiv1.GitHubRepositorySpec{
+					Owner: "owner",
+					Name:  "repo",
+					PersonalAccessToken: apiv1.GitHubRepositoryPersonalAccessToken{
+						Secret: apiv1.SecretReferenceWithOptionalNamespace{Name: "github"},
+						Key:    "token",
+					},
+				},
+				Mirror: &apiv1.RepositoryMirror{StorageClassName: "nfs"},
+			},
+			Status: apiv1.RepositoryStatus{Revisions: map[string]string{"main": "abc"}},
+		}
+	}
+	listJobs := func(r *RepositoryReconciler) []batchv1.Job {
+		jobs := &batchv1.JobList{}
+		Expect(r.List(context.Background(), jobs, client.InNamespace("ns"))).To(Succeed())
+		return jobs.Items
+	}
+	ensureMirror := func(r *RepositoryReconciler, rec *k8s.Reconciliation[*apiv1.Repository]) {
+		Expect(r.ensureMirror(rec, "https://github.com/owner/repo")).To(BeNil())
+	}
+
+	It("should create the mirror claim and launch a fetch job", func() {
+		r, rec := newRec(newRepo())
+		ensureMirror(r, rec)
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(r.Get(rec.Ctx, client.ObjectKey{Namespace: "ns", Name: "repo-mirror"}, pvc)).To(Succeed())
+		Expect(*pvc.Spec.StorageClassName).To(Equal("nfs"))
+		Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteMany))
+		Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("10Gi"))
+
+		jobs := listJobs(r)
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "MIRROR", Value: "true"}))
+		Expect(jobs[0].Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "CREDENTIALS_DIR", Value: gitCredentialsMountPath}))
+		Expect(jobs[0].Spec.Template.Spec.Volumes[1].Projected.Sources[0].Secret.Name).To(Equal("repo-mirror-credentials"))
+		Expect(rec.Object.Status.Mirror.FetchJobName).To(Equal(jobs[0].Name))
+		Expect(rec.Object.Status.Mirror.FetchedRevisions).To(Equal(map[string]string{"main": "abc"}))
+	})
+
+	It("should not launch another fetch job while one is running or revisions are unchanged", func() {
+		r, rec := newRec(newRepo())
+		ensureMirror(r, rec)
+		ensureMirror(r, rec)
+		Expect(listJobs(r)).To(HaveLen(1))
+
+		job := listJobs(r)[0]
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(r.Status().Update(rec.Ctx, &job)).To(Succeed())
+		ensureMirror(r, rec)
+		Expect(rec.Object.Status.Mirror.FetchJobName).To(BeEmpty())
+		Expect(listJobs(r)).To(HaveLen(1))
+
+		rec.Object.Status.Revisions["feature"] = "def"
+		ensureMirror(r, rec)
+		Expect(listJobs(r)).To(HaveLen(2))
+	})
+
+	It("should fetch again after a failed fetch job", func() {
+		r, rec := newRec(newRepo())
+		ensureMirror(r, rec)
+		job := listJobs(r)[0]
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+		Expect(r.Status().Update(rec.Ctx, &job)).To(Succeed())
+
+		ensureMirror(r, rec)
+		Expect(listJobs(r)).To(HaveLen(2))
+		Expect(rec.Object.Status.Mirror.FetchJobName).ToNot(Equal(job.Name))
+	})
+
+	It("should remove the mirror when disabled", func() {
+		r, rec := newRec(newRepo())
+		ensureMirror(r, rec)
+		rec.Object.Spec.Mirror = nil
+		ensureMirror(r, rec)
+		Expect(rec.Object.Status.Mirror).To(BeNil())
+		Expect(r.Get(rec.Ctx, client.ObjectKey{Namespace: "ns", Name: "repo-mirror"}, &corev1.PersistentVolumeClaim{})).ToNot(Succeed())
+	})
+
+	It("should mount the mirror into the clone container only", func() {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns"},
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						InitContainers: []corev1.Container{{Name: string(PhaseClone)}, {Name: string(PhaseBake)}},
+						Containers:     []corev1.Container{{Name: string(PhaseApply)}},
+					},
+				},
+			},
+		}
+		repo := &apiv1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns"},
+			Status:     apiv1.RepositoryStatus{Mirror: &apiv1.RepositoryStatusMirror{PersistentVolumeClaimName: "repo-mirror"}},
+		}
+		addMirrorVolume(job, repo)
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].PersistentVolumeClaim.ReadOnly).To(BeTrue())
+		Expect(podSpec.InitContainers[0].VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: "mirror", MountPath: mirrorMountPath, ReadOnly: true}))
+		Expect(podSpec.InitContainers[0].Env).To(ConsistOf(corev1.EnvVar{Name: "MIRROR_DIR", Value: mirrorMountPath}))
+		Expect(podSpec.InitContainers[1].VolumeMounts).To(BeEmpty())
+		Expect(podSpec.Containers[0].VolumeMounts).To(BeEmpty())
+	})
+
+	It("should not mount the mirror into jobs of other namespaces", func() {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other"},
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: string(PhaseClone)}}},
+				},
+			},
+		}
+		repo := &apiv1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns"},
+			Status:     apiv1.RepositoryStatus{Mirror: &apiv1.RepositoryStatusMirror{PersistentVolumeClaimName: "repo-mirror"}},
+		}
+		addMirrorVolume(job, repo)
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(BeEmpty())
+		Expect(podSpec.Containers[0].VolumeMounts).To(BeEmpty())
+		Expect(podSpec.Containers[0].Env).To(BeEmpty())
+	})
+})
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+	"github.com/arikkfir/devbot/internal/util/k8s"
+)
+
+func TestController(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Controller Suite")
+}
+
+// newTestScheme creates a scheme with the Kubernetes & devbot API types registered.
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(apiv1.AddToScheme(scheme)).To(Succeed())
+	return scheme
+}
+
+// newFakeClient creates a fake client serving the given objects, with the status subresource of devbot's API types and
+// the ownership index of owned types.
+func newFakeClient(scheme *runtime.Scheme, objects ...runtime.Object) client.WithWatch {
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&apiv1.Application{}, &apiv1.Environment{}, &apiv1.Deployment{}, &apiv1.Repository{}).
+		WithIndex(&apiv1.Environment{}, k8s.OwnershipIndexField, k8s.IndexGetOwnerReferencesOf).
+		WithIndex(&apiv1.Deployment{}, k8s.OwnershipIndexField, k8s.IndexGetOwnerReferencesOf).
+		WithIndex(&batchv1.Job{}, k8s.OwnershipIndexField, k8s.IndexGetOwnerReferencesOf).
+		WithRuntimeObjects(objects...).
+		Build()
+}
+
+// newDeploymentReconciliation creates a deployment reconciler backed by a fake client serving the given deployment &
+// objects, and a reconciliation of that deployment.
+func newDeploymentReconciliation(deployment *apiv1.Deployment, objects ...runtime.Object) (*DeploymentReconciler, *k8s.Reconciliation[*apiv1.Deployment]) {
+	scheme := newTestScheme()
+	c := newFakeClient(scheme, append(objects, deployment)...)
+	r := &DeploymentReconciler{Client: c, Scheme: scheme}
+	return r, &k8s.Reconciliation[*apiv1.Deployment]{Ctx: context.Background(), Client: c, Object: deployment}
+}
+
+// newRepositoryReconciliation creates a repository reconciler backed by a fake client serving the given repository &
+// objects, and a reconciliation of that repository.
+func newRepositoryReconciliation(repo *apiv1.Repository, objects ...runtime.Object) (*RepositoryReconciler, *k8s.Reconciliation[*apiv1.Repository]) {
+	scheme := newTestScheme()
+	c := newFakeClient(scheme, append(objects, repo)...)
+	r := &RepositoryReconciler{Client: c, Scheme: scheme}
+	return r, &k8s.Reconciliation[*apiv1.Repository]{Ctx: context.Background(), Client: c, Object: repo}
+}
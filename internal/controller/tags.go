@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// findHighestMatchingTag returns the name & commit SHA of the highest tag whose name is a semantic version matching the
+// given constraint. Tags whose names are not semantic versions are ignored, and ties between tags of equal versions (e.g.
+// "v1.2.3" and "1.2.3") are broken by picking the greater tag name. Empty results are returned if no tag matches.
+func findHighestMatchingTag(tags map[string]string, constraint string) (string, string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid version constraint '%s': %w", constraint, err)
+	}
+
+	var highestTag string
+	var highestVersion *semver.Version
+	for tag := range tags {
+		v, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+		if !c.Check(v) {
+			continue
+		} else if highestVersion == nil || v.GreaterThan(highestVersion) || v.Equal(highestVersion) && tag > highestTag {
+			highestTag, highestVersion = tag, v
+		}
+	}
+	if highestVersion == nil {
+		return "", "", nil
+	}
+	return highestTag, tags[highestTag], nil
+}
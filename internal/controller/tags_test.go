@@ -0,0 +1,47 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tags", func() {
+	tags := map[string]string{
+		"v1.3.9":       "sha-1.3.9",
+		"v1.4.0":       "sha-1.4.0",
+		"v1.4.2":       "sha-1.4.2",
+		"1.4.10":       "sha-1.4.10",
+		"v1.5.0-rc.1":  "sha-1.5.0-rc.1",
+		"v2.0.0":       "sha-2.0.0",
+		"release-next": "sha-release-next",
+	}
+
+	DescribeTable("should find highest matching tag",
+		func(constraint, expectedTag, expectedSHA string) {
+			tag, sha, err := findHighestMatchingTag(tags, constraint)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tag).To(Equal(expectedTag))
+			Expect(sha).To(Equal(expectedSHA))
+		},
+		Entry("tilde", "~1.4", "1.4.10", "sha-1.4.10"),
+		Entry("caret", "^1.3", "1.4.10", "sha-1.4.10"),
+		Entry("exact", "1.3.9", "v1.3.9", "sha-1.3.9"),
+		Entry("pre-release", "~1.5.0-0", "v1.5.0-rc.1", "sha-1.5.0-rc.1"),
+		Entry("no match", "~3", "", ""),
+	)
+
+	It("should break ties between equal versions by tag name", func() {
+		tags := map[string]string{"1.2.3": "sha-1", "v1.2.3": "sha-2", "v1.2.3+build": "sha-3"}
+		for range 10 {
+			tag, sha, err := findHighestMatchingTag(tags, "~1.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tag).To(Equal("v1.2.3+build"))
+			Expect(sha).To(Equal("sha-3"))
+		}
+	})
+
+	It("should fail on invalid constraints", func() {
+		_, _, err := findHighestMatchingTag(tags, "not a constraint")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,312 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// lfsMediaType is the media type of Git LFS API requests & responses.
+	lfsMediaType = "application/vnd.git-lfs+json"
+
+	// lfsPointerVersion is the first line of Git LFS pointer files.
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+
+	// lfsPointerMaxSize is the maximum size of Git LFS pointer files; larger files are never pointers.
+	lfsPointerMaxSize = 1024
+
+	// lfsBatchSize is the maximum number of objects requested in a single batch request.
+	lfsBatchSize = 100
+)
+
+var lfsOIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// LFSPointer identifies a Git LFS object, as referenced by a pointer file committed in its place.
+type LFSPointer struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// ParseLFSPointer parses the given file contents as a Git LFS pointer. The second return value is false if the contents
+// are not a valid pointer.
+func ParseLFSPointer(data []byte) (LFSPointer, bool) {
+	if len(data) > lfsPointerMaxSize || !bytes.HasPrefix(data, []byte(lfsPointerVersion+"\n")) {
+		return LFSPointer{}, false
+	}
+
+	pointer := LFSPointer{Size: -1}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "oid":
+			if oid, ok := strings.CutPrefix(value, "sha256:"); ok && lfsOIDPattern.MatchString(oid) {
+				pointer.OID = oid
+			}
+		case "size":
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size >= 0 {
+				pointer.Size = size
+			}
+		}
+	}
+	if pointer.OID == "" || pointer.Size < 0 {
+		return LFSPointer{}, false
+	}
+	return pointer, true
+}
+
+// FindLFSPointers finds the Git LFS pointer files in the worktree at the given directory, keyed by their paths. Nested
+// repositories (e.g. submodules) are skipped, as their objects are stored in their own LFS endpoints.
+func FindLFSPointers(dir string) (map[string]LFSPointer, error) {
+	pointers := make(map[string]LFSPointer)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			} else if path != dir {
+				if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		} else if info.Size() > lfsPointerMaxSize {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if pointer, ok := ParseLFSPointer(data); ok {
+			pointers[path] = pointer
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed scanning for LFS pointers: %w", err)
+	}
+	return pointers, nil
+}
+
+// LFSEndpoint is a Git LFS server endpoint, along with the headers authenticating requests to it.
+type LFSEndpoint struct {
+	URL    string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+// LFSEndpointForURL returns the default Git LFS endpoint of the repository with the given HTTP(S) URL.
+func LFSEndpointForURL(url string) LFSEndpoint {
+	url = strings.TrimSuffix(url, "/")
+	if !strings.HasSuffix(url, ".git") {
+		url += ".git"
+	}
+	return LFSEndpoint{URL: url + "/info/lfs"}
+}
+
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+type lfsBatchObject struct {
+	LFSPointer
+	Actions map[string]lfsAction `json:"actions,omitempty"`
+	Error   *lfsObjectError      `json:"error,omitempty"`
+}
+
+type lfsObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lfsBatchRequest struct {
+	Operation string       `json:"operation"`
+	Transfers []string     `json:"transfers"`
+	Objects   []LFSPointer `json:"objects"`
+	Ref       *lfsBatchRef `json:"ref,omitempty"`
+}
+
+type lfsBatchRef struct {
+	Name string `json:"name"`
+}
+
+type lfsBatchResponse struct {
+	Objects []lfsBatchObject `json:"objects"`
+}
+
+// FetchLFSObjects downloads the objects of the given pointer files from the given endpoint using the Git LFS batch API,
+// replacing each pointer file with its object's contents. Downloaded objects are verified against their pointers.
+func FetchLFSObjects(ctx context.Context, httpClient *http.Client, endpoint LFSEndpoint, ref string, pointers map[string]LFSPointer) error {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	// Collect unique objects, and the files each one replaces
+	files := make(map[string][]string)
+	var objects []LFSPointer
+	for path, pointer := range pointers {
+		if _, ok := files[pointer.OID]; !ok {
+			objects = append(objects, pointer)
+		}
+		files[pointer.OID] = append(files[pointer.OID], path)
+	}
+
+	for start := 0; start < len(objects); start += lfsBatchSize {
+		batch := objects[start:min(start+lfsBatchSize, len(objects))]
+		resp, err := requestLFSBatch(ctx, httpClient, endpoint, ref, batch)
+		if err != nil {
+			return err
+		}
+		for _, object := range resp.Objects {
+			if object.Error != nil {
+				return fmt.Errorf("failed fetching LFS object '%s': %s (%d)", object.OID, object.Error.Message, object.Error.Code)
+			}
+			download, ok := object.Actions["download"]
+			if !ok {
+				return fmt.Errorf("no download action for LFS object '%s'", object.OID)
+			}
+			if err := downloadLFSObject(ctx, httpClient, download, object.LFSPointer, files[object.OID]); err != nil {
+				return fmt.Errorf("failed fetching LFS object '%s': %w", object.OID, err)
+			}
+			delete(files, object.OID)
+		}
+	}
+	if len(files) > 0 {
+		return fmt.Errorf("%d LFS objects missing from batch responses", len(files))
+	}
+	return nil
+}
+
+// requestLFSBatch requests download actions for the given objects from the given endpoint.
+func requestLFSBatch(ctx context.Context, httpClient *http.Client, endpoint LFSEndpoint, ref string, objects []LFSPointer) (*lfsBatchResponse, error) {
+	batchRequest := lfsBatchRequest{Operation: "download", Transfers: []string{"basic"}, Objects: objects}
+	if ref != "" {
+		batchRequest.Ref = &lfsBatchRef{Name: ref}
+	}
+	body, err := json.Marshal(batchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding LFS batch request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint.URL, "/")+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed creating LFS batch request: %w", err)
+	}
+	for k, v := range endpoint.Header {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed requesting LFS batch: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LFS batch request failed with status %d", resp.StatusCode)
+	}
+
+	batchResponse := &lfsBatchResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(batchResponse); err != nil {
+		return nil, fmt.Errorf("failed decoding LFS batch response: %w", err)
+	}
+	return batchResponse, nil
+}
+
+// downloadLFSObject downloads the given object using the given download action, and writes it over the given pointer
+// files. The object is written to a temporary file first, so that pointer files are only replaced by verified objects.
+func downloadLFSObject(ctx context.Context, httpClient *http.Client, action lfsAction, pointer LFSPointer, paths []string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, action.Href, nil)
+	if err != nil {
+		return fmt.Errorf("failed creating download request: %w", err)
+	}
+	for k, v := range action.Header {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed downloading: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(paths[0]), ".lfs-*")
+	if err != nil {
+		return fmt.Errorf("failed creating temporary file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(temp, hash), resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed downloading: %w", err)
+	} else if size != pointer.Size {
+		return fmt.Errorf("expected %d bytes, got %d", pointer.Size, size)
+	} else if oid := hex.EncodeToString(hash.Sum(nil)); oid != pointer.OID {
+		return fmt.Errorf("checksum mismatch: got '%s'", oid)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed writing temporary file: %w", err)
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed inspecting '%s': %w", path, err)
+		}
+		if err := copyFile(temp.Name(), path, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed writing '%s': %w", path, err)
+		}
+	}
+	return nil
+}
+
+// copyFile copies the source file over the target file, atomically replacing it with a file of the given permissions.
+func copyFile(source, target string, perm fs.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(target), ".lfs-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	} else if err := out.Chmod(perm); err != nil {
+		return err
+	} else if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), target)
+}
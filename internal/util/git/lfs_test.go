@@ -0,0 +1,135 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func lfsPointerFor(contents string) (LFSPointer, string) {
+	hash := sha256.Sum256([]byte(contents))
+	pointer := LFSPointer{OID: hex.EncodeToString(hash[:]), Size: int64(len(contents))}
+	return pointer, fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsPointerVersion, pointer.OID, pointer.Size)
+}
+
+var _ = Describe("ParseLFSPointer", func() {
+	It("should parse valid pointers", func() {
+		expected, data := lfsPointerFor("large file")
+		pointer, ok := ParseLFSPointer([]byte(data))
+		Expect(ok).To(BeTrue())
+		Expect(pointer).To(Equal(expected))
+	})
+
+	DescribeTable("should reject non-pointers",
+		func(data string) {
+			_, ok := ParseLFSPointer([]byte(data))
+			Expect(ok).To(BeFalse())
+		},
+		Entry("regular file", "apiVersion: v1\nkind: ConfigMap\n"),
+		Entry("missing oid", lfsPointerVersion+"\nsize 10\n"),
+		Entry("missing size", lfsPointerVersion+"\noid sha256:"+fmt.Sprintf("%064d", 0)+"\n"),
+		Entry("invalid oid", lfsPointerVersion+"\noid sha256:abc\nsize 10\n"),
+	)
+})
+
+var _ = Describe("FetchLFSObjects", func() {
+	var dir string
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("should replace pointer files with their objects", func(ctx context.Context) {
+		pointer, pointerData := lfsPointerFor("large file")
+		Expect(os.MkdirAll(filepath.Join(dir, "fixtures"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "fixtures", "a.bin"), []byte(pointerData), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "fixtures", "b.bin"), []byte(pointerData), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte("resources: []\n"), 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dir, "submodule", ".git"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "submodule", "c.bin"), []byte(pointerData), 0644)).To(Succeed())
+
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/owner/repo.git/info/lfs/objects/batch":
+				Expect(r.Method).To(Equal(http.MethodPost))
+				Expect(r.Header.Get("Authorization")).To(Equal("Basic token"))
+				Expect(r.Header.Get("Accept")).To(Equal(lfsMediaType))
+				request := lfsBatchRequest{}
+				Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+				Expect(request.Operation).To(Equal("download"))
+				Expect(request.Ref).To(Equal(&lfsBatchRef{Name: "refs/heads/main"}))
+				Expect(request.Objects).To(Equal([]LFSPointer{pointer}))
+				w.Header().Set("Content-Type", lfsMediaType)
+				Expect(json.NewEncoder(w).Encode(lfsBatchResponse{Objects: []lfsBatchObject{{
+					LFSPointer: pointer,
+					Actions: map[string]lfsAction{
+						"download": {Href: server.URL + "/objects/" + pointer.OID, Header: map[string]string{"X-Object": "1"}},
+					},
+				}}})).To(Succeed())
+			case "/objects/" + pointer.OID:
+				Expect(r.Header.Get("X-Object")).To(Equal("1"))
+				_, _ = w.Write([]byte("large file"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		pointers, err := FindLFSPointers(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pointers).To(HaveLen(2))
+
+		endpoint := LFSEndpointForURL(server.URL + "/owner/repo")
+		endpoint.Header = map[string]string{"Authorization": "Basic token"}
+		Expect(FetchLFSObjects(ctx, server.Client(), endpoint, "refs/heads/main", pointers)).To(Succeed())
+
+		Expect(os.ReadFile(filepath.Join(dir, "fixtures", "a.bin"))).To(Equal([]byte("large file")))
+		Expect(os.ReadFile(filepath.Join(dir, "fixtures", "b.bin"))).To(Equal([]byte("large file")))
+		Expect(os.Stat(filepath.Join(dir, "fixtures", "b.bin"))).To(HaveField("Mode()", os.FileMode(0755)))
+		Expect(os.ReadFile(filepath.Join(dir, "submodule", "c.bin"))).To(Equal([]byte(pointerData)))
+		Expect(filepath.Glob(filepath.Join(dir, "fixtures", ".lfs-*"))).To(BeEmpty())
+	})
+
+	It("should not replace pointer files with corrupt objects", func(ctx context.Context) {
+		pointer, pointerData := lfsPointerFor("large file")
+		Expect(os.WriteFile(filepath.Join(dir, "a.bin"), []byte(pointerData), 0644)).To(Succeed())
+
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				Expect(json.NewEncoder(w).Encode(lfsBatchResponse{Objects: []lfsBatchObject{{
+					LFSPointer: pointer,
+					Actions:    map[string]lfsAction{"download": {Href: server.URL + "/object"}},
+				}}})).To(Succeed())
+			} else {
+				_, _ = w.Write([]byte("corrupt!!!"))
+			}
+		}))
+		defer server.Close()
+
+		err := FetchLFSObjects(ctx, server.Client(), LFSEndpointForURL(server.URL+"/owner/repo"), "", map[string]LFSPointer{filepath.Join(dir, "a.bin"): pointer})
+		Expect(err).To(MatchError(ContainSubstring("checksum mismatch")))
+		Expect(os.ReadFile(filepath.Join(dir, "a.bin"))).To(Equal([]byte(pointerData)))
+	})
+
+	It("should report object errors", func(ctx context.Context) {
+		pointer, _ := lfsPointerFor("large file")
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			object := lfsBatchObject{LFSPointer: pointer, Error: &lfsObjectError{Code: 404, Message: "Object does not exist"}}
+			Expect(json.NewEncoder(w).Encode(lfsBatchResponse{Objects: []lfsBatchObject{object}})).To(Succeed())
+		}))
+		defer server.Close()
+
+		err := FetchLFSObjects(ctx, server.Client(), LFSEndpointForURL(server.URL+"/owner/repo"), "", map[string]LFSPointer{filepath.Join(dir, "a.bin"): pointer})
+		Expect(err).To(MatchError(ContainSubstring("Object does not exist (404)")))
+	})
+})
@@ -5,20 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"github.com/arikkfir/command"
+	"github.com/arikkfir/devbot/internal/util/tracing"
 	"github.com/arikkfir/devbot/internal/util/version"
 	"go.opentelemetry.io/contrib/exporters/autoexport"
 	"go.opentelemetry.io/contrib/propagators/autoprop"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"os"
 )
 
 type OTelHook struct {
 	ServiceName         string
 	MetricReaderFactory func(ctx context.Context) (metric.Reader, error)
 	cleanups            []func(context.Context) error
+	span                oteltrace.Span
 }
 
 func (h *OTelHook) PreRun(ctx context.Context) error {
@@ -74,10 +79,23 @@ func (h *OTelHook) PreRun(ctx context.Context) error {
 	otel.SetMeterProvider(meterProvider)
 
 	h.cleanups = cleanups
+
+	// Continue the trace given by our parent (e.g. the controller launching this job), spanning the entire run
+	if traceParent := os.Getenv(tracing.TraceParentEnvVar); traceParent != "" {
+		_, h.span = otel.Tracer(h.ServiceName).Start(tracing.ContextWithTraceParent(ctx, traceParent), h.ServiceName)
+	}
 	return nil
 }
 
 func (h *OTelHook) PostRun(ctx context.Context, err error, _ command.ExitCode) error {
+	if h.span != nil {
+		if err != nil {
+			h.span.RecordError(err)
+			h.span.SetStatus(codes.Error, err.Error())
+		}
+		h.span.End()
+	}
+
 	var cleanupErrors error
 	for _, cleanup := range h.cleanups {
 		cleanupErrors = errors.Join(cleanupErrors, cleanup(ctx))
@@ -0,0 +1,25 @@
+package observability
+
+import (
+	"context"
+	"os"
+
+	"github.com/arikkfir/command"
+	"github.com/rs/zerolog/log"
+
+	"github.com/arikkfir/devbot/internal/util/termination"
+)
+
+// TerminationHook writes the termination message describing the error of failed commands, for the controller to
+// report.
+type TerminationHook struct{}
+
+func (h *TerminationHook) PostRun(_ context.Context, err error, _ command.ExitCode) error {
+	if err == nil {
+		return nil
+	}
+	if writeErr := os.WriteFile(termination.Path, termination.MessageOf(err).Encode(), 0644); writeErr != nil {
+		log.Warn().Err(writeErr).Msg("Failed writing termination message")
+	}
+	return nil
+}
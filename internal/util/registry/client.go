@@ -0,0 +1,182 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// manifestMediaTypes are the manifest media types accepted when checking whether an image exists.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Client checks for the existence of images in registries, using the OCI distribution API.
+type Client struct {
+	HTTPClient  *http.Client
+	Credentials map[string]Credential
+}
+
+// ImageExists checks whether the manifest of the given image exists in its registry. Registries requiring token
+// authentication are supported, using the credentials of the registry (if any) to obtain tokens.
+func (c *Client) ImageExists(ctx context.Context, ref Reference) (bool, error) {
+	scheme := "https"
+	if isInsecureRegistry(ref.Registry) {
+		scheme = "http"
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, ref.Registry, ref.Repository, ref.Reference)
+
+	resp, err := c.headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return false, err
+	}
+
+	// Authenticate & retry, if necessary
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := c.authorize(ctx, ref, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return false, err
+		}
+		if resp, err = c.headManifest(ctx, manifestURL, authorization); err != nil {
+			return false, err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %d checking image '%s'", resp.StatusCode, ref)
+	}
+}
+
+func (c *Client) headManifest(ctx context.Context, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating request for '%s': %w", manifestURL, err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed requesting '%s': %w", manifestURL, err)
+	}
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+// authorize returns the "Authorization" header value answering the given authentication challenge.
+func (c *Client) authorize(ctx context.Context, ref Reference, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	cred, hasCred := c.Credentials[ref.Registry]
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCred {
+			return "", fmt.Errorf("registry '%s' requires credentials", ref.Registry)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(cred.Username, cred.Password)
+		return req.Header.Get("Authorization"), nil
+
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", fmt.Errorf("registry '%s' sent an invalid token realm: %s", ref.Registry, params["realm"])
+		}
+		query := realm.Query()
+		if service := params["service"]; service != "" {
+			query.Set("service", service)
+		}
+		query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.Repository))
+		realm.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed creating token request for '%s': %w", realm, err)
+		}
+		if hasCred {
+			req.SetBasicAuth(cred.Username, cred.Password)
+		}
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed requesting token from '%s': %w", realm, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unexpected status %d requesting token from '%s'", resp.StatusCode, realm)
+		}
+		token := struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("failed decoding token from '%s': %w", realm, err)
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+
+	default:
+		return "", fmt.Errorf("registry '%s' requires unsupported authentication: %s", ref.Registry, challenge)
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// parseChallenge parses the given "WWW-Authenticate" header value into its scheme & parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.TrimSpace(key)
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key], rest = value[1:end+1], value[end+2:]
+		} else {
+			params[key], rest, _ = strings.Cut(value, ",")
+		}
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return scheme, params
+}
+
+// MissingImages returns the given images that do not exist (yet) in their registries.
+func (c *Client) MissingImages(ctx context.Context, images []string) ([]string, error) {
+	var missing []string
+	for _, image := range images {
+		ref, err := ParseReference(image)
+		if err != nil {
+			return nil, err
+		}
+		if exists, err := c.ImageExists(ctx, ref); err != nil {
+			return nil, fmt.Errorf("failed checking image '%s': %w", image, err)
+		} else if !exists {
+			missing = append(missing, image)
+		}
+	}
+	return missing, nil
+}
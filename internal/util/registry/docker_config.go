@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Credential is a username & password used to authenticate against a registry.
+type Credential struct {
+	Username string
+	Password string
+}
+
+// dockerConfig is the format of ".dockerconfigjson" files (e.g. in "kubernetes.io/dockerconfigjson" secrets).
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+}
+
+// LoadDockerConfigs loads the credentials from all Docker config files in the given directory (and its immediate
+// subdirectories), keyed by registry host.
+func LoadDockerConfigs(dir string) (map[string]Credential, error) {
+	var files []string
+	for _, pattern := range []string{"*.json", "*/*.json", "*/.dockerconfigjson"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("failed listing Docker config files in '%s': %w", dir, err)
+		}
+		files = append(files, matches...)
+	}
+
+	credentials := make(map[string]Credential)
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading Docker config file '%s': %w", file, err)
+		}
+		cfg := dockerConfig{}
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return nil, fmt.Errorf("failed parsing Docker config file '%s': %w", file, err)
+		}
+		for host, auth := range cfg.Auths {
+			cred := Credential{Username: auth.Username, Password: auth.Password}
+			if auth.Auth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+				if err != nil {
+					return nil, fmt.Errorf("failed decoding credentials of '%s' in Docker config file '%s': %w", host, file, err)
+				}
+				cred.Username, cred.Password, _ = strings.Cut(string(decoded), ":")
+			}
+			credentials[normalizeRegistryHost(host)] = cred
+		}
+	}
+	return credentials, nil
+}
+
+// normalizeRegistryHost strips the scheme & path from the given Docker config host key, mapping Docker Hub aliases to
+// the Docker Hub registry host.
+func normalizeRegistryHost(host string) string {
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case dockerHubDomain, "index.docker.io":
+		return dockerHubRegistry
+	default:
+		return host
+	}
+}
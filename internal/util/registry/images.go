@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// MissingImagesMessagePrefix prefixes the termination message of jobs that gave up waiting for missing images.
+const MissingImagesMessagePrefix = "Missing images: "
+
+// containerListKeys are the keys of pod spec fields listing containers.
+var containerListKeys = []string{"containers", "initContainers", "ephemeralContainers"}
+
+// ExtractImages returns the (unique, sorted) container images referenced by the resources in the given multi-document
+// YAML manifest. Container lists are found anywhere in the resources, supporting any resource embedding a pod spec
+// (e.g. Deployments, StatefulSets, CronJobs, or custom resources).
+func ExtractImages(manifest []byte) ([]string, error) {
+	var images []string
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	for {
+		var doc any
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed decoding manifest: %w", err)
+		}
+		images = collectImages(doc, images)
+	}
+	slices.Sort(images)
+	return slices.Compact(images), nil
+}
+
+func collectImages(node any, images []string) []string {
+	switch n := node.(type) {
+	case map[string]any:
+		for key, value := range n {
+			if list, ok := value.([]any); ok && slices.Contains(containerListKeys, key) {
+				for _, item := range list {
+					if container, ok := item.(map[string]any); ok {
+						if image, ok := container["image"].(string); ok && image != "" {
+							images = append(images, image)
+						}
+					}
+				}
+			}
+			images = collectImages(value, images)
+		}
+	case []any:
+		for _, item := range n {
+			images = collectImages(item, images)
+		}
+	}
+	return images
+}
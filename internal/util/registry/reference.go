@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	dockerHubDomain   = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// Reference is a parsed container image reference.
+type Reference struct {
+	Registry   string
+	Repository string
+	Reference  string
+}
+
+func (r Reference) String() string {
+	if strings.HasPrefix(r.Reference, "sha256:") {
+		return r.Registry + "/" + r.Repository + "@" + r.Reference
+	}
+	return r.Registry + "/" + r.Repository + ":" + r.Reference
+}
+
+// ParseReference parses the given image reference (e.g. "nginx", "ghcr.io/owner/app:v1" or "app@sha256:..."),
+// applying the same defaults as Docker: images without a registry are pulled from Docker Hub, official Docker Hub
+// images reside under "library/", and images without a tag or digest refer to the "latest" tag.
+func ParseReference(image string) (Reference, error) {
+	if image == "" {
+		return Reference{}, fmt.Errorf("empty image reference")
+	}
+
+	ref := Reference{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Reference = name[:i], name[i+1:]
+	}
+
+	// A tag is separated by the last colon, unless that colon belongs to the registry host's port
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i+1:], "/") {
+		if ref.Reference == "" {
+			ref.Reference = name[i+1:]
+		}
+		name = name[:i]
+	}
+	if ref.Reference == "" {
+		ref.Reference = "latest"
+	}
+
+	// The first path component is a registry host only if it looks like one
+	if i := strings.Index(name, "/"); i >= 0 && (strings.ContainsAny(name[:i], ".:") || name[:i] == "localhost") {
+		ref.Registry, ref.Repository = name[:i], name[i+1:]
+	} else {
+		ref.Registry, ref.Repository = dockerHubDomain, name
+	}
+	if ref.Registry == dockerHubDomain {
+		ref.Registry = dockerHubRegistry
+		if !strings.Contains(ref.Repository, "/") {
+			ref.Repository = "library/" + ref.Repository
+		}
+	}
+
+	if ref.Repository == "" || strings.ToLower(ref.Repository) != ref.Repository {
+		return Reference{}, fmt.Errorf("invalid image reference '%s'", image)
+	}
+	return ref, nil
+}
+
+// isInsecureRegistry reports whether the given registry host is accessed over plain HTTP. Like Docker, registries on
+// the loopback interface are considered insecure.
+func isInsecureRegistry(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
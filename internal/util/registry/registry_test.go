@@ -0,0 +1,131 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseReference", func() {
+	DescribeTable("should apply Docker defaults",
+		func(image string, expected Reference) {
+			Expect(ParseReference(image)).To(Equal(expected))
+		},
+		Entry("official image", "nginx", Reference{"registry-1.docker.io", "library/nginx", "latest"}),
+		Entry("user image with tag", "owner/app:v1", Reference{"registry-1.docker.io", "owner/app", "v1"}),
+		Entry("registry with port", "localhost:5000/app", Reference{"localhost:5000", "app", "latest"}),
+		Entry("registry with tag", "ghcr.io/owner/app:abc123", Reference{"ghcr.io", "owner/app", "abc123"}),
+		Entry("digest", "ghcr.io/owner/app:v1@sha256:0123", Reference{"ghcr.io", "owner/app", "sha256:0123"}),
+	)
+
+	It("should reject invalid references", func() {
+		_, err := ParseReference("ghcr.io/Owner/App")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ExtractImages", func() {
+	It("should extract unique images from all pod specs", func() {
+		manifest := `
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: init
+          image: busybox
+      containers:
+        - name: app
+          image: ghcr.io/owner/app:abc123
+---
+apiVersion: batch/v1
+kind: CronJob
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: job
+              image: ghcr.io/owner/app:abc123
+---
+apiVersion: v1
+kind: ConfigMap
+data:
+  image: not-an-image
+`
+		Expect(ExtractImages([]byte(manifest))).To(Equal([]string{"busybox", "ghcr.io/owner/app:abc123"}))
+	})
+})
+
+var _ = Describe("Client", func() {
+	var server *httptest.Server
+	var registry string
+	var manifests map[string]bool
+
+	BeforeEach(func() {
+		manifests = map[string]bool{"/v2/owner/app/manifests/v1": true}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			if u, p, ok := r.BasicAuth(); !ok || u != "user" || p != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			Expect(r.URL.Query().Get("scope")).To(Equal("repository:owner/app:pull"))
+			_, _ = w.Write([]byte(`{"token":"t0k3n"}`))
+		})
+		mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer t0k3n" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if manifests[r.URL.Path] {
+				w.WriteHeader(http.StatusOK)
+			} else {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+		registry = strings.TrimPrefix(server.URL, "http://")
+	})
+
+	It("should report missing images using token authentication", func() {
+		c := &Client{Credentials: map[string]Credential{registry: {Username: "user", Password: "pass"}}}
+		missing, err := c.MissingImages(context.Background(), []string{registry + "/owner/app:v1", registry + "/owner/app:v2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(missing).To(Equal([]string{registry + "/owner/app:v2"}))
+	})
+
+	It("should fail without valid credentials", func() {
+		c := &Client{}
+		_, err := c.MissingImages(context.Background(), []string{registry + "/owner/app:v1"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should load credentials from Docker config files", func() {
+		dir := GinkgoT().TempDir()
+		auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+		Expect(os.MkdirAll(filepath.Join(dir, "pull-secret"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "pull-secret", ".dockerconfigjson"), []byte(`{"auths":{"`+registry+`":{"auth":"`+auth+`"},"https://index.docker.io/v1/":{"username":"hub","password":"secret"}}}`), 0o644)).To(Succeed())
+
+		credentials, err := LoadDockerConfigs(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(map[string]Credential{
+			registry:               {Username: "user", Password: "pass"},
+			"registry-1.docker.io": {Username: "hub", Password: "secret"},
+		}))
+
+		c := &Client{Credentials: credentials}
+		Expect(c.MissingImages(context.Background(), []string{registry + "/owner/app:v1"})).To(BeEmpty())
+	})
+})
@@ -0,0 +1,104 @@
+package termination
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Path is the path Kubernetes reads the container termination message from.
+	Path = "/dev/termination-log"
+
+	// maxMessageSize is the size Kubernetes truncates container termination messages to.
+	maxMessageSize = 4096
+)
+
+// Message is the structured termination message written by failed deployment job containers.
+type Message struct {
+	// Step is the step of the job that failed, if known (e.g. "kustomize build").
+	Step string `json:"step,omitempty"`
+
+	// Error is the error the step failed with.
+	Error string `json:"error"`
+
+	// Output is the tail of the output of the failed step's command, if any.
+	Output string `json:"output,omitempty"`
+}
+
+// String returns a human-readable representation of the message, suitable for status conditions.
+func (m Message) String() string {
+	s := m.Error
+	if m.Step != "" {
+		s = m.Step + ": " + s
+	}
+	if m.Output != "" {
+		s += "\n" + m.Output
+	}
+	return s
+}
+
+// Encode encodes the message as JSON, trimming the beginning of its output (and then the end of its error) so that it
+// fits in a container termination message.
+func (m Message) Encode() []byte {
+	for {
+		data, err := json.Marshal(m)
+		if err != nil {
+			panic(fmt.Errorf("failed encoding termination message: %w", err))
+		} else if excess := len(data) - maxMessageSize; excess <= 0 {
+			return data
+		} else if len(m.Output) > 0 {
+			m.Output = strings.ToValidUTF8(m.Output[min(excess, len(m.Output)):], "")
+		} else if len(m.Error) > 0 {
+			m.Error = strings.ToValidUTF8(m.Error[:max(len(m.Error)-excess, 0)], "")
+		} else {
+			m.Step = strings.ToValidUTF8(m.Step[:max(len(m.Step)-excess, 0)], "")
+		}
+	}
+}
+
+// Parse parses the given container termination message. Messages not written as structured messages (e.g. the logs of
+// containers that failed unexpectedly) are returned as the message's error.
+func Parse(data string) Message {
+	m := Message{}
+	if err := json.Unmarshal([]byte(data), &m); err != nil || m.Error == "" {
+		return Message{Error: strings.TrimSpace(data)}
+	}
+	return m
+}
+
+// StepError is an error of a specific step of a job, optionally carrying the output of the command that failed it.
+type StepError struct {
+	Step   string
+	Output string
+	Err    error
+}
+
+// StepFailed returns a StepError for the given step, unless the given error is nil.
+func StepFailed(step string, err error, output fmt.Stringer) error {
+	if err == nil {
+		return nil
+	}
+	stepErr := &StepError{Step: step, Err: err}
+	if output != nil {
+		stepErr.Output = strings.TrimSpace(output.String())
+	}
+	return stepErr
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// MessageOf returns the termination message describing the given error.
+func MessageOf(err error) Message {
+	if stepErr := (&StepError{}); errors.As(err, &stepErr) {
+		return Message{Step: stepErr.Step, Error: stepErr.Err.Error(), Output: stepErr.Output}
+	}
+	return Message{Error: err.Error()}
+}
@@ -0,0 +1,60 @@
+package termination
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Message", func() {
+	It("should describe step errors", func() {
+		output := NewTail()
+		_, _ = fmt.Fprint(output, "Error: accumulating resources\n")
+		err := fmt.Errorf("baking: %w", StepFailed("kustomize build", errors.New("exit status 1"), output))
+		Expect(MessageOf(err)).To(Equal(Message{Step: "kustomize build", Error: "exit status 1", Output: "Error: accumulating resources"}))
+		Expect(MessageOf(errors.New("oops"))).To(Equal(Message{Error: "oops"}))
+		Expect(StepFailed("checkout", nil, nil)).To(BeNil())
+	})
+
+	It("should round-trip through encoding", func() {
+		m := Message{Step: "kubectl apply", Error: "exit status 1", Output: "error: no objects passed to apply"}
+		Expect(Parse(string(m.Encode()))).To(Equal(m))
+		Expect(m.String()).To(Equal("kubectl apply: exit status 1\nerror: no objects passed to apply"))
+	})
+
+	It("should parse unstructured messages as errors", func() {
+		Expect(Parse("panic: oops\n\ngoroutine 1 [running]:\n")).To(Equal(Message{Error: "panic: oops\n\ngoroutine 1 [running]:"}))
+		Expect(Parse(`{"level":"info"}`)).To(Equal(Message{Error: `{"level":"info"}`}))
+	})
+
+	It("should trim the beginning of long outputs to fit", func() {
+		m := Message{Step: "kustomize build", Error: "exit status 1", Output: strings.Repeat("é", maxMessageSize) + "the end"}
+		data := m.Encode()
+		Expect(len(data)).To(BeNumerically("<=", maxMessageSize))
+		parsed := Parse(string(data))
+		Expect(parsed.Error).To(Equal("exit status 1"))
+		Expect(parsed.Output).To(HaveSuffix("the end"))
+		Expect(utf8.ValidString(parsed.Output)).To(BeTrue())
+	})
+
+	It("should trim the end of long errors to fit", func() {
+		m := Message{Error: "start " + strings.Repeat("x", 2*maxMessageSize)}
+		data := m.Encode()
+		Expect(len(data)).To(BeNumerically("<=", maxMessageSize))
+		Expect(Parse(string(data)).Error).To(HavePrefix("start x"))
+	})
+})
+
+var _ = Describe("Tail", func() {
+	It("should retain only the last bytes written", func() {
+		tail := NewTail()
+		_, _ = tail.Write([]byte(strings.Repeat("a", maxMessageSize)))
+		_, _ = tail.Write([]byte("bc"))
+		Expect(tail.String()).To(HaveLen(maxMessageSize))
+		Expect(tail.String()).To(HaveSuffix("aabc"))
+	})
+})
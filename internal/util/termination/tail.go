@@ -0,0 +1,32 @@
+package termination
+
+import "sync"
+
+// Tail is a writer retaining only the last bytes written to it, e.g. to capture the end of a command's output for its
+// termination message.
+type Tail struct {
+	mu   sync.Mutex
+	size int
+	data []byte
+}
+
+// NewTail creates a writer retaining as many of the last bytes written to it as fit in a termination message.
+func NewTail() *Tail {
+	return &Tail{size: maxMessageSize}
+}
+
+func (t *Tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data = append(t.data, p...)
+	if excess := len(t.data) - t.size; excess > 0 {
+		t.data = append(t.data[:0], t.data[excess:]...)
+	}
+	return len(p), nil
+}
+
+func (t *Tail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.data)
+}
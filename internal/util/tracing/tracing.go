@@ -0,0 +1,31 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	// TraceParentEnvVar is the environment variable carrying the W3C trace context of job pods.
+	TraceParentEnvVar = "TRACEPARENT"
+
+	traceParentKey = "traceparent"
+)
+
+// TraceParent returns the W3C "traceparent" value of the span in the given context, or an empty string if the context
+// carries no valid span.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get(traceParentKey)
+}
+
+// ContextWithTraceParent returns a copy of the given context carrying the remote span described by the given W3C
+// "traceparent" value, so that spans started from it continue that trace. Invalid values are ignored.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{traceParentKey: traceParent})
+}
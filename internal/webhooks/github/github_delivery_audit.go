@@ -0,0 +1,125 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/webhooks/v6/github"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+const (
+	deliveryResultParseFailed        = "ParseFailed"
+	deliveryResultRepositoryNotFound = "RepositoryNotFound"
+	deliveryResultUnsupported        = "Unsupported"
+)
+
+// statusRecorder is an http.ResponseWriter that remembers the status code written to it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// deliveryAudit accumulates the outcome of a single webhook delivery, for each of the repositories it was matched to.
+type deliveryAudit struct {
+	receivedAt metav1.Time
+	id         string
+	event      string
+	ref        string
+	result     string
+	repos      []client.ObjectKey
+	results    map[client.ObjectKey]string
+	unverified []string
+}
+
+func newDeliveryAudit(r *http.Request) *deliveryAudit {
+	return &deliveryAudit{
+		receivedAt: metav1.Now(),
+		id:         r.Header.Get(deliveryHeader),
+		event:      r.Header.Get("X-GitHub-Event"),
+		results:    make(map[client.ObjectKey]string),
+	}
+}
+
+// setResult sets the result of the delivery for the given repository.
+func (a *deliveryAudit) setResult(repo *apiv1.Repository, result string) {
+	key := client.ObjectKeyFromObject(repo)
+	if _, ok := a.results[key]; !ok {
+		a.repos = append(a.repos, key)
+	}
+	a.results[key] = result
+}
+
+// setUnverifiedResult counts the result of the delivery for a repository whose secret could not be used to verify the
+// delivery. Since the sender is not authenticated, such results are not recorded in the repository status.
+func (a *deliveryAudit) setUnverifiedResult(result string) {
+	a.unverified = append(a.unverified, result)
+}
+
+// refOfPayload returns the branch or tag the given payload refers to, if any.
+func refOfPayload(payload any) string {
+	switch p := payload.(type) {
+	case github.PushPayload:
+		return p.Ref
+	case github.PullRequestPayload:
+		return p.PullRequest.Head.Ref
+	case github.CreatePayload:
+		return p.Ref
+	case github.DeletePayload:
+		return p.Ref
+	default:
+		return ""
+	}
+}
+
+// recordDelivery counts the given delivery by result, and records it in the status of each repository it was verified
+// for. Failures are logged but otherwise ignored, as they must not affect the response to the sender.
+func (ph *PushHandler) recordDelivery(ctx context.Context, l *zerolog.Logger, a *deliveryAudit, statusCode int) {
+	latency := time.Since(a.receivedAt.Time)
+
+	if a.result != "" {
+		WebhookDeliveries.Add(ctx, 1, metric.WithAttributes(attribute.String("event", a.event), attribute.String("result", a.result)))
+	}
+	for _, result := range a.unverified {
+		WebhookDeliveries.Add(ctx, 1, metric.WithAttributes(attribute.String("event", a.event), attribute.String("result", result)))
+	}
+
+	for _, key := range a.repos {
+		delivery := apiv1.RepositoryWebhookDelivery{
+			ID:         a.id,
+			Event:      a.event,
+			Ref:        a.ref,
+			Result:     a.results[key],
+			StatusCode: statusCode,
+			ReceivedAt: a.receivedAt,
+			Latency:    metav1.Duration{Duration: latency},
+		}
+		WebhookDeliveries.Add(ctx, 1, metric.WithAttributes(attribute.String("event", a.event), attribute.String("result", delivery.Result)))
+		err := ph.updateRepositoryStatus(ctx, key.Namespace, key.Name, func(repo *apiv1.Repository) bool {
+			repo.Status.WebhookDeliveries = append(repo.Status.WebhookDeliveries, delivery)
+			if excess := len(repo.Status.WebhookDeliveries) - apiv1.MaxWebhookDeliveries; excess > 0 {
+				repo.Status.WebhookDeliveries = repo.Status.WebhookDeliveries[excess:]
+			}
+			if repo.Status.WebhookDeliveryCounts == nil {
+				repo.Status.WebhookDeliveryCounts = make(map[string]int64)
+			}
+			repo.Status.WebhookDeliveryCounts[delivery.Result]++
+			return true
+		})
+		if err != nil {
+			l.Error().Err(err).Str("k8sRepoNamespace", key.Namespace).Str("k8sRepoName", key.Name).Msg("Failed recording webhook delivery")
+		}
+	}
+}
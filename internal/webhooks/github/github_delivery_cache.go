@@ -0,0 +1,50 @@
+package github
+
+import (
+	"sync"
+)
+
+// deliveryCache remembers a bounded number of recent webhook delivery IDs, evicting the oldest ones first.
+type deliveryCache struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newDeliveryCache(size int) *deliveryCache {
+	return &deliveryCache{ids: make(map[string]struct{}, size), order: make([]string, size)}
+}
+
+// add records the given delivery ID, returning false if it was already recorded.
+func (c *deliveryCache) add(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.ids[id]; ok {
+		return false
+	}
+	if evicted := c.order[c.next]; evicted != "" {
+		delete(c.ids, evicted)
+	}
+	c.order[c.next] = id
+	c.next = (c.next + 1) % len(c.order)
+	c.ids[id] = struct{}{}
+	return true
+}
+
+// remove forgets the given delivery ID (e.g. since handling it failed), allowing it to be redelivered.
+func (c *deliveryCache) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.ids[id]; !ok {
+		return
+	}
+	delete(c.ids, id)
+	for i, recorded := range c.order {
+		if recorded == id {
+			c.order[i] = ""
+		}
+	}
+}
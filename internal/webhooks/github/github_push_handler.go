@@ -3,9 +3,6 @@ package github
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha1"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -16,21 +13,50 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"github.com/arikkfir/devbot/internal/util/lang"
+	"github.com/arikkfir/devbot/internal/util/tracing"
 
 	"github.com/go-playground/webhooks/v6/github"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	apiv1 "github.com/arikkfir/devbot/api/v1"
 )
 
 const (
-	refreshAnnotationName = "refresh.devbot.com"
+	deliveryHeader    = "X-GitHub-Delivery"
+	deliveryCacheSize = 1024
 )
 
+var (
+	WebhooksMeter            = otel.Meter("devbot.kfirs.com/webhooks/github")
+	WebhooksTracer           = otel.Tracer("devbot.kfirs.com/webhooks/github")
+	RepositoryLookupDuration metric.Float64Histogram
+	WebhookDeliveries        metric.Int64Counter
+)
+
+func init() {
+	var err error
+
+	RepositoryLookupDuration, err = WebhooksMeter.Float64Histogram("repository_lookup_duration", metric.WithUnit("s"), metric.WithDescription("Duration of looking up Repository objects for incoming webhook events"))
+	if err != nil {
+		panic(fmt.Errorf("failed to init webhooks meter: %w", err))
+	}
+
+	WebhookDeliveries, err = WebhooksMeter.Int64Counter("webhook_deliveries", metric.WithDescription("Number of webhook deliveries received, by event & result"))
+	if err != nil {
+		panic(fmt.Errorf("failed to init webhooks meter: %w", err))
+	}
+}
+
 var (
 	ErrRepositoryNotFound       = fmt.Errorf("payload repository not found")
 	ErrNotGitHubRepository      = fmt.Errorf("repository not configured for GitHub")
@@ -45,26 +71,62 @@ var (
 type PushHandler struct {
 	client.Client
 	github.Webhook
+	deliveries *deliveryCache
 }
 
-func NewPushHandler(kubeConfig *rest.Config, s *runtime.Scheme) (*PushHandler, error) {
-	hook, err := github.New()
+func NewPushHandler(ctx context.Context, kubeConfig *rest.Config, s *runtime.Scheme) (*PushHandler, error) {
+	repositoriesCache, err := cache.New(kubeConfig, cache.Options{Scheme: s, ByObject: map[client.Object]cache.ByObject{&apiv1.Repository{}: {}}})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GitHub webhook: %w", err)
+		return nil, fmt.Errorf("failed to create Kubernetes cache: %w", err)
+	}
+	if err := repositoriesCache.IndexField(ctx, &apiv1.Repository{}, RepositoryOwnerAndNameField, IndexRepositoryByOwnerAndName); err != nil {
+		return nil, fmt.Errorf("failed to create repository index: %w", err)
+	}
+	go func() {
+		if err := repositoriesCache.Start(ctx); err != nil {
+			log.Fatal().Err(err).Msg("Kubernetes cache failed")
+		}
+	}()
+	if !repositoriesCache.WaitForCacheSync(ctx) {
+		return nil, fmt.Errorf("failed to sync Kubernetes cache")
 	}
 
-	k8sClient, err := client.New(kubeConfig, client.Options{Scheme: s})
+	// Only repositories are read from the cache; secrets are always read directly from the API server, since we might
+	// not have permission to list & watch them
+	k8sClient, err := client.New(kubeConfig, client.Options{
+		Scheme: s,
+		Cache:  &client.CacheOptions{Reader: repositoriesCache, DisableFor: []client.Object{&v12.Secret{}}},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
 	}
+	return newPushHandler(k8sClient)
+}
 
-	return &PushHandler{Client: k8sClient, Webhook: *hook}, nil
+func newPushHandler(k8sClient client.Client) (*PushHandler, error) {
+	hook, err := github.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub webhook: %w", err)
+	}
+	return &PushHandler{Client: k8sClient, Webhook: *hook, deliveries: newDeliveryCache(deliveryCacheSize)}, nil
 }
 
 func (ph *PushHandler) HandleWebhookRequest(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := WebhooksTracer.Start(r.Context(), "GitHub webhook",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("github.event", r.Header.Get("X-GitHub-Event")),
+			attribute.String("github.delivery", r.Header.Get(deliveryHeader)),
+		))
+	defer span.End()
 	l := log.Ctx(ctx)
 
+	// Record the delivery once handled
+	audit := newDeliveryAudit(r)
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = recorder
+	defer func() { ph.recordDelivery(ctx, l, audit, recorder.status) }()
+
 	// Replace request body with a Tee reader that also writes to a local buffer
 	// Restore request's original body reader upon exit (so server can close it)
 	origReqBody := r.Body
@@ -72,109 +134,228 @@ func (ph *PushHandler) HandleWebhookRequest(w http.ResponseWriter, r *http.Reque
 	payloadBody := &bytes.Buffer{}
 	r.Body = io.NopCloser(io.TeeReader(r.Body, payloadBody))
 
-	// Parse the payload
-	payload, err := ph.Parse(r, github.PushEvent, github.PingEvent)
+	// Parse the payload; "repository" events are parsed locally, since the go-playground payload cannot represent them
+	var payload any
+	var err error
+	if github.Event(r.Header.Get("X-GitHub-Event")) == github.RepositoryEvent {
+		payload, err = parseRepositoryPayload(r)
+	} else {
+		payload, err = ph.Parse(r,
+			github.PushEvent, github.PullRequestEvent, github.PingEvent, github.CreateEvent, github.DeleteEvent,
+			github.InstallationEvent, github.InstallationRepositoriesEvent)
+	}
 	if err != nil {
 		if errors.Is(err, github.ErrEventNotFound) {
 			log.Warn().Err(err).Msg("Unexpected event received - webhook configuration needs to be adjusted")
+			audit.result = deliveryResultUnsupported
 			w.WriteHeader(http.StatusNotImplemented)
 		} else {
 			log.Error().Err(err).Msg("Failed to parse GitHub webhook")
+			audit.result = deliveryResultParseFailed
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		return
 	}
+	audit.ref = refOfPayload(payload)
 
-	// Obtain repository owner and name from payload
-	var ghRepoOwner, ghRepoName string
-	if pingPayload, ok := payload.(github.PingPayload); ok {
-		ghRepoOwner, ghRepoName = pingPayload.Repository.Owner.Login, pingPayload.Repository.Name
-	} else if pushPayload, ok := payload.(github.PushPayload); ok {
-		ghRepoOwner, ghRepoName = pushPayload.Repository.Owner.Login, pushPayload.Repository.Name
-	} else {
-		log.Error().Type("payload", payload).Msg("Unsupported webhook event")
+	// Obtain repository owners and names from payload
+	refs, err := ghRepoRefsOfPayload(payload)
+	if err != nil {
+		log.Error().Err(err).Msg("Unsupported webhook event")
+		audit.result = deliveryResultUnsupported
 		w.WriteHeader(http.StatusNotImplemented)
 		return
+	} else if len(refs) > 0 {
+		l = lang.Ptr(l.With().Str("ghRepoOwner", refs[0].Owner).Str("ghRepoName", refs[0].Name).Logger())
 	}
-	l = lang.Ptr(l.With().Str("ghRepoOwner", ghRepoOwner).Str("ghRepoName", ghRepoName).Logger())
 
-	// Find corresponding Repository object
-	repo, err := ph.getRepositoryObjectForPayload(ctx, ghRepoOwner, ghRepoName)
+	// Find corresponding Repository objects
+	repos, err := ph.getRepositoryObjectsForPayload(ctx, refs...)
 	if err != nil {
 		if errors.Is(err, ErrRepositoryNotFound) {
 			l.Warn().Msg("Repository not found")
+			audit.result = deliveryResultRepositoryNotFound
 			w.WriteHeader(http.StatusNotFound)
 		} else {
-			l.Error().Err(err).Msg("Failed finding repository object")
+			l.Error().Err(err).Msg("Failed finding repository objects")
+			audit.result = apiv1.WebhookDeliveryFailed
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		return
 	}
-	l = lang.Ptr(l.With().Str("k8sRepoNamespace", repo.Namespace).Str("k8sRepoName", repo.Name).Logger())
 
-	// Fetch webhook secret this repository references
-	webhookSecret, err := ph.getRepositoryWebhookSecret(ctx, repo)
-	if err != nil {
-		l.Error().Err(err).Msg("Failed getting webhook secret")
-		w.WriteHeader(http.StatusInternalServerError)
+	// Validate webhook contents with the secret of each repository; repositories whose secret does not match the
+	// payload signature are skipped
+	var verifiedRepos []apiv1.Repository
+	failureStatus := http.StatusInternalServerError
+	for _, repo := range repos {
+		rl := l.With().Str("k8sRepoNamespace", repo.Namespace).Str("k8sRepoName", repo.Name).Logger()
+		webhookSecret, err := ph.getRepositoryWebhookSecret(ctx, &repo)
+		if err != nil {
+			rl.Error().Err(err).Msg("Failed getting webhook secret")
+			audit.setUnverifiedResult(apiv1.WebhookDeliverySecretUnavailable)
+			continue
+		}
+		if err := verifyPayloadSignature(r.Header, payloadBody.Bytes(), webhookSecret); err != nil {
+			rl.Error().Err(err).Msg("Failed verifying payload signature")
+			audit.setUnverifiedResult(apiv1.WebhookDeliveryInvalidSignature)
+			failureStatus = http.StatusBadRequest
+			continue
+		}
+		verifiedRepos = append(verifiedRepos, repo)
+	}
+	if len(verifiedRepos) == 0 {
+		w.WriteHeader(failureStatus)
 		return
 	}
 
-	// Validate webhook contents with our secret
-	signature := r.Header.Get("X-Hub-Signature")
-	if len(signature) == 0 {
-		l.Error().Msg("Empty or missing signature header 'X-Hub-Signature'")
+	// Reject replayed deliveries
+	deliveryID := r.Header.Get(deliveryHeader)
+	if deliveryID == "" {
+		l.Error().Msgf("Empty or missing delivery header '%s'", deliveryHeader)
+		for _, repo := range verifiedRepos {
+			audit.setResult(&repo, apiv1.WebhookDeliveryMissingDeliveryID)
+		}
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	mac := hmac.New(sha1.New, []byte(webhookSecret))
-	_, _ = mac.Write(payloadBody.Bytes())
-	expectedMAC := hex.EncodeToString(mac.Sum(nil))
-	actualMAC := []byte(signature[5:])
-	if !hmac.Equal(actualMAC, []byte(expectedMAC)) {
-		l.Error().Bytes("actualMAC", actualMAC).Bytes("expectedMAC", []byte(expectedMAC)).Msg("Failed verifying payload signature")
-		w.WriteHeader(http.StatusBadRequest)
+	l = lang.Ptr(l.With().Str("deliveryID", deliveryID).Logger())
+	if !ph.deliveries.add(deliveryID) {
+		l.Warn().Msg("Rejecting replayed webhook delivery")
+		for _, repo := range verifiedRepos {
+			audit.setResult(&repo, apiv1.WebhookDeliveryReplayed)
+		}
+		w.WriteHeader(http.StatusConflict)
 		return
 	}
 
-	// Detect event type and act
-	if _, ok := payload.(github.PingPayload); ok {
-		log.Info().Msg("Received ping event from GitHub")
-		w.WriteHeader(http.StatusOK)
-		return
-	} else if _, ok := payload.(github.PushPayload); ok {
-		f := func() error { return ph.annotateRepository(ctx, repo.Namespace, repo.Name) }
-		if err := retry.RetryOnConflict(retry.DefaultBackoff, f); err != nil {
-			log.Error().Err(err).Msg("Failed annotating repository for reconciliation")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+	// Act on the event for every verified repository
+	failed := false
+	for _, repo := range verifiedRepos {
+		rl := l.With().Str("k8sRepoNamespace", repo.Namespace).Str("k8sRepoName", repo.Name).Logger()
+		if err := ph.handleEvent(ctx, &rl, payload, &repo); err != nil {
+			rl.Error().Err(err).Msg("Failed handling webhook event")
+			audit.setResult(&repo, apiv1.WebhookDeliveryFailed)
+			failed = true
+		} else {
+			audit.setResult(&repo, apiv1.WebhookDeliveryAccepted)
 		}
-	} else {
-		log.Error().Type("payload", payload).Msg("Unsupported webhook event")
-		w.WriteHeader(http.StatusNotImplemented)
-		return
 	}
 
 	// TODO: fail webhook call if repository is in Invalid condition due to webhook configuration
 
-	w.WriteHeader(http.StatusOK)
+	if failed {
+		// Forget the delivery, so that GitHub's redelivery of it is not rejected as a replay
+		ph.deliveries.remove(deliveryID)
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
 }
 
-func (ph *PushHandler) getRepositoryObjectForPayload(ctx context.Context, owner, name string) (*apiv1.Repository, error) {
-	repositories := apiv1.RepositoryList{}
-	if err := ph.List(ctx, &repositories); err != nil {
-		return nil, fmt.Errorf("failed to list GitHub repositories: %w", err)
+func (ph *PushHandler) handleEvent(ctx context.Context, l *zerolog.Logger, payload any, repo *apiv1.Repository) error {
+	switch p := payload.(type) {
+	case github.PingPayload:
+		l.Info().Msg("Received ping event from GitHub")
+		return nil
+	case github.PushPayload:
+		// Apply the pushed branch revision directly to the repository status; only if the status is out of sync with
+		// the push (or a tag was pushed), request a full refresh
+		if applied, err := ph.applyPushToRepositoryStatus(ctx, repo.Namespace, repo.Name, p); err != nil {
+			return fmt.Errorf("failed applying push to repository status: %w", err)
+		} else if applied {
+			return nil
+		}
+		l.Info().Str("ref", p.Ref).Msg("Push not applicable to repository status, requesting refresh")
+		f := func() error { return ph.annotateRepository(ctx, repo.Namespace, repo.Name, true) }
+		if err := retry.RetryOnConflict(retry.DefaultBackoff, f); err != nil {
+			return fmt.Errorf("failed annotating repository for reconciliation: %w", err)
+		}
+		return nil
+	case github.PullRequestPayload:
+		// Pull requests opened, closed, reopened, synchronized or (un)labeled change the set of environments; other
+		// actions (e.g. edits or review requests) do not affect us
+		switch p.Action {
+		case "opened", "closed", "reopened", "synchronize", "labeled", "unlabeled":
+			f := func() error { return ph.annotateRepository(ctx, repo.Namespace, repo.Name, false) }
+			if err := retry.RetryOnConflict(retry.DefaultBackoff, f); err != nil {
+				return fmt.Errorf("failed annotating repository for reconciliation: %w", err)
+			}
+		default:
+			l.Debug().Str("action", p.Action).Msg("Ignoring pull request event")
+		}
+		return nil
+	case github.CreatePayload:
+		// A new branch or tag was created; request a refresh to pick it up along with its revision
+		f := func() error { return ph.annotateRepository(ctx, repo.Namespace, repo.Name, true) }
+		if err := retry.RetryOnConflict(retry.DefaultBackoff, f); err != nil {
+			return fmt.Errorf("failed annotating repository for reconciliation: %w", err)
+		}
+		return nil
+	case github.DeletePayload:
+		// A branch or tag was deleted; remove it from the repository status immediately
+		if err := ph.applyDeleteToRepositoryStatus(ctx, repo.Namespace, repo.Name, p); err != nil {
+			return fmt.Errorf("failed applying %s deletion to repository status: %w", p.RefType, err)
+		}
+		return nil
+	case RepositoryPayload:
+		// Default branch changes, renames & transfers are applied to the repository status immediately; other
+		// actions (e.g. archiving or visibility changes) are picked up by a full refresh
+		switch p.Action {
+		case "edited", "renamed", "transferred":
+			if err := ph.applyRepositoryEventToRepositoryStatus(ctx, repo.Namespace, repo.Name, p); err != nil {
+				return fmt.Errorf("failed applying repository event to repository status: %w", err)
+			}
+		default:
+			f := func() error { return ph.annotateRepository(ctx, repo.Namespace, repo.Name, false) }
+			if err := retry.RetryOnConflict(retry.DefaultBackoff, f); err != nil {
+				return fmt.Errorf("failed annotating repository for reconciliation: %w", err)
+			}
+		}
+		return nil
+	case github.InstallationPayload, github.InstallationRepositoriesPayload:
+		// Installation changes (only sent to GitHub App webhooks) may grant or revoke our access to the repository
+		f := func() error { return ph.annotateRepository(ctx, repo.Namespace, repo.Name, false) }
+		if err := retry.RetryOnConflict(retry.DefaultBackoff, f); err != nil {
+			return fmt.Errorf("failed annotating repository for reconciliation: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported webhook event: %T", payload)
 	}
+}
 
-	for _, r := range repositories.Items {
-		if r.Spec.GitHub != nil {
-			if r.Spec.GitHub.Owner == owner && r.Spec.GitHub.Name == name {
-				return &r, nil
+// getRepositoryObjectsForPayload returns all Repository objects tracking any of the given GitHub repositories, possibly
+// across multiple namespaces. Each Repository object is returned once, even if it matches multiple given repositories.
+func (ph *PushHandler) getRepositoryObjectsForPayload(ctx context.Context, refs ...ghRepoRef) (repos []apiv1.Repository, err error) {
+	start := time.Now()
+	defer func() {
+		result := "found"
+		if errors.Is(err, ErrRepositoryNotFound) {
+			result = "not_found"
+		} else if err != nil {
+			result = "error"
+		}
+		RepositoryLookupDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attribute.String("result", result)))
+	}()
+
+	seen := make(map[client.ObjectKey]bool)
+	for _, ref := range refs {
+		repositories := apiv1.RepositoryList{}
+		if err := ph.List(ctx, &repositories, client.MatchingFields{RepositoryOwnerAndNameField: repositoryOwnerAndNameKey(ref.Owner, ref.Name)}); err != nil {
+			return nil, fmt.Errorf("failed to list GitHub repositories: %w", err)
+		}
+		for _, repo := range repositories.Items {
+			if key := client.ObjectKeyFromObject(&repo); !seen[key] {
+				seen[key] = true
+				repos = append(repos, repo)
 			}
 		}
 	}
-
-	return nil, ErrRepositoryNotFound
+	if len(repos) == 0 {
+		return nil, ErrRepositoryNotFound
+	}
+	return repos, nil
 }
 
 func (ph *PushHandler) getRepositoryWebhookSecret(ctx context.Context, repo *apiv1.Repository) (string, error) {
@@ -218,7 +399,10 @@ func (ph *PushHandler) getRepositoryWebhookSecret(ctx context.Context, repo *api
 	return string(secretValue), nil
 }
 
-func (ph *PushHandler) annotateRepository(ctx context.Context, namespace, name string) error {
+// annotateRepository requests a refresh of the given repository. If continueTrace is true, the trace context of the given
+// context is recorded as well, so that deployments of revisions discovered by the refresh continue its trace; it should
+// only be set for events that may introduce new revisions.
+func (ph *PushHandler) annotateRepository(ctx context.Context, namespace, name string, continueTrace bool) error {
 	repo := &apiv1.Repository{}
 	if err := ph.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, repo); err != nil {
 		return err
@@ -228,6 +412,32 @@ func (ph *PushHandler) annotateRepository(ctx context.Context, namespace, name s
 		repo.ObjectMeta.Annotations = map[string]string{}
 	}
 
-	repo.ObjectMeta.Annotations[refreshAnnotationName] = time.Now().String()
+	// A full refresh supersedes any pending refresh of specific branches
+	repo.ObjectMeta.Annotations[apiv1.RefreshAnnotation] = time.Now().String()
+	delete(repo.ObjectMeta.Annotations, apiv1.RefreshBranchesAnnotation)
+	if traceParent := tracing.TraceParent(ctx); continueTrace && traceParent != "" {
+		repo.ObjectMeta.Annotations[apiv1.TraceParentAnnotation] = traceParent
+	}
 	return client.IgnoreNotFound(ph.Update(ctx, repo))
 }
+
+// annotateTraceParent records the trace context of the given context on the given repository (if it is not already
+// recorded), so that deployments of revisions introduced by the current webhook delivery continue its trace. The given
+// repository's resource version is updated, allowing it to be updated further.
+func (ph *PushHandler) annotateTraceParent(ctx context.Context, repo *apiv1.Repository) error {
+	traceParent := tracing.TraceParent(ctx)
+	if traceParent == "" || repo.Annotations[apiv1.TraceParentAnnotation] == traceParent {
+		return nil
+	}
+
+	annotated := repo.DeepCopy()
+	if annotated.Annotations == nil {
+		annotated.Annotations = map[string]string{}
+	}
+	annotated.Annotations[apiv1.TraceParentAnnotation] = traceParent
+	if err := ph.Patch(ctx, annotated, client.MergeFrom(repo)); err != nil {
+		return err
+	}
+	repo.Annotations, repo.ResourceVersion = annotated.Annotations, annotated.ResourceVersion
+	return nil
+}
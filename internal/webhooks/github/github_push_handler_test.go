@@ -0,0 +1,486 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+const (
+	webhookSecret = "s3cr3t"
+	pushPayload   = `{"ref":"refs/heads/main","before":"0000000000000000000000000000000000000000","after":"1111111111111111111111111111111111111111","repository":{"name":"name","owner":{"login":"owner"}}}`
+	pushedSHA     = "1111111111111111111111111111111111111111"
+)
+
+func sign(h func() hash.Hash, prefix, secret string, payload []byte) string {
+	mac := hmac.New(h, []byte(secret))
+	_, _ = mac.Write(payload)
+	return prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookSecret(namespace, secret string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "webhook"},
+		Data:       map[string][]byte{"secret": []byte(secret)},
+	}
+}
+
+func newRepository(namespace, name, owner, ghName string) *apiv1.Repository {
+	return &apiv1.Repository{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: apiv1.RepositorySpec{
+			GitHub: &apiv1.GitHubRepositorySpec{
+				Owner: owner,
+				Name:  ghName,
+				WebhookSecret: &apiv1.GitHubRepositoryWebhookSecret{
+					Secret: apiv1.SecretReferenceWithOptionalNamespace{Name: "webhook"},
+					Key:    "secret",
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("PushHandler", func() {
+	var k8sClient client.Client
+	var handler *PushHandler
+	var deliveryCounter int
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(apiv1.AddToScheme(scheme)).To(Succeed())
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&apiv1.Repository{}, RepositoryOwnerAndNameField, IndexRepositoryByOwnerAndName).
+			WithStatusSubresource(&apiv1.Repository{}).
+			WithObjects(
+				newWebhookSecret("ns", webhookSecret),
+				newRepository("ns", "repo", "owner", "name"),
+				newWebhookSecret("other-ns", webhookSecret),
+				newRepository("other-ns", "repo", "Owner", "Name"),
+				newWebhookSecret("foreign-ns", "another-secret"),
+				newRepository("foreign-ns", "repo", "owner", "name"),
+				newRepository("ns", "unrelated", "owner", "unrelated"),
+			).
+			Build()
+
+		var err error
+		handler, err = newPushHandler(k8sClient)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	newRequest := func(headers map[string]string) *http.Request {
+		deliveryCounter++
+		r := httptest.NewRequest(http.MethodPost, "/github/webhook", bytes.NewBufferString(pushPayload))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-GitHub-Event", "push")
+		r.Header.Set(deliveryHeader, fmt.Sprintf("delivery-%d", deliveryCounter))
+		for k, v := range headers {
+			if v == "" {
+				r.Header.Del(k)
+			} else {
+				r.Header.Set(k, v)
+			}
+		}
+		return r
+	}
+
+	handle := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		handler.HandleWebhookRequest(w, r)
+		return w.Code
+	}
+
+	mainRevisionOf := func(namespace, name string) string {
+		repo := &apiv1.Repository{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, repo)).To(Succeed())
+		return repo.Status.Revisions["main"]
+	}
+	mainRevision := func() string { return mainRevisionOf("ns", "repo") }
+
+	It("should accept valid SHA-256 signatures", func() {
+		Expect(handle(newRequest(map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", webhookSecret, []byte(pushPayload)),
+		}))).To(Equal(http.StatusOK))
+		Expect(mainRevision()).To(Equal(pushedSHA))
+	})
+
+	It("should accept valid legacy SHA-1 signatures", func() {
+		Expect(handle(newRequest(map[string]string{
+			signatureSHA1Header: sign(sha1.New, "sha1=", webhookSecret, []byte(pushPayload)),
+		}))).To(Equal(http.StatusOK))
+		Expect(mainRevision()).To(Equal(pushedSHA))
+	})
+
+	It("should prefer SHA-256 signatures over SHA-1 signatures", func() {
+		Expect(handle(newRequest(map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", "wrong", []byte(pushPayload)),
+			signatureSHA1Header:   sign(sha1.New, "sha1=", webhookSecret, []byte(pushPayload)),
+		}))).To(Equal(http.StatusBadRequest))
+		Expect(mainRevision()).To(BeEmpty())
+	})
+
+	DescribeTable("should reject invalid signatures",
+		func(headers map[string]string) {
+			Expect(handle(newRequest(headers))).To(Equal(http.StatusBadRequest))
+			Expect(mainRevision()).To(BeEmpty())
+		},
+		Entry("missing", map[string]string{}),
+		Entry("SHA-256 without prefix", map[string]string{signatureSHA256Header: "abc"}),
+		Entry("SHA-256 with wrong prefix", map[string]string{signatureSHA256Header: sign(sha256.New, "sha1=", webhookSecret, []byte(pushPayload))}),
+		Entry("SHA-256 not hex", map[string]string{signatureSHA256Header: "sha256=zzzz"}),
+		Entry("SHA-256 mismatch", map[string]string{signatureSHA256Header: sign(sha256.New, "sha256=", "wrong", []byte(pushPayload))}),
+		Entry("SHA-1 without prefix", map[string]string{signatureSHA1Header: "a"}),
+		Entry("SHA-1 mismatch", map[string]string{signatureSHA1Header: sign(sha1.New, "sha1=", "wrong", []byte(pushPayload))}),
+	)
+
+	It("should fan out events to all repositories whose secret matches the signature", func() {
+		Expect(handle(newRequest(map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", webhookSecret, []byte(pushPayload)),
+		}))).To(Equal(http.StatusOK))
+		Expect(mainRevisionOf("ns", "repo")).To(Equal(pushedSHA))
+		Expect(mainRevisionOf("other-ns", "repo")).To(Equal(pushedSHA))
+		Expect(mainRevisionOf("foreign-ns", "repo")).To(BeEmpty())
+		Expect(mainRevisionOf("ns", "unrelated")).To(BeEmpty())
+	})
+
+	It("should reject deliveries without a delivery ID", func() {
+		Expect(handle(newRequest(map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", webhookSecret, []byte(pushPayload)),
+			deliveryHeader:        "",
+		}))).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should reject replayed deliveries", func() {
+		headers := map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", webhookSecret, []byte(pushPayload)),
+			deliveryHeader:        "replayed",
+		}
+		Expect(handle(newRequest(headers))).To(Equal(http.StatusOK))
+		Expect(handle(newRequest(headers))).To(Equal(http.StatusConflict))
+	})
+
+	It("should accept redeliveries of failed deliveries", func() {
+		failures := 1
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(apiv1.AddToScheme(scheme)).To(Succeed())
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&apiv1.Repository{}, RepositoryOwnerAndNameField, IndexRepositoryByOwnerAndName).
+			WithStatusSubresource(&apiv1.Repository{}).
+			WithObjects(newWebhookSecret("ns", webhookSecret), newRepository("ns", "repo", "owner", "name")).
+			WithInterceptorFuncs(interceptor.Funcs{
+				SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+					if failures > 0 && len(obj.(*apiv1.Repository).Status.WebhookDeliveries) == 0 {
+						failures--
+						return fmt.Errorf("simulated failure")
+					}
+					return c.SubResource(subResource).Update(ctx, obj, opts...)
+				},
+			}).
+			Build()
+		var err error
+		handler, err = newPushHandler(k8sClient)
+		Expect(err).NotTo(HaveOccurred())
+
+		headers := map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", webhookSecret, []byte(pushPayload)),
+			deliveryHeader:        "redelivered",
+		}
+		Expect(handle(newRequest(headers))).To(Equal(http.StatusInternalServerError))
+		Expect(mainRevision()).To(BeEmpty())
+		Expect(handle(newRequest(headers))).To(Equal(http.StatusOK))
+		Expect(mainRevision()).To(Equal(pushedSHA))
+		Expect(handle(newRequest(headers))).To(Equal(http.StatusConflict))
+	})
+
+	It("should not remember deliveries with invalid signatures", func() {
+		Expect(handle(newRequest(map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", "wrong", []byte(pushPayload)),
+			deliveryHeader:        "forged",
+		}))).To(Equal(http.StatusBadRequest))
+		Expect(handle(newRequest(map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", webhookSecret, []byte(pushPayload)),
+			deliveryHeader:        "forged",
+		}))).To(Equal(http.StatusOK))
+	})
+	It("should record deliveries in the status of matching repositories", func() {
+		Expect(handle(newRequest(map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", webhookSecret, []byte(pushPayload)),
+			deliveryHeader:        "audited",
+		}))).To(Equal(http.StatusOK))
+
+		repo := &apiv1.Repository{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "repo"}, repo)).To(Succeed())
+		Expect(repo.Status.WebhookDeliveries).To(HaveLen(1))
+		Expect(repo.Status.WebhookDeliveries[0].ID).To(Equal("audited"))
+		Expect(repo.Status.WebhookDeliveries[0].Event).To(Equal("push"))
+		Expect(repo.Status.WebhookDeliveries[0].Ref).To(Equal("refs/heads/main"))
+		Expect(repo.Status.WebhookDeliveries[0].Result).To(Equal(apiv1.WebhookDeliveryAccepted))
+		Expect(repo.Status.WebhookDeliveries[0].StatusCode).To(Equal(http.StatusOK))
+		Expect(repo.Status.WebhookDeliveryCounts).To(Equal(map[string]int64{apiv1.WebhookDeliveryAccepted: 1}))
+
+		foreign := &apiv1.Repository{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "foreign-ns", Name: "repo"}, foreign)).To(Succeed())
+		Expect(foreign.Status.WebhookDeliveries).To(BeEmpty())
+
+		unrelated := &apiv1.Repository{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "unrelated"}, unrelated)).To(Succeed())
+		Expect(unrelated.Status.WebhookDeliveries).To(BeEmpty())
+	})
+
+	It("should not record unverified deliveries in repository status", func() {
+		Expect(handle(newRequest(map[string]string{
+			signatureSHA256Header: sign(sha256.New, "sha256=", "wrong", []byte(pushPayload)),
+		}))).To(Equal(http.StatusBadRequest))
+
+		repo := &apiv1.Repository{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "repo"}, repo)).To(Succeed())
+		Expect(repo.Status.WebhookDeliveries).To(BeEmpty())
+		Expect(repo.Status.WebhookDeliveryCounts).To(BeEmpty())
+	})
+
+	It("should retain only the most recent deliveries", func() {
+		headers := map[string]string{signatureSHA256Header: sign(sha256.New, "sha256=", webhookSecret, []byte(pushPayload))}
+		for i := 0; i < apiv1.MaxWebhookDeliveries+5; i++ {
+			Expect(handle(newRequest(headers))).To(Equal(http.StatusOK))
+		}
+		headers[deliveryHeader] = "last"
+		Expect(handle(newRequest(headers))).To(Equal(http.StatusOK))
+		Expect(handle(newRequest(headers))).To(Equal(http.StatusConflict))
+
+		repo := &apiv1.Repository{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "repo"}, repo)).To(Succeed())
+		Expect(repo.Status.WebhookDeliveries).To(HaveLen(apiv1.MaxWebhookDeliveries))
+		Expect(repo.Status.WebhookDeliveries[apiv1.MaxWebhookDeliveries-1].Result).To(Equal(apiv1.WebhookDeliveryReplayed))
+		Expect(repo.Status.WebhookDeliveryCounts).To(Equal(map[string]int64{
+			apiv1.WebhookDeliveryAccepted: int64(apiv1.MaxWebhookDeliveries + 6),
+			apiv1.WebhookDeliveryReplayed: 1,
+		}))
+	})
+})
+
+var _ = Describe("deliveryCache", func() {
+	It("should evict oldest deliveries when full", func() {
+		c := newDeliveryCache(2)
+		Expect(c.add("1")).To(BeTrue())
+		Expect(c.add("2")).To(BeTrue())
+		Expect(c.add("1")).To(BeFalse())
+		Expect(c.add("3")).To(BeTrue())
+		Expect(c.add("2")).To(BeFalse())
+		Expect(c.add("1")).To(BeTrue())
+	})
+
+	It("should forget removed deliveries", func() {
+		c := newDeliveryCache(2)
+		Expect(c.add("1")).To(BeTrue())
+		c.remove("1")
+		Expect(c.add("1")).To(BeTrue())
+		Expect(c.add("2")).To(BeTrue())
+		Expect(c.add("1")).To(BeFalse())
+	})
+})
+
+var _ = Describe("PushHandler push events", func() {
+	var k8sClient client.Client
+	var handler *PushHandler
+	var deliveryCounter int
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(apiv1.AddToScheme(scheme)).To(Succeed())
+		repo := newRepository("ns", "repo", "owner", "name")
+		repo.Status.Revisions = map[string]string{"main": "aaaa", "feature": "bbbb"}
+		repo.Status.Tags = map[string]string{"v1.0.0": "cccc"}
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&apiv1.Repository{}, RepositoryOwnerAndNameField, IndexRepositoryByOwnerAndName).
+			WithStatusSubresource(&apiv1.Repository{}).
+			WithObjects(newWebhookSecret("ns", webhookSecret), repo).
+			Build()
+
+		var err error
+		handler, err = newPushHandler(k8sClient)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	push := func(ref, before, after string, deleted bool) int {
+		deliveryCounter++
+		payload := []byte(fmt.Sprintf(`{"ref":%q,"before":%q,"after":%q,"deleted":%t,"repository":{"name":"name","owner":{"login":"owner"}}}`, ref, before, after, deleted))
+		r := httptest.NewRequest(http.MethodPost, "/github/webhook", bytes.NewBuffer(payload))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-GitHub-Event", "push")
+		r.Header.Set(deliveryHeader, fmt.Sprintf("push-delivery-%d", deliveryCounter))
+		r.Header.Set(signatureSHA256Header, sign(sha256.New, "sha256=", webhookSecret, payload))
+		w := httptest.NewRecorder()
+		handler.HandleWebhookRequest(w, r)
+		return w.Code
+	}
+
+	getRepo := func() *apiv1.Repository {
+		repo := &apiv1.Repository{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "repo"}, repo)).To(Succeed())
+		return repo
+	}
+
+	It("should update the pushed branch revision", func() {
+		Expect(push("refs/heads/main", "aaaa", "dddd", false)).To(Equal(http.StatusOK))
+		repo := getRepo()
+		Expect(repo.Status.Revisions).To(Equal(map[string]string{"main": "dddd", "feature": "bbbb"}))
+		Expect(repo.Annotations).NotTo(HaveKey(apiv1.RefreshAnnotation))
+	})
+
+	It("should add created branches", func() {
+		Expect(push("refs/heads/new", nullSHA, "eeee", false)).To(Equal(http.StatusOK))
+		Expect(getRepo().Status.Revisions).To(HaveKeyWithValue("new", "eeee"))
+	})
+
+	It("should remove deleted branches", func() {
+		Expect(push("refs/heads/feature", "bbbb", nullSHA, true)).To(Equal(http.StatusOK))
+		Expect(getRepo().Status.Revisions).To(Equal(map[string]string{"main": "aaaa"}))
+	})
+
+	It("should request a refresh for pushed tags", func() {
+		Expect(push("refs/tags/v1.1.0", nullSHA, "ffff", false)).To(Equal(http.StatusOK))
+		repo := getRepo()
+		Expect(repo.Status.Tags).To(Equal(map[string]string{"v1.0.0": "cccc"}))
+		Expect(repo.Annotations).To(HaveKey(apiv1.RefreshAnnotation))
+	})
+
+	It("should request a refresh when the status is out of sync", func() {
+		Expect(push("refs/heads/main", "9999", "dddd", false)).To(Equal(http.StatusOK))
+		repo := getRepo()
+		Expect(repo.Status.Revisions).To(HaveKeyWithValue("main", "aaaa"))
+		Expect(repo.Annotations).To(HaveKey(apiv1.RefreshAnnotation))
+	})
+})
+
+var _ = Describe("PushHandler repository events", func() {
+	var k8sClient client.Client
+	var handler *PushHandler
+	var deliveryCounter int
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(apiv1.AddToScheme(scheme)).To(Succeed())
+		repo := newRepository("ns", "repo", "owner", "name")
+		repo.Status.DefaultBranch = "main"
+		repo.Status.ResolvedName = "owner/name"
+		repo.Status.Revisions = map[string]string{"main": "aaaa", "feature": "bbbb"}
+		repo.Status.Tags = map[string]string{"v1.0.0": "cccc"}
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&apiv1.Repository{}, RepositoryOwnerAndNameField, IndexRepositoryByOwnerAndName).
+			WithStatusSubresource(&apiv1.Repository{}).
+			WithObjects(newWebhookSecret("ns", webhookSecret), repo).
+			Build()
+
+		var err error
+		handler, err = newPushHandler(k8sClient)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	send := func(event, payload string) int {
+		deliveryCounter++
+		r := httptest.NewRequest(http.MethodPost, "/github/webhook", bytes.NewBufferString(payload))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-GitHub-Event", event)
+		r.Header.Set(deliveryHeader, fmt.Sprintf("repository-delivery-%d", deliveryCounter))
+		r.Header.Set(signatureSHA256Header, sign(sha256.New, "sha256=", webhookSecret, []byte(payload)))
+		w := httptest.NewRecorder()
+		handler.HandleWebhookRequest(w, r)
+		return w.Code
+	}
+
+	getRepo := func() *apiv1.Repository {
+		repo := &apiv1.Repository{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "repo"}, repo)).To(Succeed())
+		return repo
+	}
+
+	It("should request a refresh when a branch is created", func() {
+		Expect(send("create", `{"ref":"new","ref_type":"branch","repository":{"name":"name","owner":{"login":"owner"}}}`)).To(Equal(http.StatusOK))
+		Expect(getRepo().Annotations).To(HaveKey(apiv1.RefreshAnnotation))
+	})
+
+	It("should remove deleted branches", func() {
+		Expect(send("delete", `{"ref":"feature","ref_type":"branch","repository":{"name":"name","owner":{"login":"owner"}}}`)).To(Equal(http.StatusOK))
+		Expect(getRepo().Status.Revisions).To(Equal(map[string]string{"main": "aaaa"}))
+	})
+
+	It("should remove deleted tags", func() {
+		Expect(send("delete", `{"ref":"v1.0.0","ref_type":"tag","repository":{"name":"name","owner":{"login":"owner"}}}`)).To(Equal(http.StatusOK))
+		repo := getRepo()
+		Expect(repo.Status.Tags).To(BeEmpty())
+		Expect(repo.Status.Revisions).To(HaveLen(2))
+	})
+
+	It("should update the default branch", func() {
+		Expect(send("repository", `{"action":"edited","changes":{"default_branch":{"from":"main"}},"repository":{"name":"name","full_name":"owner/name","default_branch":"develop","owner":{"login":"owner"}}}`)).To(Equal(http.StatusOK))
+		repo := getRepo()
+		Expect(repo.Status.DefaultBranch).To(Equal("develop"))
+		Expect(repo.Status.IsInvalid()).To(BeFalse())
+	})
+
+	It("should flag renamed repositories as invalid", func() {
+		Expect(send("repository", `{"action":"renamed","changes":{"repository":{"name":{"from":"name"}}},"repository":{"name":"renamed","full_name":"owner/renamed","default_branch":"main","owner":{"login":"owner"}}}`)).To(Equal(http.StatusOK))
+		repo := getRepo()
+		Expect(repo.Status.ResolvedName).To(Equal("owner/renamed"))
+		Expect(repo.Status.GetInvalidReason()).To(Equal(apiv1.RepositoryMoved))
+	})
+
+	It("should flag transferred repositories as invalid", func() {
+		Expect(send("repository", `{"action":"transferred","changes":{"owner":{"from":{"organization":{"login":"owner"}}}},"repository":{"name":"name","full_name":"new-owner/name","default_branch":"main","owner":{"login":"new-owner"}}}`)).To(Equal(http.StatusOK))
+		repo := getRepo()
+		Expect(repo.Status.ResolvedName).To(Equal("new-owner/name"))
+		Expect(repo.Status.GetInvalidReason()).To(Equal(apiv1.RepositoryMoved))
+	})
+
+	DescribeTable("should request a refresh upon pull requests changing environments",
+		func(action string, refresh bool) {
+			payload := fmt.Sprintf(`{"action":%q,"number":1,"pull_request":{"number":1,"head":{"ref":"feature"}},"repository":{"name":"name","owner":{"login":"owner"}}}`, action)
+			Expect(send("pull_request", payload)).To(Equal(http.StatusOK))
+			if refresh {
+				Expect(getRepo().Annotations).To(HaveKey(apiv1.RefreshAnnotation))
+			} else {
+				Expect(getRepo().Annotations).NotTo(HaveKey(apiv1.RefreshAnnotation))
+			}
+		},
+		Entry("opened", "opened", true),
+		Entry("closed", "closed", true),
+		Entry("reopened", "reopened", true),
+		Entry("synchronized", "synchronize", true),
+		Entry("labeled", "labeled", true),
+		Entry("unlabeled", "unlabeled", true),
+		Entry("edited", "edited", false),
+		Entry("review requested", "review_requested", false),
+	)
+
+	It("should request a refresh of repositories in installation events", func() {
+		Expect(send("installation", `{"action":"created","repositories":[{"name":"name","full_name":"owner/name"},{"name":"other","full_name":"owner/other"}]}`)).To(Equal(http.StatusOK))
+		Expect(getRepo().Annotations).To(HaveKey(apiv1.RefreshAnnotation))
+	})
+})
@@ -0,0 +1,74 @@
+package github
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-playground/webhooks/v6/github"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+const (
+	branchRefPrefix = "refs/heads/"
+	nullSHA         = "0000000000000000000000000000000000000000"
+)
+
+// applyPushToRepositoryStatus updates the revision of the pushed branch in the status of the given repository, based on
+// the push payload. The update is only applied if the revision currently recorded in the status matches the payload's
+// "before" revision; otherwise the status is out of sync (e.g. a missed or out-of-order delivery) and false is
+// returned, signaling that a full refresh is necessary. Conflicting concurrent updates are retried.
+//
+// Pushes of other refs (e.g. tags) are never applied, since their payload revisions may not be commits (the revision
+// of an annotated tag is that of the tag object); false is returned for them too, so the refresh resolves their commits.
+func (ph *PushHandler) applyPushToRepositoryStatus(ctx context.Context, namespace, name string, payload github.PushPayload) (bool, error) {
+	if !strings.HasPrefix(payload.Ref, branchRefPrefix) {
+		return false, nil
+	}
+	refName := strings.TrimPrefix(payload.Ref, branchRefPrefix)
+
+	applied := false
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		applied = false
+
+		repo := &apiv1.Repository{}
+		if err := ph.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, repo); err != nil {
+			return err
+		}
+
+		refs := &repo.Status.Revisions
+		current, exists := (*refs)[refName]
+		if payload.Deleted {
+			if !exists {
+				applied = true
+				return nil
+			} else if current != payload.Before {
+				return nil
+			}
+			delete(*refs, refName)
+		} else {
+			if current == payload.After {
+				applied = true
+				return nil
+			} else if (exists && current != payload.Before) || (!exists && payload.Before != nullSHA && payload.Before != "") {
+				return nil
+			}
+			if *refs == nil {
+				*refs = make(map[string]string)
+			}
+			(*refs)[refName] = payload.After
+		}
+
+		if err := ph.annotateTraceParent(ctx, repo); err != nil {
+			return err
+		}
+		if err := ph.Status().Update(ctx, repo); err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, client.IgnoreNotFound(err)
+}
@@ -0,0 +1,244 @@
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	v12 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/arikkfir/devbot/internal/util/lang"
+	"github.com/arikkfir/devbot/internal/util/tracing"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+const (
+	refreshDeliveryHeader  = "X-Devbot-Delivery"
+	refreshSignatureHeader = "X-Devbot-Signature-256"
+	refreshEvent           = "refresh"
+	maxRefreshRequestSize  = 64 * 1024
+)
+
+var (
+	ErrRefreshUnauthorized           = fmt.Errorf("refresh request carries no valid bearer token or signature")
+	ErrRefreshSecretNotFound         = fmt.Errorf("refresh webhook secret not found")
+	ErrRefreshSecretKeyNotFound      = fmt.Errorf("refresh webhook secret key not found in secret")
+	ErrRefreshSecretIsEmpty          = fmt.Errorf("refresh webhook secret is empty")
+	ErrRefreshRequestBranchMalformed = fmt.Errorf("refresh request branch is malformed")
+
+	branchRE = regexp.MustCompile(`^[a-zA-Z0-9_.-]+(/[a-zA-Z0-9_.-]+)*$`)
+)
+
+// RefreshRequest is the (optional) JSON body of refresh webhook requests. If a branch is given, only that branch is
+// refreshed; otherwise, a full refresh of the repository is requested.
+type RefreshRequest struct {
+	Branch string `json:"branch,omitempty"`
+}
+
+// validate verifies the request is well-formed.
+func (rr *RefreshRequest) validate() error {
+	if rr.Branch != "" && !branchRE.MatchString(rr.Branch) {
+		return ErrRefreshRequestBranchMalformed
+	}
+	return nil
+}
+
+// HandleRefreshRequest handles requests to the generic refresh webhook (e.g. "POST /refresh/{namespace}/{repository}"),
+// used by CI systems to trigger a refresh of a repository. Requests are authenticated with the secret referenced by the
+// repository's refresh webhook configuration, either as a bearer token or as an HMAC-SHA256 signature of the body.
+func (ph *PushHandler) HandleRefreshRequest(w http.ResponseWriter, r *http.Request) {
+	ctx, span := WebhooksTracer.Start(r.Context(), "Refresh webhook", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	namespace, name := r.PathValue("namespace"), r.PathValue("repository")
+	l := lang.Ptr(log.Ctx(ctx).With().Str("k8sRepoNamespace", namespace).Str("k8sRepoName", name).Logger())
+
+	// Record the delivery once handled
+	audit := newDeliveryAudit(r)
+	audit.id, audit.event = r.Header.Get(refreshDeliveryHeader), refreshEvent
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = recorder
+	defer func() { ph.recordDelivery(ctx, l, audit, recorder.status) }()
+
+	// Find the repository; repositories without a refresh webhook are treated as missing, so as not to disclose them
+	repo := &apiv1.Repository{}
+	if err := ph.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, repo); err != nil {
+		if apierrors.IsNotFound(err) {
+			l.Warn().Msg("Repository not found")
+			audit.result = deliveryResultRepositoryNotFound
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			l.Error().Err(err).Msg("Failed getting repository")
+			audit.result = apiv1.WebhookDeliveryFailed
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	} else if repo.Spec.RefreshWebhook == nil {
+		l.Warn().Msg("Refresh webhook not enabled for repository")
+		audit.result = deliveryResultRepositoryNotFound
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Read the request body
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRefreshRequestSize))
+	if err != nil {
+		l.Error().Err(err).Msg("Failed reading refresh request body")
+		audit.setUnverifiedResult(apiv1.WebhookDeliveryInvalidRequest)
+		if maxBytesErr := (&http.MaxBytesError{}); errors.As(err, &maxBytesErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		return
+	}
+
+	// Authenticate the request
+	secret, err := ph.getRepositoryRefreshSecret(ctx, repo)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed getting refresh webhook secret")
+		audit.setUnverifiedResult(apiv1.WebhookDeliverySecretUnavailable)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	} else if err := verifyRefreshRequest(r.Header, body, secret); err != nil {
+		l.Warn().Err(err).Msg("Rejecting unauthorized refresh request")
+		audit.setUnverifiedResult(apiv1.WebhookDeliveryInvalidSignature)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Continue the trace of the caller, if given (e.g. a CI pipeline); only done once the caller is authenticated, since
+	// the trace context is recorded on the repository & propagated to deployments
+	if callerCtx := (propagation.TraceContext{}).Extract(ctx, propagation.HeaderCarrier(r.Header)); trace.SpanContextFromContext(callerCtx).IsRemote() {
+		var refreshSpan trace.Span
+		ctx, refreshSpan = WebhooksTracer.Start(callerCtx, "Refresh", trace.WithLinks(trace.LinkFromContext(ctx)))
+		defer refreshSpan.End()
+	}
+
+	// Reject replayed deliveries (only if the sender identifies its deliveries)
+	if audit.id != "" && !ph.deliveries.add(audit.id) {
+		l.Warn().Str("deliveryID", audit.id).Msg("Rejecting replayed refresh request")
+		audit.setResult(repo, apiv1.WebhookDeliveryReplayed)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	// Parse the request
+	req := RefreshRequest{}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			l.Warn().Err(err).Msg("Failed parsing refresh request")
+			ph.deliveries.remove(audit.id)
+			audit.setResult(repo, apiv1.WebhookDeliveryInvalidRequest)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	audit.ref = req.Branch
+	if err := req.validate(); err != nil {
+		l.Warn().Err(err).Msg("Invalid refresh request")
+		ph.deliveries.remove(audit.id)
+		audit.setResult(repo, apiv1.WebhookDeliveryInvalidRequest)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Request a refresh of the given branch, or a full refresh
+	if req.Branch != "" {
+		err = retry.RetryOnConflict(retry.DefaultBackoff, func() error { return ph.annotateRepositoryBranch(ctx, namespace, name, req.Branch) })
+	} else {
+		err = retry.RetryOnConflict(retry.DefaultBackoff, func() error { return ph.annotateRepository(ctx, namespace, name, true) })
+	}
+	if err != nil {
+		l.Error().Err(err).Msg("Failed refreshing repository")
+		ph.deliveries.remove(audit.id)
+		audit.setResult(repo, apiv1.WebhookDeliveryFailed)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	audit.setResult(repo, apiv1.WebhookDeliveryAccepted)
+	w.WriteHeader(http.StatusOK)
+}
+
+// annotateRepositoryBranch requests a refresh of the given branch of the given repository. If a refresh of other
+// branches is pending, the branch is added to it; if a full refresh is pending, it covers the branch already.
+func (ph *PushHandler) annotateRepositoryBranch(ctx context.Context, namespace, name, branch string) error {
+	repo := &apiv1.Repository{}
+	if err := ph.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, repo); err != nil {
+		return err
+	}
+
+	if repo.ObjectMeta.Annotations == nil {
+		repo.ObjectMeta.Annotations = map[string]string{}
+	}
+	annotations := repo.ObjectMeta.Annotations
+
+	pendingRefresh := annotations[apiv1.RefreshAnnotation] != "" && annotations[apiv1.RefreshAnnotation] != repo.Status.LastRefreshRequest
+	if !pendingRefresh {
+		annotations[apiv1.RefreshBranchesAnnotation] = branch
+	} else if branches := annotations[apiv1.RefreshBranchesAnnotation]; branches != "" && !slices.Contains(strings.Split(branches, ","), branch) {
+		annotations[apiv1.RefreshBranchesAnnotation] = branches + "," + branch
+	}
+	annotations[apiv1.RefreshAnnotation] = time.Now().String()
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		annotations[apiv1.TraceParentAnnotation] = traceParent
+	}
+	return client.IgnoreNotFound(ph.Update(ctx, repo))
+}
+
+// verifyRefreshRequest verifies the given request carries the given secret as a bearer token, or a valid HMAC-SHA256
+// signature of its body.
+func verifyRefreshRequest(header http.Header, body []byte, secret string) error {
+	if token, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer "); ok {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return ErrRefreshUnauthorized
+		}
+		return nil
+	} else if signature := header.Get(refreshSignatureHeader); signature != "" {
+		return verifyHMAC(sha256.New, "sha256=", refreshSignatureHeader, signature, body, secret)
+	} else {
+		return ErrRefreshUnauthorized
+	}
+}
+
+func (ph *PushHandler) getRepositoryRefreshSecret(ctx context.Context, repo *apiv1.Repository) (string, error) {
+	cfg := repo.Spec.RefreshWebhook
+
+	// Fetch secret
+	secret := &v12.Secret{}
+	if err := ph.Client.Get(ctx, cfg.Secret.GetObjectKey(repo.Namespace), secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", ErrRefreshSecretNotFound
+		} else if apierrors.IsForbidden(err) {
+			return "", fmt.Errorf("refresh webhook secret is forbidden: %w", err)
+		} else {
+			return "", fmt.Errorf("refresh webhook secret could not be read: %w", err)
+		}
+	}
+
+	// Extract refresh webhook secret
+	secretValue, ok := secret.Data[cfg.Key]
+	if !ok {
+		return "", ErrRefreshSecretKeyNotFound
+	} else if string(secretValue) == "" {
+		return "", ErrRefreshSecretIsEmpty
+	}
+
+	return string(secretValue), nil
+}
@@ -0,0 +1,176 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+var _ = Describe("PushHandler refresh requests", func() {
+	var k8sClient client.Client
+	var mux *http.ServeMux
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(apiv1.AddToScheme(scheme)).To(Succeed())
+		repo := newRepository("ns", "repo", "owner", "name")
+		repo.Spec.RefreshWebhook = &apiv1.RepositoryRefreshWebhook{
+			Secret: apiv1.SecretReferenceWithOptionalNamespace{Name: "webhook"},
+			Key:    "secret",
+		}
+		repo.Status.Revisions = map[string]string{"main": "aaaa"}
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&apiv1.Repository{}, RepositoryOwnerAndNameField, IndexRepositoryByOwnerAndName).
+			WithStatusSubresource(&apiv1.Repository{}).
+			WithObjects(
+				newWebhookSecret("ns", webhookSecret),
+				repo,
+				newRepository("ns", "disabled", "owner", "disabled"),
+			).
+			Build()
+
+		handler, err := newPushHandler(k8sClient)
+		Expect(err).NotTo(HaveOccurred())
+		mux = http.NewServeMux()
+		mux.HandleFunc("POST /refresh/{namespace}/{repository}", handler.HandleRefreshRequest)
+	})
+
+	refresh := func(path, body string, headers map[string]string) int {
+		r := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	getRepo := func() *apiv1.Repository {
+		repo := &apiv1.Repository{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "repo"}, repo)).To(Succeed())
+		return repo
+	}
+
+	It("should request a refresh for bearer-authenticated requests", func() {
+		Expect(refresh("/refresh/ns/repo", "", map[string]string{"Authorization": "Bearer " + webhookSecret})).To(Equal(http.StatusOK))
+		repo := getRepo()
+		Expect(repo.Annotations).To(HaveKey(apiv1.RefreshAnnotation))
+		Expect(repo.Status.WebhookDeliveries).To(HaveLen(1))
+		Expect(repo.Status.WebhookDeliveries[0].Event).To(Equal(refreshEvent))
+		Expect(repo.Status.WebhookDeliveries[0].Result).To(Equal(apiv1.WebhookDeliveryAccepted))
+	})
+
+	It("should request a refresh of only the given branch for signed requests", func() {
+		body := `{"branch":"main"}`
+		Expect(refresh("/refresh/ns/repo", body, map[string]string{
+			refreshSignatureHeader: sign(sha256.New, "sha256=", webhookSecret, []byte(body)),
+		})).To(Equal(http.StatusOK))
+		repo := getRepo()
+		Expect(repo.Annotations).To(HaveKey(apiv1.RefreshAnnotation))
+		Expect(repo.Annotations).To(HaveKeyWithValue(apiv1.RefreshBranchesAnnotation, "main"))
+		Expect(repo.Status.Revisions).To(Equal(map[string]string{"main": "aaaa"}))
+	})
+
+	It("should accumulate pending branch refreshes", func() {
+		headers := map[string]string{"Authorization": "Bearer " + webhookSecret}
+		Expect(refresh("/refresh/ns/repo", `{"branch":"main"}`, headers)).To(Equal(http.StatusOK))
+		Expect(refresh("/refresh/ns/repo", `{"branch":"feature/x"}`, headers)).To(Equal(http.StatusOK))
+		Expect(refresh("/refresh/ns/repo", `{"branch":"main"}`, headers)).To(Equal(http.StatusOK))
+		Expect(getRepo().Annotations).To(HaveKeyWithValue(apiv1.RefreshBranchesAnnotation, "main,feature/x"))
+
+		// Once handled, new branch refreshes start afresh
+		repo := getRepo()
+		repo.Status.LastRefreshRequest = repo.Annotations[apiv1.RefreshAnnotation]
+		Expect(k8sClient.Status().Update(context.Background(), repo)).To(Succeed())
+		Expect(refresh("/refresh/ns/repo", `{"branch":"feature/y"}`, headers)).To(Equal(http.StatusOK))
+		Expect(getRepo().Annotations).To(HaveKeyWithValue(apiv1.RefreshBranchesAnnotation, "feature/y"))
+	})
+
+	It("should not narrow pending full refreshes to a branch", func() {
+		headers := map[string]string{"Authorization": "Bearer " + webhookSecret}
+		Expect(refresh("/refresh/ns/repo", `{"branch":"main"}`, headers)).To(Equal(http.StatusOK))
+		Expect(refresh("/refresh/ns/repo", "", headers)).To(Equal(http.StatusOK))
+		Expect(getRepo().Annotations).NotTo(HaveKey(apiv1.RefreshBranchesAnnotation))
+		Expect(refresh("/refresh/ns/repo", `{"branch":"main"}`, headers)).To(Equal(http.StatusOK))
+		Expect(getRepo().Annotations).NotTo(HaveKey(apiv1.RefreshBranchesAnnotation))
+	})
+
+	It("should continue the caller's trace", func() {
+		const traceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+		Expect(refresh("/refresh/ns/repo", "", map[string]string{
+			"Authorization": "Bearer " + webhookSecret,
+			"Traceparent":   traceParent,
+		})).To(Equal(http.StatusOK))
+		Expect(getRepo().Annotations).To(HaveKeyWithValue(apiv1.TraceParentAnnotation, traceParent))
+
+		body := `{"branch":"main"}`
+		Expect(refresh("/refresh/ns/repo", body, map[string]string{
+			refreshSignatureHeader: sign(sha256.New, "sha256=", webhookSecret, []byte(body)),
+			"Traceparent":          "00-0af7651916cd43dd8448eb211c80319d-b7ad6b7169203331-01",
+		})).To(Equal(http.StatusOK))
+		Expect(getRepo().Annotations).To(HaveKeyWithValue(apiv1.TraceParentAnnotation, "00-0af7651916cd43dd8448eb211c80319d-b7ad6b7169203331-01"))
+	})
+
+	It("should not record the trace of unauthorized callers or of requests not changing revisions", func() {
+		const traceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+		Expect(refresh("/refresh/ns/repo", "", map[string]string{
+			"Authorization": "Bearer wrong",
+			"Traceparent":   traceParent,
+		})).To(Equal(http.StatusUnauthorized))
+		Expect(refresh("/refresh/ns/repo", `{"branch":`, map[string]string{
+			"Authorization": "Bearer " + webhookSecret,
+			"Traceparent":   traceParent,
+		})).To(Equal(http.StatusBadRequest))
+		repo := getRepo()
+		Expect(repo.Status.WebhookDeliveries).To(HaveLen(1))
+		Expect(repo.Annotations).NotTo(HaveKey(apiv1.TraceParentAnnotation))
+	})
+
+	DescribeTable("should reject unauthorized requests",
+		func(body string, headers map[string]string) {
+			Expect(refresh("/refresh/ns/repo", body, headers)).To(Equal(http.StatusUnauthorized))
+			repo := getRepo()
+			Expect(repo.Annotations).NotTo(HaveKey(apiv1.RefreshAnnotation))
+			Expect(repo.Status.Revisions).To(Equal(map[string]string{"main": "aaaa"}))
+			Expect(repo.Status.WebhookDeliveries).To(BeEmpty())
+		},
+		Entry("missing credentials", "", map[string]string{}),
+		Entry("wrong bearer token", "", map[string]string{"Authorization": "Bearer wrong"}),
+		Entry("signature mismatch", `{"branch":"feature"}`, map[string]string{
+			refreshSignatureHeader: sign(sha256.New, "sha256=", webhookSecret, []byte(`{"branch":"main"}`)),
+		}),
+	)
+
+	DescribeTable("should reject invalid requests",
+		func(body string) {
+			Expect(refresh("/refresh/ns/repo", body, map[string]string{"Authorization": "Bearer " + webhookSecret})).To(Equal(http.StatusBadRequest))
+			Expect(getRepo().Annotations).NotTo(HaveKey(apiv1.RefreshAnnotation))
+		},
+		Entry("malformed JSON", `{"branch":`),
+		Entry("malformed branch", `{"branch":"main branch"}`),
+	)
+
+	It("should not expose repositories without a refresh webhook", func() {
+		Expect(refresh("/refresh/ns/disabled", "", map[string]string{"Authorization": "Bearer " + webhookSecret})).To(Equal(http.StatusNotFound))
+		Expect(refresh("/refresh/ns/missing", "", map[string]string{"Authorization": "Bearer " + webhookSecret})).To(Equal(http.StatusNotFound))
+	})
+
+	It("should reject replayed deliveries", func() {
+		headers := map[string]string{"Authorization": "Bearer " + webhookSecret, refreshDeliveryHeader: "ci-1"}
+		Expect(refresh("/refresh/ns/repo", "", headers)).To(Equal(http.StatusOK))
+		Expect(refresh("/refresh/ns/repo", "", headers)).To(Equal(http.StatusConflict))
+	})
+})
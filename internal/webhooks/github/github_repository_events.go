@@ -0,0 +1,189 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/webhooks/v6/github"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+// RepositoryPayload is the payload of GitHub "repository" events. The go-playground RepositoryPayload cannot be used,
+// since it fails to parse "transferred" events (it models the previous owner as a string rather than an object).
+type RepositoryPayload struct {
+	Action  string `json:"action"`
+	Changes struct {
+		Repository struct {
+			Name struct {
+				From string `json:"from"`
+			} `json:"name"`
+		} `json:"repository"`
+		Owner struct {
+			From struct {
+				User struct {
+					Login string `json:"login"`
+				} `json:"user"`
+				Organization struct {
+					Login string `json:"login"`
+				} `json:"organization"`
+			} `json:"from"`
+		} `json:"owner"`
+	} `json:"changes"`
+	Repository struct {
+		Name          string `json:"name"`
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+		Owner         struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+}
+
+// parseRepositoryPayload parses the given request as a GitHub "repository" event.
+func parseRepositoryPayload(r *http.Request) (RepositoryPayload, error) {
+	defer func() {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_ = r.Body.Close()
+	}()
+
+	payload := RepositoryPayload{}
+	if r.Method != http.MethodPost {
+		return payload, github.ErrInvalidHTTPMethod
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		return payload, github.ErrParsingPayload
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return payload, fmt.Errorf("%w: %w", github.ErrParsingPayload, err)
+	}
+	return payload, nil
+}
+
+// ghRepoRef is a reference to a GitHub repository by owner & name.
+type ghRepoRef struct {
+	Owner, Name string
+}
+
+// ghRepoRefsOfPayload returns the GitHub repositories the given payload refers to. Events of renamed & transferred
+// repositories also refer to the repository's previous name, since Repository objects still refer to it.
+func ghRepoRefsOfPayload(payload any) ([]ghRepoRef, error) {
+	switch p := payload.(type) {
+	case github.PingPayload:
+		return []ghRepoRef{{p.Repository.Owner.Login, p.Repository.Name}}, nil
+	case github.PushPayload:
+		return []ghRepoRef{{p.Repository.Owner.Login, p.Repository.Name}}, nil
+	case github.PullRequestPayload:
+		return []ghRepoRef{{p.Repository.Owner.Login, p.Repository.Name}}, nil
+	case github.CreatePayload:
+		return []ghRepoRef{{p.Repository.Owner.Login, p.Repository.Name}}, nil
+	case github.DeletePayload:
+		return []ghRepoRef{{p.Repository.Owner.Login, p.Repository.Name}}, nil
+	case RepositoryPayload:
+		refs := []ghRepoRef{{p.Repository.Owner.Login, p.Repository.Name}}
+		switch p.Action {
+		case "renamed":
+			refs = append(refs, ghRepoRef{p.Repository.Owner.Login, p.Changes.Repository.Name.From})
+		case "transferred":
+			if from := p.Changes.Owner.From.User.Login; from != "" {
+				refs = append(refs, ghRepoRef{from, p.Repository.Name})
+			} else if from := p.Changes.Owner.From.Organization.Login; from != "" {
+				refs = append(refs, ghRepoRef{from, p.Repository.Name})
+			}
+		}
+		return refs, nil
+	case github.InstallationPayload:
+		var refs []ghRepoRef
+		for _, repo := range p.Repositories {
+			if owner, name, ok := strings.Cut(repo.FullName, "/"); ok {
+				refs = append(refs, ghRepoRef{owner, name})
+			}
+		}
+		return refs, nil
+	case github.InstallationRepositoriesPayload:
+		var refs []ghRepoRef
+		for _, repo := range append(p.RepositoriesAdded, p.RepositoriesRemoved...) {
+			if owner, name, ok := strings.Cut(repo.FullName, "/"); ok {
+				refs = append(refs, ghRepoRef{owner, name})
+			}
+		}
+		return refs, nil
+	default:
+		return nil, fmt.Errorf("unsupported webhook event: %T", payload)
+	}
+}
+
+// updateRepositoryStatus applies the given mutation to the status of the given repository, retrying on conflicts. The
+// mutation function returns whether it changed the status; if not, no update is sent.
+func (ph *PushHandler) updateRepositoryStatus(ctx context.Context, namespace, name string, mutate func(*apiv1.Repository) bool) error {
+	return ph.doUpdateRepositoryStatus(ctx, namespace, name, false, mutate)
+}
+
+// updateRepositoryRevisions is like updateRepositoryStatus, but is used for mutations of the repository's branch or tag
+// revisions; if the status is changed, the trace context of the given context is recorded on the repository as well.
+func (ph *PushHandler) updateRepositoryRevisions(ctx context.Context, namespace, name string, mutate func(*apiv1.Repository) bool) error {
+	return ph.doUpdateRepositoryStatus(ctx, namespace, name, true, mutate)
+}
+
+func (ph *PushHandler) doUpdateRepositoryStatus(ctx context.Context, namespace, name string, continueTrace bool, mutate func(*apiv1.Repository) bool) error {
+	return client.IgnoreNotFound(retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		repo := &apiv1.Repository{}
+		if err := ph.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, repo); err != nil {
+			return err
+		}
+		if !mutate(repo) {
+			return nil
+		}
+		if continueTrace {
+			if err := ph.annotateTraceParent(ctx, repo); err != nil {
+				return err
+			}
+		}
+		return ph.Status().Update(ctx, repo)
+	}))
+}
+
+// applyDeleteToRepositoryStatus removes the deleted branch or tag from the status of the given repository.
+func (ph *PushHandler) applyDeleteToRepositoryStatus(ctx context.Context, namespace, name string, payload github.DeletePayload) error {
+	return ph.updateRepositoryRevisions(ctx, namespace, name, func(repo *apiv1.Repository) bool {
+		var refs map[string]string
+		switch payload.RefType {
+		case "branch":
+			refs = repo.Status.Revisions
+		case "tag":
+			refs = repo.Status.Tags
+		}
+		if _, ok := refs[payload.Ref]; !ok {
+			return false
+		}
+		delete(refs, payload.Ref)
+		return true
+	})
+}
+
+// applyRepositoryEventToRepositoryStatus reflects default branch changes, renames & transfers in the status of the
+// given repository. Renames & transfers also mark the repository as invalid, since its spec now refers to the old name.
+func (ph *PushHandler) applyRepositoryEventToRepositoryStatus(ctx context.Context, namespace, name string, payload RepositoryPayload) error {
+	return ph.updateRepositoryStatus(ctx, namespace, name, func(repo *apiv1.Repository) bool {
+		changed := false
+		if payload.Repository.DefaultBranch != "" && repo.Status.DefaultBranch != payload.Repository.DefaultBranch {
+			repo.Status.DefaultBranch = payload.Repository.DefaultBranch
+			changed = true
+		}
+		if payload.Action == "renamed" || payload.Action == "transferred" {
+			if repo.Status.ResolvedName != payload.Repository.FullName {
+				repo.Status.ResolvedName = payload.Repository.FullName
+				changed = true
+			}
+			changed = repo.Status.SetInvalidDueToRepositoryMoved("Repository moved to '%s', please update the repository spec", payload.Repository.FullName) || changed
+		}
+		return changed
+	})
+}
@@ -0,0 +1,29 @@
+package github
+
+import (
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/arikkfir/devbot/api/v1"
+)
+
+const (
+	// RepositoryOwnerAndNameField is the name of the field index mapping GitHub "owner/name" to Repository objects.
+	RepositoryOwnerAndNameField = "spec.github.ownerAndName"
+)
+
+// IndexRepositoryByOwnerAndName extracts the GitHub "owner/name" index value of the given Repository object.
+func IndexRepositoryByOwnerAndName(obj client.Object) []string {
+	repo := obj.(*apiv1.Repository)
+	if repo.Spec.GitHub == nil {
+		return nil
+	}
+	return []string{repositoryOwnerAndNameKey(repo.Spec.GitHub.Owner, repo.Spec.GitHub.Name)}
+}
+
+// repositoryOwnerAndNameKey returns the index key for the given GitHub owner & name. GitHub owners & repository names
+// are case-insensitive, and therefore so is the key.
+func repositoryOwnerAndNameKey(owner, name string) string {
+	return strings.ToLower(owner + "/" + name)
+}
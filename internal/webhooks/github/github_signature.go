@@ -0,0 +1,53 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+const (
+	signatureSHA256Header = "X-Hub-Signature-256"
+	signatureSHA1Header   = "X-Hub-Signature"
+)
+
+var (
+	ErrSignatureMissing   = fmt.Errorf("payload signature missing")
+	ErrSignatureMalformed = fmt.Errorf("payload signature malformed")
+	ErrSignatureMismatch  = fmt.Errorf("payload signature mismatch")
+)
+
+// verifyPayloadSignature verifies the given payload against the signature headers sent by GitHub. The SHA-256
+// signature is preferred; the legacy SHA-1 signature is only verified if no SHA-256 signature was sent.
+func verifyPayloadSignature(header http.Header, payload []byte, secret string) error {
+	if signature := header.Get(signatureSHA256Header); signature != "" {
+		return verifyHMAC(sha256.New, "sha256=", signatureSHA256Header, signature, payload, secret)
+	} else if signature := header.Get(signatureSHA1Header); signature != "" {
+		return verifyHMAC(sha1.New, "sha1=", signatureSHA1Header, signature, payload, secret)
+	} else {
+		return ErrSignatureMissing
+	}
+}
+
+func verifyHMAC(h func() hash.Hash, prefix, headerName, signature string, payload []byte, secret string) error {
+	if !strings.HasPrefix(signature, prefix) {
+		return fmt.Errorf("%w: header '%s' lacks the '%s' prefix", ErrSignatureMalformed, headerName, prefix)
+	}
+
+	actualMAC, err := hex.DecodeString(signature[len(prefix):])
+	if err != nil {
+		return fmt.Errorf("%w: header '%s' is not hex-encoded", ErrSignatureMalformed, headerName)
+	}
+
+	mac := hmac.New(h, []byte(secret))
+	_, _ = mac.Write(payload)
+	if !hmac.Equal(actualMAC, mac.Sum(nil)) {
+		return fmt.Errorf("%w: header '%s' does not match payload", ErrSignatureMismatch, headerName)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package github
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGitHubWebhooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GitHub Webhooks Suite")
+}